@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"os"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/audit"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+// newAuditLog returns the event bus options writing the fault audit log
+// configured by the flags, none when it is disabled.
+func newAuditLog(logger *log.DefaultLogger, pod *podinfo.Info) ([]events.Option, error) {
+	path := viper.GetString("audit-log")
+	if path == "" {
+		return nil, nil
+	}
+
+	w := os.Stdout
+	if path != "-" {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, errors.Wrap(err, "unable to open audit log")
+		}
+		w = f
+	}
+
+	l, err := audit.New(logger, w, audit.Format(viper.GetString("audit-format")), audit.WithPod(pod))
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("Writing fault audit log", fields.String("path", path))
+	return []events.Option{events.WithHook(l.Log)}, nil
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/audit"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+func TestNewAuditLog(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	pod := &podinfo.Info{Hostname: "localhost"}
+
+	viper.Reset()
+	opts, err := newAuditLog(logger, pod)
+	require.NoError(t, err)
+	require.Empty(t, opts)
+
+	path := filepath.Join(t.TempDir(), "audit.log")
+	viper.Set("audit-log", path)
+	viper.Set("audit-format", "xml")
+	_, err = newAuditLog(logger, pod)
+	require.Error(t, err)
+
+	viper.Set("audit-format", "json")
+	opts, err = newAuditLog(logger, pod)
+	require.NoError(t, err)
+
+	events.NewBus(opts...).Publish(events.Event{Type: events.TypeScheduled, Source: "crash", FaultID: "f1", Trigger: events.TriggerFlag})
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	var record audit.Record
+	require.NoError(t, json.Unmarshal(b, &record))
+	require.Equal(t, "f1", record.FaultID)
+	require.Equal(t, "localhost", record.Pod.Hostname)
+
+	viper.Set("audit-log", filepath.Join(t.TempDir(), "missing", "audit.log"))
+	_, err = newAuditLog(logger, pod)
+	require.Error(t, err)
+	viper.Reset()
+}
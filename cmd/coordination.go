@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/coordination"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+// coordinationRetry is the interval a crash waiting for the crash slot asks
+// for it again.
+const coordinationRetry = time.Second
+
+// newCoordinator returns the coordinator of the crashes across replicas
+// configured by the flags, or nil when the crashes are not coordinated.
+func newCoordinator(logger *log.DefaultLogger, pod *podinfo.Info) (coordination.Coordinator, error) {
+	identity := replicaIdentity(pod, viper.GetString("port"))
+	hold := viper.GetDuration("crash-coordination-hold")
+
+	switch mode := viper.GetString("crash-coordination"); mode {
+	case "":
+		return nil, nil
+
+	case "lease":
+		client, err := newKubeClient()
+		if err != nil {
+			return nil, err
+		}
+		name := viper.GetString("crash-coordination-lease")
+		c, err := coordination.NewLease(client, pod.Namespace, name, identity, hold)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Coordinating crashes with a lease", fields.String("lease", pod.Namespace+"/"+name), fields.Duration("hold", hold))
+		return c, nil
+
+	case "file":
+		path := viper.GetString("crash-coordination-file")
+		c, err := coordination.NewFile(path, identity, hold)
+		if err != nil {
+			return nil, err
+		}
+		logger.Info("Coordinating crashes with a file", fields.String("path", path), fields.Duration("hold", hold))
+		return c, nil
+
+	default:
+		return nil, errors.Errorf("unknown crash coordination %q, must be lease or file", mode)
+	}
+}
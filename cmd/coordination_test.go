@@ -0,0 +1,68 @@
+package cmd
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+func TestNewCoordinator(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	pod := &podinfo.Info{Hostname: "localhost"}
+
+	viper.Reset()
+	c, err := newCoordinator(logger, pod)
+	require.NoError(t, err)
+	require.Nil(t, c)
+
+	viper.Set("crash-coordination", "zookeeper")
+	_, err = newCoordinator(logger, pod)
+	require.Error(t, err)
+
+	// outside of a cluster
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	viper.Set("crash-coordination", "lease")
+	_, err = newCoordinator(logger, pod)
+	require.Error(t, err)
+
+	viper.Set("crash-coordination", "file")
+	viper.Set("crash-coordination-file", filepath.Join(t.TempDir(), "crash"))
+	viper.Set("crash-coordination-hold", time.Minute)
+	c, err = newCoordinator(logger, pod)
+	require.NoError(t, err)
+	require.NotNil(t, c)
+	viper.Reset()
+}
+
+func TestNewCoordinator_FileIdentity(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	pod := &podinfo.Info{Hostname: "localhost"}
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("crash-coordination", "file")
+	viper.Set("crash-coordination-file", filepath.Join(t.TempDir(), "crash"))
+	viper.Set("crash-coordination-hold", time.Minute)
+
+	// two local processes on the same host and slot file
+	viper.Set("port", "3000")
+	first, err := newCoordinator(logger, pod)
+	require.NoError(t, err)
+	viper.Set("port", "3001")
+	second, err := newCoordinator(logger, pod)
+	require.NoError(t, err)
+
+	ok, err := first.TryAcquire(context.Background())
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = second.TryAcquire(context.Background())
+	require.NoError(t, err)
+	require.False(t, ok)
+}
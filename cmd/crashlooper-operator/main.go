@@ -0,0 +1,119 @@
+// Command crashlooper-operator reconciles the ChaosScenario resources into
+// crashlooper Deployments and scenario ConfigMaps, and pushes the live faults
+// of their spec to the running replicas through the control API.
+package main
+
+import (
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+
+	"github.com/pixelfactoryio/crashlooper/internal/operator"
+	"github.com/pixelfactoryio/crashlooper/internal/operator/v1alpha1"
+)
+
+func main() {
+	logger := log.New()
+
+	cmd, err := newOperatorCmd()
+	if err == nil {
+		err = cmd.Execute()
+	}
+	if err != nil {
+		logger.Error("an unexpected error occurred", fields.Error(err))
+		os.Exit(1)
+	}
+}
+
+func newOperatorCmd() (*cobra.Command, error) {
+	cmd := &cobra.Command{
+		Use:           "crashlooper-operator",
+		Short:         "Reconcile the ChaosScenario resources into crashlooper Deployments",
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE:          run,
+	}
+	cobra.OnInitialize(func() {
+		viper.SetEnvPrefix("CRASHLOOPER_OPERATOR")
+		viper.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+		viper.AutomaticEnv()
+	})
+
+	cmd.Flags().String("log-level", "info", "Operator log level")
+	cmd.Flags().String("namespace", "", "Namespace of the ChaosScenario resources reconciled (default empty means all)")
+	cmd.Flags().Duration("resync", 0, "Interval the live faults are pushed again to the replicas (default=0 means 30s)")
+	cmd.Flags().String("metrics-addr", ":8080", "Prometheus metrics bind address, 0 disables them")
+	cmd.Flags().String("probe-addr", ":8081", "Health checks bind address")
+	cmd.Flags().Bool("leader-elect", false, "Elect a leader among the operator replicas")
+	for _, name := range []string{"log-level", "namespace", "resync", "metrics-addr", "probe-addr", "leader-elect"} {
+		if err := viper.BindPFlag(name, cmd.Flags().Lookup(name)); err != nil {
+			return nil, err
+		}
+	}
+
+	return cmd, nil
+}
+
+func run(c *cobra.Command, args []string) error {
+	logger := log.New(log.WithLevel(viper.GetString("log-level")))
+	ctrl.SetLogger(zap.New())
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		return err
+	}
+	if err := v1alpha1.AddToScheme(scheme); err != nil {
+		return err
+	}
+
+	opts := ctrl.Options{
+		Scheme:                 scheme,
+		Metrics:                metricsserver.Options{BindAddress: viper.GetString("metrics-addr")},
+		HealthProbeBindAddress: viper.GetString("probe-addr"),
+		LeaderElection:         viper.GetBool("leader-elect"),
+		LeaderElectionID:       "crashlooper-operator.pixelfactory.io",
+		// the auth Secrets are read when needed rather than cached
+		Client: ctrlclient.Options{Cache: &ctrlclient.CacheOptions{DisableFor: []ctrlclient.Object{&corev1.Secret{}}}},
+	}
+	if ns := viper.GetString("namespace"); ns != "" {
+		opts.Cache = cache.Options{DefaultNamespaces: map[string]cache.Config{ns: {}}}
+	}
+
+	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), opts)
+	if err != nil {
+		return errors.Wrap(err, "unable to create manager")
+	}
+
+	var reconcilerOpts []operator.Option
+	if d := viper.GetDuration("resync"); d > 0 {
+		reconcilerOpts = append(reconcilerOpts, operator.WithResync(d))
+	}
+	r := operator.NewReconciler(logger, mgr.GetClient(), scheme, reconcilerOpts...)
+	if err := r.SetupWithManager(mgr); err != nil {
+		return errors.Wrap(err, "unable to set up controller")
+	}
+
+	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
+		return err
+	}
+	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+		return err
+	}
+
+	logger.Info("Starting operator", fields.String("namespace", viper.GetString("namespace")))
+	return mgr.Start(ctrl.SetupSignalHandler())
+}
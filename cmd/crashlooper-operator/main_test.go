@@ -0,0 +1,26 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewOperatorCmd(t *testing.T) {
+	cmd, err := newOperatorCmd()
+	require.NoError(t, err)
+	require.Equal(t, "crashlooper-operator", cmd.Use)
+
+	for name, typ := range map[string]string{
+		"log-level":    "string",
+		"namespace":    "string",
+		"resync":       "duration",
+		"metrics-addr": "string",
+		"probe-addr":   "string",
+		"leader-elect": "bool",
+	} {
+		f := cmd.Flags().Lookup(name)
+		require.NotNil(t, f, name)
+		require.Equal(t, typ, f.Value.Type(), name)
+	}
+}
@@ -0,0 +1,676 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
+	"github.com/pixelfactoryio/crashlooper/internal/auth"
+	"github.com/pixelfactoryio/crashlooper/internal/client"
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// newCtlCmd create the ctl command
+func newCtlCmd() (*cobra.Command, error) {
+	ctlCmd := &cobra.Command{
+		Use:   "ctl",
+		Short: "Control a running crashlooper",
+		Long: `Control a running crashlooper through its HTTP control API: show its status,
+schedule a crash, change its memory usage or threads and processes target, add
+and remove faults or run a scenario of timed actions.`,
+	}
+
+	ctlCmd.PersistentFlags().String("server", "http://localhost:3000", "Address of the crashlooper to control")
+	if err := viper.BindPFlag("ctl-server", ctlCmd.PersistentFlags().Lookup("server")); err != nil {
+		return nil, err
+	}
+
+	ctlCmd.PersistentFlags().StringP("output", "o", "table", "Output format (table or json)")
+	if err := viper.BindPFlag("ctl-output", ctlCmd.PersistentFlags().Lookup("output")); err != nil {
+		return nil, err
+	}
+
+	ctlCmd.PersistentFlags().Duration("timeout", 10*time.Second, "Timeout of each request")
+	if err := viper.BindPFlag("ctl-timeout", ctlCmd.PersistentFlags().Lookup("timeout")); err != nil {
+		return nil, err
+	}
+
+	ctlCmd.PersistentFlags().String("token", "", "Bearer token authenticating the requests")
+	if err := viper.BindPFlag("ctl-token", ctlCmd.PersistentFlags().Lookup("token")); err != nil {
+		return nil, err
+	}
+
+	ctlCmd.PersistentFlags().String("cert", "", "PEM client certificate authenticating the requests")
+	if err := viper.BindPFlag("ctl-cert", ctlCmd.PersistentFlags().Lookup("cert")); err != nil {
+		return nil, err
+	}
+
+	ctlCmd.PersistentFlags().String("key", "", "PEM private key of the client certificate")
+	if err := viper.BindPFlag("ctl-key", ctlCmd.PersistentFlags().Lookup("key")); err != nil {
+		return nil, err
+	}
+
+	ctlCmd.PersistentFlags().String("ca-cert", "", "PEM certificate authorities verifying the server certificate")
+	if err := viper.BindPFlag("ctl-ca-cert", ctlCmd.PersistentFlags().Lookup("ca-cert")); err != nil {
+		return nil, err
+	}
+
+	ctlCmd.AddCommand(
+		newCtlStatusCmd(),
+		newCtlCrashCmd(),
+		newCtlMemoryCmd(),
+		newCtlPidsCmd(),
+		newCtlTLSCmd(),
+		newCtlFaultCmd(),
+		newCtlScenarioCmd(),
+	)
+
+	return ctlCmd, nil
+}
+
+func newCtlClient() (*client.Client, error) {
+	tlsConfig, err := newCtlTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	httpClient := &http.Client{Timeout: viper.GetDuration("ctl-timeout")}
+	if tlsConfig != nil {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.TLSClientConfig = tlsConfig
+		httpClient.Transport = transport
+	}
+
+	return client.New(
+		viper.GetString("ctl-server"),
+		client.WithHTTPClient(httpClient),
+		client.WithToken(viper.GetString("ctl-token")),
+	)
+}
+
+// newCtlTLSConfig returns the TLS configuration of the client certificate and
+// certificate authorities flags, or nil if none is set.
+func newCtlTLSConfig() (*tls.Config, error) {
+	certFile, keyFile, caFile := viper.GetString("ctl-cert"), viper.GetString("ctl-key"), viper.GetString("ctl-ca-cert")
+	if certFile == "" && keyFile == "" && caFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid client certificate")
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	if caFile != "" {
+		pool, err := auth.LoadCertPool(caFile)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid CA certificate")
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	return tlsConfig, nil
+}
+
+func newCtlStatusCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show the status of the controls",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			ctl, err := newCtlClient()
+			if err != nil {
+				return err
+			}
+
+			status, err := ctl.Status(c.Context())
+			if err != nil {
+				return err
+			}
+
+			return printOutput(c.OutOrStdout(), status, func(w io.Writer) {
+				fmt.Fprintf(w, "STARTED AT\t%s\n", status.StartedAt.Format(time.RFC3339))
+				fmt.Fprintf(w, "UPTIME\t%s\n", time.Duration(status.Uptime))
+				if status.Crash != nil {
+					fmt.Fprintf(w, "CRASH\t%s\n", formatSchedule(*status.Crash))
+				}
+				if status.Memory != nil {
+					fmt.Fprintf(w, "MEMORY\t%s\n", formatMemory(*status.Memory))
+				}
+				if status.Pids != nil {
+					fmt.Fprintf(w, "PIDS\t%s\n", formatPids(*status.Pids))
+				}
+				if status.TLS != nil {
+					fmt.Fprintf(w, "TLS\t%s\n", formatTLS(*status.TLS))
+				}
+				if status.TCPFaults != nil {
+					b, _ := json.Marshal(status.TCPFaults)
+					fmt.Fprintf(w, "TCP FAULTS\t%s\n", b)
+				}
+				fmt.Fprintln(w)
+				printFaults(w, status.Faults)
+			})
+		},
+	}
+}
+
+func newCtlCrashCmd() *cobra.Command {
+	crashCmd := &cobra.Command{
+		Use:   "crash",
+		Short: "Schedule or cancel a crash",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			ctl, err := newCtlClient()
+			if err != nil {
+				return err
+			}
+
+			if cancel, _ := c.Flags().GetBool("cancel"); cancel {
+				if err := ctl.CancelCrash(c.Context()); err != nil {
+					return err
+				}
+				return printOutput(c.OutOrStdout(), crash.Schedule{}, func(w io.Writer) {
+					fmt.Fprintln(w, "Crash cancelled")
+				})
+			}
+
+			after, _ := c.Flags().GetDuration("after")
+			req := handlers.CrashRequest{After: chaos.Duration(after)}
+			if c.Flags().Changed("exit-code") {
+				exitCode, _ := c.Flags().GetInt("exit-code")
+				req.ExitCode = &exitCode
+			}
+
+			schedule, err := ctl.Crash(c.Context(), req)
+			if err != nil {
+				return err
+			}
+
+			return printOutput(c.OutOrStdout(), schedule, func(w io.Writer) {
+				fmt.Fprintf(w, "CRASH\t%s\n", formatSchedule(*schedule))
+			})
+		},
+	}
+
+	crashCmd.Flags().Duration("after", 0, "Delay before the crash")
+	crashCmd.Flags().Int("exit-code", 1, "Exit code of the crash")
+	crashCmd.Flags().Bool("cancel", false, "Cancel the pending crash")
+
+	return crashCmd
+}
+
+func newCtlMemoryCmd() *cobra.Command {
+	memoryCmd := &cobra.Command{
+		Use:   "memory",
+		Short: "Control the memory usage",
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set",
+		Short: "Set the memory usage target, releasing the memory above it",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			settings, err := memorySettings(c)
+			if err != nil {
+				return err
+			}
+
+			ctl, err := newCtlClient()
+			if err != nil {
+				return err
+			}
+
+			status, err := ctl.SetMemory(c.Context(), settings)
+			if err != nil {
+				return err
+			}
+
+			return printOutput(c.OutOrStdout(), status, func(w io.Writer) {
+				fmt.Fprintf(w, "MEMORY\t%s\n", formatMemory(*status))
+			})
+		},
+	}
+
+	setCmd.Flags().String("target", "", "Memory usage target (e.g. 1GiB)")
+	setCmd.Flags().String("increment", "", "Memory usage increment, keeps the current one when empty")
+	setCmd.Flags().Duration("interval", 0, "Memory usage increment interval, keeps the current one when 0")
+	_ = setCmd.MarkFlagRequired("target")
+
+	memoryCmd.AddCommand(setCmd)
+	return memoryCmd
+}
+
+func memorySettings(c *cobra.Command) (memory.Settings, error) {
+	var settings memory.Settings
+
+	target, _ := c.Flags().GetString("target")
+	t, err := units.ParseBase2Bytes(target)
+	if err != nil {
+		return settings, errors.Wrap(err, "invalid target")
+	}
+	settings.Target = t
+
+	if increment, _ := c.Flags().GetString("increment"); increment != "" {
+		inc, err := units.ParseBase2Bytes(increment)
+		if err != nil {
+			return settings, errors.Wrap(err, "invalid increment")
+		}
+		settings.Increment = inc
+	}
+
+	interval, _ := c.Flags().GetDuration("interval")
+	settings.Interval = chaos.Duration(interval)
+
+	return settings, nil
+}
+
+func newCtlPidsCmd() *cobra.Command {
+	pidsCmd := &cobra.Command{
+		Use:   "pids",
+		Short: "Control the threads or processes spawned",
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set",
+		Short: "Set the threads or processes target, up to the cgroup pids limit",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			mode, _ := c.Flags().GetString("mode")
+			target, _ := c.Flags().GetInt("target")
+			increment, _ := c.Flags().GetInt("increment")
+			interval, _ := c.Flags().GetDuration("interval")
+			settings := pids.Settings{
+				Mode:      pids.Mode(mode),
+				Target:    target,
+				Increment: increment,
+				Interval:  chaos.Duration(interval),
+			}
+
+			ctl, err := newCtlClient()
+			if err != nil {
+				return err
+			}
+
+			status, err := ctl.SetPids(c.Context(), settings)
+			if err != nil {
+				return err
+			}
+
+			return printOutput(c.OutOrStdout(), status, func(w io.Writer) {
+				fmt.Fprintf(w, "PIDS\t%s\n", formatPids(*status))
+			})
+		},
+	}
+
+	setCmd.Flags().String("mode", "", "Spawn threads or processes, keeps the current mode when empty")
+	setCmd.Flags().Int("target", 0, "Number of threads or processes spawned, 0 releases them")
+	setCmd.Flags().Int("increment", 0, "Number spawned per interval, keeps the current one when 0")
+	setCmd.Flags().Duration("interval", 0, "Spawn interval, keeps the current one when 0")
+	_ = setCmd.MarkFlagRequired("target")
+
+	pidsCmd.AddCommand(setCmd)
+	return pidsCmd
+}
+
+func newCtlTLSCmd() *cobra.Command {
+	tlsCmd := &cobra.Command{
+		Use:   "tls",
+		Short: "Show the certificate served over TLS",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			ctl, err := newCtlClient()
+			if err != nil {
+				return err
+			}
+
+			status, err := ctl.TLS(c.Context())
+			if err != nil {
+				return err
+			}
+
+			return printTLS(c.OutOrStdout(), status)
+		},
+	}
+
+	setCmd := &cobra.Command{
+		Use:   "set",
+		Short: "Replace the certificate served over TLS",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			mode, _ := c.Flags().GetString("mode")
+			interval, _ := c.Flags().GetDuration("rotate-interval")
+			settings := certs.Settings{Mode: certs.Mode(mode), RotateInterval: chaos.Duration(interval)}
+			if err := settings.Validate(); err != nil {
+				return err
+			}
+
+			ctl, err := newCtlClient()
+			if err != nil {
+				return err
+			}
+
+			status, err := ctl.SetTLS(c.Context(), settings)
+			if err != nil {
+				return err
+			}
+
+			return printTLS(c.OutOrStdout(), status)
+		},
+	}
+
+	setCmd.Flags().String("mode", string(certs.ModeValid), "Certificate served (valid, expired, not-yet-valid, wrong-host or self-signed)")
+	setCmd.Flags().Duration("rotate-interval", 0, "Certificate rotation interval (default=0 means never)")
+
+	tlsCmd.AddCommand(setCmd)
+	return tlsCmd
+}
+
+func printTLS(out io.Writer, status *certs.Status) error {
+	return printOutput(out, status, func(w io.Writer) {
+		fmt.Fprintf(w, "TLS\t%s\n", formatTLS(*status))
+		fmt.Fprintf(w, "SERIAL\t%s\n", status.Serial)
+		fmt.Fprintf(w, "ISSUER\t%s\n", status.Issuer)
+		fmt.Fprintf(w, "HOSTS\t%s\n", strings.Join(status.Hosts, ", "))
+		fmt.Fprintf(w, "VALIDITY\t%s to %s\n", status.NotBefore.Format(time.RFC3339), status.NotAfter.Format(time.RFC3339))
+	})
+}
+
+func newCtlFaultCmd() *cobra.Command {
+	faultCmd := &cobra.Command{
+		Use:   "fault",
+		Short: "Control the faults applied to the application routes",
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the active faults",
+		Args:  cobra.NoArgs,
+		RunE: func(c *cobra.Command, args []string) error {
+			ctl, err := newCtlClient()
+			if err != nil {
+				return err
+			}
+
+			faults, err := ctl.Faults(c.Context())
+			if err != nil {
+				return err
+			}
+
+			return printOutput(c.OutOrStdout(), faults, func(w io.Writer) {
+				printFaults(w, faults)
+			})
+		},
+	}
+
+	addCmd := &cobra.Command{
+		Use:       "add KIND",
+		Short:     "Add or replace the fault of a kind",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: kindNames(),
+		RunE: func(c *cobra.Command, args []string) error {
+			ctl, err := newCtlClient()
+			if err != nil {
+				return err
+			}
+
+			fault, err := ctl.SetFault(c.Context(), faultFromFlags(c, chaos.Kind(args[0])))
+			if err != nil {
+				return err
+			}
+
+			return printOutput(c.OutOrStdout(), fault, func(w io.Writer) {
+				printFaults(w, []chaos.Fault{*fault})
+			})
+		},
+	}
+
+	addCmd.Flags().Float64("probability", 1, "Probability of applying the fault to a request, in (0, 1]")
+	addCmd.Flags().String("path-prefix", "", "Only apply the fault to the requests under this path")
+	addCmd.Flags().Duration("delay", 0, "Latency fault delay")
+	addCmd.Flags().Duration("jitter", 0, "Latency fault random extra delay")
+	addCmd.Flags().Bool("reset", false, "Disconnect fault resets the connection instead of closing it")
+	addCmd.Flags().Int("status-code", 0, "Error fault status code")
+	addCmd.Flags().String("body", "", "Error fault response body")
+	addCmd.Flags().Int("exit-code", 0, "Crash fault exit code")
+
+	removeCmd := &cobra.Command{
+		Use:       "remove KIND",
+		Short:     "Remove the fault of a kind",
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: kindNames(),
+		RunE: func(c *cobra.Command, args []string) error {
+			ctl, err := newCtlClient()
+			if err != nil {
+				return err
+			}
+
+			if err := ctl.RemoveFault(c.Context(), chaos.Kind(args[0])); err != nil {
+				return err
+			}
+
+			return printOutput(c.OutOrStdout(), map[string]string{"removed": args[0]}, func(w io.Writer) {
+				fmt.Fprintf(w, "Fault %s removed\n", args[0])
+			})
+		},
+	}
+
+	faultCmd.AddCommand(listCmd, addCmd, removeCmd)
+	return faultCmd
+}
+
+func faultFromFlags(c *cobra.Command, kind chaos.Kind) chaos.Fault {
+	f := chaos.Fault{Kind: kind}
+	f.Probability, _ = c.Flags().GetFloat64("probability")
+	f.PathPrefix, _ = c.Flags().GetString("path-prefix")
+	delay, _ := c.Flags().GetDuration("delay")
+	f.Delay = chaos.Duration(delay)
+	jitter, _ := c.Flags().GetDuration("jitter")
+	f.Jitter = chaos.Duration(jitter)
+	f.Reset, _ = c.Flags().GetBool("reset")
+	f.StatusCode, _ = c.Flags().GetInt("status-code")
+	f.Body, _ = c.Flags().GetString("body")
+	f.ExitCode, _ = c.Flags().GetInt("exit-code")
+	return f
+}
+
+func kindNames() []string {
+	names := make([]string, 0, len(chaos.Kinds))
+	for _, k := range chaos.Kinds {
+		names = append(names, string(k))
+	}
+	return names
+}
+
+func newCtlScenarioCmd() *cobra.Command {
+	scenarioCmd := &cobra.Command{
+		Use:   "scenario",
+		Short: "Run scenarios of timed actions",
+	}
+
+	runCmd := &cobra.Command{
+		Use:   "run FILE",
+		Short: "Run a JSON scenario, - reads it from stdin",
+		Long: `Run a JSON scenario applying each step at its offset from the start of the run:
+
+  {
+    "name": "degrade",
+    "steps": [
+      {"at": "0s", "fault": {"kind": "latency", "probability": 0.5, "delay": "200ms"}},
+      {"at": "1m", "memory": {"target": "512MiB", "increment": "64MiB"}},
+      {"at": "2m", "remove_fault": "latency"},
+      {"at": "3m", "crash": {"after": "0s", "exit_code": 137}}
+    ]
+  }
+
+Steps may also use "cancel_crash": true.`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(c *cobra.Command, args []string) error {
+			s, err := readScenario(c, args[0])
+			if err != nil {
+				return err
+			}
+
+			ctl, err := newCtlClient()
+			if err != nil {
+				return err
+			}
+
+			ctx, stop := signal.NotifyContext(c.Context(), os.Interrupt, syscall.SIGTERM)
+			defer stop()
+
+			out := c.OutOrStdout()
+			start := time.Now()
+			return ctl.RunScenario(ctx, s, func(step client.Step) {
+				_ = printOutput(out, step, func(w io.Writer) {
+					fmt.Fprintf(w, "%s\t%s\n", time.Since(start).Truncate(time.Millisecond), step)
+				})
+			})
+		},
+	}
+
+	scenarioCmd.AddCommand(runCmd)
+	return scenarioCmd
+}
+
+func readScenario(c *cobra.Command, path string) (*client.Scenario, error) {
+	if path == "-" {
+		return client.LoadScenario(c.InOrStdin())
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to open scenario")
+	}
+	defer f.Close()
+
+	return client.LoadScenario(f)
+}
+
+// printOutput writes v as JSON, or calls table to write it as a table,
+// depending on the output flag.
+func printOutput(out io.Writer, v interface{}, table func(w io.Writer)) error {
+	switch format := viper.GetString("ctl-output"); format {
+	case "json":
+		enc := json.NewEncoder(out)
+		enc.SetIndent("", "  ")
+		return enc.Encode(v)
+	case "table", "":
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		table(w)
+		return w.Flush()
+	default:
+		return errors.Errorf("invalid output format %q, expected table or json", format)
+	}
+}
+
+func printFaults(w io.Writer, faults []chaos.Fault) {
+	if len(faults) == 0 {
+		fmt.Fprintln(w, "No active faults")
+		return
+	}
+
+	fmt.Fprintln(w, "KIND\tPROBABILITY\tPATH PREFIX\tPARAMETERS")
+	for _, f := range faults {
+		prefix := f.PathPrefix
+		if prefix == "" {
+			prefix = "*"
+		}
+		fmt.Fprintf(w, "%s\t%v\t%s\t%s\n", f.Kind, f.Probability, prefix, faultParameters(f))
+	}
+}
+
+func faultParameters(f chaos.Fault) string {
+	var params []string
+	switch f.Kind {
+	case chaos.KindLatency:
+		params = append(params, "delay="+time.Duration(f.Delay).String())
+		if f.Jitter > 0 {
+			params = append(params, "jitter="+time.Duration(f.Jitter).String())
+		}
+	case chaos.KindDisconnect:
+		params = append(params, fmt.Sprintf("reset=%t", f.Reset))
+	case chaos.KindError:
+		params = append(params, fmt.Sprintf("status_code=%d", f.StatusCode))
+		if f.Body != "" {
+			params = append(params, fmt.Sprintf("body=%q", f.Body))
+		}
+	case chaos.KindCrash:
+		params = append(params, fmt.Sprintf("exit_code=%d", f.ExitCode))
+	}
+	return strings.Join(params, " ")
+}
+
+func formatSchedule(s crash.Schedule) string {
+	if !s.Scheduled {
+		return "not scheduled"
+	}
+	return fmt.Sprintf(
+		"at %s (in %s), exit code %d",
+		s.At.Format(time.RFC3339),
+		time.Until(s.At).Truncate(time.Second),
+		s.ExitCode,
+	)
+}
+
+func formatTLS(s certs.Status) string {
+	if s.RotateInterval > 0 {
+		return fmt.Sprintf("%s %s certificate, rotated every %s", s.Mode, s.Source, time.Duration(s.RotateInterval))
+	}
+	return fmt.Sprintf("%s %s certificate", s.Mode, s.Source)
+}
+
+func formatPids(s pids.Status) string {
+	var b strings.Builder
+	if s.Target == 0 {
+		fmt.Fprintf(&b, "%d %s spawned, no target", s.Spawned, s.Mode)
+	} else {
+		fmt.Fprintf(&b, "%d %s spawned of %d, %d every %s", s.Spawned, s.Mode, s.Target, s.Increment, time.Duration(s.Interval))
+	}
+	if s.LimitReached {
+		b.WriteString(", limit reached")
+	}
+	if s.Cgroup != nil {
+		limit := "max"
+		if s.Cgroup.Limit > 0 {
+			limit = strconv.FormatUint(s.Cgroup.Limit, 10)
+		}
+		fmt.Fprintf(&b, " (cgroup %d/%s pids)", s.Cgroup.Current, limit)
+	}
+	return b.String()
+}
+
+func formatMemory(s memory.Status) string {
+	if s.Target == 0 {
+		return fmt.Sprintf("%s allocated, no target", s.Allocated)
+	}
+	return fmt.Sprintf(
+		"%s allocated of %s, %s every %s",
+		s.Allocated,
+		s.Target,
+		s.Increment,
+		time.Duration(s.Interval),
+	)
+}
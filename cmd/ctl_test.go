@@ -0,0 +1,299 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
+	"github.com/pixelfactoryio/crashlooper/internal/auth"
+	"github.com/pixelfactoryio/crashlooper/internal/cgroup"
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// runCtl runs the ctl command with args against a fresh crashlooper control API
+// and returns its output.
+func runCtl(t *testing.T, server string, args ...string) (string, error) {
+	t.Helper()
+	viper.Reset()
+
+	cmd, err := newCtlCmd()
+	require.NoError(t, err)
+
+	var out bytes.Buffer
+	cmd.SetOut(&out)
+	cmd.SetErr(&out)
+	cmd.SetArgs(append([]string{"--server", server}, args...))
+
+	err = cmd.Execute()
+	return out.String(), err
+}
+
+func startCtlServer(t *testing.T) string {
+	t.Helper()
+
+	logger := log.New(log.WithLevel("info"))
+	router := api.NewRouter(
+		logger,
+		api.WithCrash(crash.New(logger, 0)),
+		api.WithMemory(memory.New(logger, 0, 0, 0)),
+		api.WithPids(pids.New(logger, pids.Settings{}, pids.WithCgroupRoot(t.TempDir()))),
+		api.WithFaults(chaos.NewController()),
+	)
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+	return srv.URL
+}
+
+func TestNewCtlCmd(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := newCtlCmd()
+
+	require.NoError(t, err)
+	require.NotNil(t, cmd)
+	require.Equal(t, "ctl", cmd.Use)
+
+	serverFlag := cmd.PersistentFlags().Lookup("server")
+	require.NotNil(t, serverFlag)
+	require.Equal(t, "http://localhost:3000", serverFlag.DefValue)
+
+	outputFlag := cmd.PersistentFlags().ShorthandLookup("o")
+	require.NotNil(t, outputFlag)
+	require.Equal(t, "table", outputFlag.DefValue)
+
+	var names []string
+	for _, c := range cmd.Commands() {
+		names = append(names, c.Name())
+	}
+	require.ElementsMatch(t, []string{"status", "crash", "memory", "pids", "tls", "fault", "scenario"}, names)
+}
+
+func TestCtl_Status(t *testing.T) {
+	server := startCtlServer(t)
+
+	out, err := runCtl(t, server, "status")
+	require.NoError(t, err)
+	require.Contains(t, out, "UPTIME")
+	require.Contains(t, out, "not scheduled")
+	require.Contains(t, out, "No active faults")
+
+	out, err = runCtl(t, server, "status", "-o", "json")
+	require.NoError(t, err)
+
+	var status handlers.ControlStatus
+	require.NoError(t, json.Unmarshal([]byte(out), &status))
+	require.NotNil(t, status.Crash)
+	require.NotNil(t, status.Memory)
+	require.NotNil(t, status.Pids)
+}
+
+func TestCtl_InvalidOutput(t *testing.T) {
+	server := startCtlServer(t)
+
+	_, err := runCtl(t, server, "status", "-o", "yaml")
+	require.Error(t, err)
+}
+
+func TestCtl_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(nil)
+	srv.Close()
+
+	_, err := runCtl(t, srv.URL, "status")
+	require.Error(t, err)
+}
+
+func TestCtl_Crash(t *testing.T) {
+	server := startCtlServer(t)
+
+	out, err := runCtl(t, server, "crash", "--after", "1h", "--exit-code", "137", "-o", "json")
+	require.NoError(t, err)
+
+	var schedule crash.Schedule
+	require.NoError(t, json.Unmarshal([]byte(out), &schedule))
+	require.True(t, schedule.Scheduled)
+	require.Equal(t, 137, schedule.ExitCode)
+
+	out, err = runCtl(t, server, "crash", "--cancel")
+	require.NoError(t, err)
+	require.Contains(t, out, "Crash cancelled")
+
+	_, err = runCtl(t, server, "crash", "--cancel")
+	require.Error(t, err)
+}
+
+func TestCtl_MemorySet(t *testing.T) {
+	server := startCtlServer(t)
+
+	out, err := runCtl(t, server, "memory", "set", "--target", "4KiB", "--increment", "1KiB", "--interval", "1ms")
+	require.NoError(t, err)
+	require.Contains(t, out, "of 4KiB, 1KiB every 1ms")
+
+	_, err = runCtl(t, server, "memory", "set", "--target", "lots")
+	require.Error(t, err)
+
+	_, err = runCtl(t, server, "memory", "set")
+	require.Error(t, err)
+}
+
+func TestCtl_PidsSet(t *testing.T) {
+	server := startCtlServer(t)
+
+	out, err := runCtl(t, server, "pids", "set", "--target", "2", "--increment", "1", "--interval", "1ms")
+	require.NoError(t, err)
+	require.Contains(t, out, "threads spawned of 2, 1 every 1ms")
+
+	out, err = runCtl(t, server, "pids", "set", "--target", "0")
+	require.NoError(t, err)
+	require.Contains(t, out, "0 threads spawned, no target")
+
+	_, err = runCtl(t, server, "pids", "set", "--target", "1", "--mode", "forks")
+	require.Error(t, err)
+
+	_, err = runCtl(t, server, "pids", "set")
+	require.Error(t, err)
+}
+
+func TestFormatPids(t *testing.T) {
+	s := pids.Status{
+		Settings:     pids.Settings{Mode: pids.ModeProcesses, Target: 100, Increment: 10, Interval: chaos.Duration(time.Second)},
+		Spawned:      40,
+		LimitReached: true,
+		Cgroup:       &cgroup.PidsStats{Current: 240, Limit: 256},
+	}
+	require.Equal(t, "40 processes spawned of 100, 10 every 1s, limit reached (cgroup 240/256 pids)", formatPids(s))
+
+	s.Cgroup.Limit = 0
+	require.Contains(t, formatPids(s), "(cgroup 240/max pids)")
+}
+
+func TestCtl_Fault(t *testing.T) {
+	server := startCtlServer(t)
+
+	out, err := runCtl(t, server, "fault", "add", "latency", "--probability", "0.5", "--delay", "200ms", "--path-prefix", "/api")
+	require.NoError(t, err)
+	require.Contains(t, out, "latency")
+	require.Contains(t, out, "delay=200ms")
+
+	out, err = runCtl(t, server, "fault", "list")
+	require.NoError(t, err)
+	require.Contains(t, out, "KIND")
+	require.Contains(t, out, "/api")
+
+	out, err = runCtl(t, server, "fault", "remove", "latency")
+	require.NoError(t, err)
+	require.Contains(t, out, "Fault latency removed")
+
+	out, err = runCtl(t, server, "fault", "list", "-o", "json")
+	require.NoError(t, err)
+	require.JSONEq(t, "[]", out)
+
+	_, err = runCtl(t, server, "fault", "add", "error", "--status-code", "200")
+	require.Error(t, err)
+
+	_, err = runCtl(t, server, "fault", "add")
+	require.Error(t, err)
+}
+
+func TestCtl_ScenarioRun(t *testing.T) {
+	server := startCtlServer(t)
+
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	scenario := `{"steps": [
+		{"at": "0s", "fault": {"kind": "error", "probability": 1, "status_code": 503}},
+		{"at": "10ms", "remove_fault": "error"}
+	]}`
+	require.NoError(t, os.WriteFile(path, []byte(scenario), 0o600))
+
+	start := time.Now()
+	out, err := runCtl(t, server, "scenario", "run", path)
+	require.NoError(t, err)
+	require.GreaterOrEqual(t, time.Since(start), 10*time.Millisecond)
+	require.Contains(t, out, "set error fault")
+	require.Contains(t, out, "remove error fault")
+	require.Equal(t, 2, strings.Count(out, "\n"))
+
+	_, err = runCtl(t, server, "scenario", "run", filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
+
+func TestCtl_Token(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	router := api.NewRouter(
+		logger,
+		api.WithCrash(crash.New(logger, 0)),
+		api.WithAuth(auth.New(auth.WithTokens("secret"))),
+	)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	// read-only requests are open
+	_, err := runCtl(t, srv.URL, "status")
+	require.NoError(t, err)
+
+	_, err = runCtl(t, srv.URL, "crash", "--after", "1h")
+	require.ErrorContains(t, err, "401")
+
+	out, err := runCtl(t, srv.URL, "--token", "secret", "crash", "--after", "1h")
+	require.NoError(t, err)
+	require.Contains(t, out, "exit code 1")
+
+	_, err = runCtl(t, srv.URL, "--token", "secret", "crash", "--cancel")
+	require.NoError(t, err)
+}
+
+func TestNewCtlTLSConfig(t *testing.T) {
+	viper.Reset()
+
+	tlsConfig, err := newCtlTLSConfig()
+	require.NoError(t, err)
+	require.Nil(t, tlsConfig)
+
+	viper.Set("ctl-cert", filepath.Join(t.TempDir(), "missing.pem"))
+	_, err = newCtlTLSConfig()
+	require.ErrorContains(t, err, "invalid client certificate")
+
+	viper.Reset()
+	viper.Set("ctl-ca-cert", filepath.Join(t.TempDir(), "missing.pem"))
+	_, err = newCtlTLSConfig()
+	require.ErrorContains(t, err, "invalid CA certificate")
+}
+
+func TestCtl_TLS(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	manager, err := certs.New(logger, []string{"localhost"})
+	require.NoError(t, err)
+	srv := httptest.NewServer(api.NewRouter(logger, api.WithTLS(manager)))
+	defer srv.Close()
+
+	out, err := runCtl(t, srv.URL, "tls")
+	require.NoError(t, err)
+	require.Contains(t, out, "valid generated certificate")
+	require.Contains(t, out, "crashlooper CA")
+
+	out, err = runCtl(t, srv.URL, "tls", "set", "--mode", "expired", "--rotate-interval", "1h")
+	require.NoError(t, err)
+	require.Contains(t, out, "expired generated certificate, rotated every 1h0m0s")
+
+	out, err = runCtl(t, srv.URL, "status")
+	require.NoError(t, err)
+	require.Contains(t, out, "expired generated certificate")
+
+	_, err = runCtl(t, srv.URL, "tls", "set", "--mode", "revoked")
+	require.Error(t, err)
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import (
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/grpcserver"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// serveGRPC serves the gRPC health and echo services, with the faults of c,
+// on the configured gRPC port until the process exits.
+func serveGRPC(logger *log.DefaultLogger, c *chaos.Controller) {
+	s := grpcserver.New(logger, ":"+viper.GetString("grpc-port"), grpcserver.WithFaults(c))
+
+	if err := s.Start(); err != nil {
+		logger.Fatal("gRPC server stopped", fields.Error(err))
+	}
+}
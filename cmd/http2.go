@@ -0,0 +1,32 @@
+package cmd
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+// withH2C returns a router also serving HTTP/2 without TLS when h2c is enabled.
+// The router is wrapped in another one because the h2c connection preface
+// ("PRI *") must reach the h2c handler before any path cleaning or matching.
+func withH2C(router *mux.Router) *mux.Router {
+	if !viper.GetBool("h2c") {
+		return router
+	}
+
+	h2s := &http2.Server{MaxConcurrentStreams: viper.GetUint32("http2-max-concurrent-streams")}
+
+	outer := mux.NewRouter().SkipClean(true)
+	outer.NewRoute().Handler(h2c.NewHandler(router, h2s))
+	return outer
+}
+
+// http2Config returns the HTTP/2 settings of the HTTPS server.
+func http2Config() *http.HTTP2Config {
+	return &http.HTTP2Config{
+		MaxConcurrentStreams: int(viper.GetUint32("http2-max-concurrent-streams")),
+	}
+}
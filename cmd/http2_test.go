@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+)
+
+// h2cClient only speaks HTTP/2 with prior knowledge over plain TCP.
+func h2cClient() *http.Client {
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	return &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+}
+
+func TestWithH2C(t *testing.T) {
+	viper.Reset()
+	viper.Set("h2c", true)
+
+	router := api.NewRouter(log.New(log.WithLevel("info")))
+	srv := httptest.NewServer(withH2C(router))
+	defer srv.Close()
+
+	resp, err := h2cClient().Get(srv.URL + "/checks/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, resp.ProtoMajor)
+
+	// HTTP/1 is still served
+	resp, err = http.Get(srv.URL + "/checks/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 1, resp.ProtoMajor)
+}
+
+func TestWithH2C_Disabled(t *testing.T) {
+	viper.Reset()
+
+	router := mux.NewRouter()
+	require.Same(t, router, withH2C(router))
+
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	resp, err := h2cClient().Get(srv.URL)
+	if err == nil {
+		resp.Body.Close()
+	}
+	require.Error(t, err)
+}
+
+func TestHTTP2Config(t *testing.T) {
+	viper.Reset()
+	viper.Set("http2-max-concurrent-streams", 10)
+
+	srv := newTLSServer(log.New(log.WithLevel("info")), http.NotFoundHandler(), nil)
+	require.Equal(t, 10, srv.HTTP2.MaxConcurrentStreams)
+}
@@ -0,0 +1,121 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/alecthomas/units"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/job"
+)
+
+// newJobCmd create the job command
+func newJobCmd() (*cobra.Command, error) {
+	return newCompletionCmd(
+		"job",
+		"Do some work then exit with a controlled code, to test Jobs and CronJobs",
+		`Allocate --allocate memory, burn CPU for --cpu-burn and sleep for --sleep,
+then exit with --exit-code. --fail-attempts fails the first attempts to test
+the backoffLimit, and --hang never exits to test the activeDeadlineSeconds
+and the CronJob concurrency policy. No HTTP server is started.`,
+	)
+}
+
+// newInitCmd create the init command
+func newInitCmd() (*cobra.Command, error) {
+	return newCompletionCmd(
+		"init",
+		"Do some work then exit with a controlled code, as an init container",
+		`Allocate --allocate memory, burn CPU for --cpu-burn and sleep for --sleep,
+then exit with --exit-code, delaying or failing the start of the pod.
+--fail-attempts fails the first attempts, with the attempt file on an
+emptyDir volume, and --hang never exits. No HTTP server is started.`,
+	)
+}
+
+// newCompletionCmd creates the job or init command, named mode.
+func newCompletionCmd(mode, short, long string) (*cobra.Command, error) {
+	cmd := &cobra.Command{
+		Use:   mode,
+		Short: short,
+		Long:  long,
+		RunE: func(c *cobra.Command, args []string) error {
+			return startCompletion(mode)
+		},
+	}
+
+	cmd.Flags().String("allocate", "", "Memory allocated and held until the exit (e.g. 256MiB)")
+	cmd.Flags().Duration("cpu-burn", 0, "Time spent burning CPU")
+	cmd.Flags().Int("cpu-workers", 1, "Number of CPUs burnt")
+	cmd.Flags().Duration("sleep", 0, "Time spent sleeping after the CPU burn")
+	cmd.Flags().Int("exit-code", 0, "Exit code of the successful attempts")
+	cmd.Flags().Int("fail-attempts", 0, "Number of first attempts failing, counted in --attempt-file")
+	cmd.Flags().Int("fail-exit-code", 1, "Exit code of the failing attempts")
+	cmd.Flags().String("attempt-file", "", "File counting the attempts, on a volume outliving the container")
+	cmd.Flags().Bool("hang", false, "Never exit after the work of a successful attempt, until killed")
+
+	// the viper keys are prefixed to not collide with the other commands flags
+	for _, name := range []string{
+		"allocate", "cpu-burn", "cpu-workers", "sleep", "exit-code",
+		"fail-attempts", "fail-exit-code", "attempt-file", "hang",
+	} {
+		if err := viper.BindPFlag(mode+"-"+name, cmd.Flags().Lookup(name)); err != nil {
+			return nil, err
+		}
+	}
+
+	return cmd, nil
+}
+
+func startCompletion(mode string) error {
+	cfg, err := completionConfig(mode)
+	if err != nil {
+		return err
+	}
+
+	pod, err := loadPod()
+	if err != nil {
+		return err
+	}
+	logger := newLogger(pod)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	code, err := job.New(logger, mode, cfg).Run(ctx)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+// completionConfig returns the configuration of the job or init mode, named
+// mode, set by the flags.
+func completionConfig(mode string) (job.Config, error) {
+	cfg := job.Config{
+		CPUBurn:      viper.GetDuration(mode + "-cpu-burn"),
+		CPUWorkers:   viper.GetInt(mode + "-cpu-workers"),
+		Sleep:        viper.GetDuration(mode + "-sleep"),
+		ExitCode:     viper.GetInt(mode + "-exit-code"),
+		FailAttempts: viper.GetInt(mode + "-fail-attempts"),
+		FailExitCode: viper.GetInt(mode + "-fail-exit-code"),
+		AttemptFile:  viper.GetString(mode + "-attempt-file"),
+		Hang:         viper.GetBool(mode + "-hang"),
+	}
+	if allocate := viper.GetString(mode + "-allocate"); allocate != "" {
+		size, err := units.ParseBase2Bytes(allocate)
+		if err != nil {
+			return job.Config{}, errors.Wrap(err, "invalid allocate")
+		}
+		cfg.Allocate = size
+	}
+	return cfg, cfg.Validate()
+}
@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewCompletionCmds(t *testing.T) {
+	for mode, newCmd := range map[string]func() (*cobra.Command, error){"job": newJobCmd, "init": newInitCmd} {
+		viper.Reset()
+
+		cmd, err := newCmd()
+		require.NoError(t, err)
+		require.Equal(t, mode, cmd.Use)
+		require.NotNil(t, cmd.RunE)
+
+		tests := map[string]string{
+			"allocate":       "",
+			"cpu-burn":       "0s",
+			"cpu-workers":    "1",
+			"sleep":          "0s",
+			"exit-code":      "0",
+			"fail-attempts":  "0",
+			"fail-exit-code": "1",
+			"attempt-file":   "",
+			"hang":           "false",
+		}
+		for name, def := range tests {
+			flag := cmd.Flags().Lookup(name)
+			require.NotNil(t, flag, name)
+			require.Equal(t, def, flag.DefValue, name)
+		}
+	}
+}
+
+func TestCompletionConfig(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := newJobCmd()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Flags().Set("allocate", "64MiB"))
+	require.NoError(t, cmd.Flags().Set("sleep", "30s"))
+	require.NoError(t, cmd.Flags().Set("fail-attempts", "2"))
+	require.NoError(t, cmd.Flags().Set("attempt-file", "/attempts/count"))
+
+	cfg, err := completionConfig("job")
+	require.NoError(t, err)
+	require.Equal(t, 64*units.MiB, cfg.Allocate)
+	require.Equal(t, 30*time.Second, cfg.Sleep)
+	require.Equal(t, 2, cfg.FailAttempts)
+	require.Equal(t, 1, cfg.FailExitCode)
+	require.Equal(t, "/attempts/count", cfg.AttemptFile)
+
+	// the flags of the init mode are bound to their own keys
+	_, err = completionConfig("init")
+	require.NoError(t, err)
+
+	require.NoError(t, cmd.Flags().Set("allocate", "lots"))
+	_, err = completionConfig("job")
+	require.Error(t, err)
+
+	require.NoError(t, cmd.Flags().Set("allocate", ""))
+	require.NoError(t, cmd.Flags().Set("attempt-file", ""))
+	_, err = completionConfig("job")
+	require.Error(t, err)
+	viper.Reset()
+}
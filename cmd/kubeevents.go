@@ -0,0 +1,31 @@
+package cmd
+
+import (
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/kubeevents"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+// newKubeEvents returns the event bus options recording the fault events as
+// Kubernetes Events of the pod, none when disabled by the flags.
+func newKubeEvents(logger *log.DefaultLogger, pod *podinfo.Info) ([]events.Option, error) {
+	if !viper.GetBool("kube-events") {
+		return nil, nil
+	}
+
+	client, err := newKubeClient()
+	if err != nil {
+		return nil, err
+	}
+
+	r, err := kubeevents.New(logger, client, pod, viper.GetDuration("kube-events-timeout"))
+	if err != nil {
+		return nil, err
+	}
+
+	go r.Start()
+	return []events.Option{events.WithHook(r.Record)}, nil
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+func TestNewKubeEvents(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	pod := &podinfo.Info{Name: "crashlooper-7d9f", Namespace: "crashlooper"}
+
+	viper.Reset()
+	opts, err := newKubeEvents(logger, pod)
+	require.NoError(t, err)
+	require.Empty(t, opts)
+
+	// outside of a cluster
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	viper.Set("kube-events", true)
+	_, err = newKubeEvents(logger, pod)
+	require.Error(t, err)
+	viper.Reset()
+}
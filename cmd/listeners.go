@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+)
+
+// listeners returns the groups of routes served on each port. The groups
+// without a dedicated port are served on the bind port, with the application
+// routes. Groups sharing a dedicated port are served by the same listener.
+func listeners() map[string]api.Routes {
+	port := viper.GetString("port")
+	ls := map[string]api.Routes{}
+
+	main := api.AllRoutes
+	for _, l := range []struct {
+		flag   string
+		routes api.Routes
+	}{
+		{"probe-port", api.ProbeRoutes},
+		{"metrics-port", api.MetricsRoutes},
+		{"admin-port", api.AdminRoutes},
+	} {
+		p := viper.GetString(l.flag)
+		if p == "" || p == port {
+			continue
+		}
+		ls[p] |= l.routes
+		main &^= l.routes
+	}
+	ls[port] = main
+
+	return ls
+}
+
+// adminPort returns the port serving the control API.
+func adminPort() string {
+	if p := viper.GetString("admin-port"); p != "" {
+		return p
+	}
+	return viper.GetString("port")
+}
+
+// serveHTTP serves handler over HTTP on port until the process exits.
+func serveHTTP(logger log.Logger, port string, handler http.Handler) {
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	logger.Info("Serving HTTP", fields.String("addr", srv.Addr))
+
+	if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		logger.Fatal("HTTP server stopped", fields.Error(err))
+	}
+}
@@ -0,0 +1,63 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+)
+
+func TestListeners(t *testing.T) {
+	tests := []struct {
+		name     string
+		ports    map[string]string
+		expected map[string]api.Routes
+	}{
+		{
+			name:     "single port",
+			ports:    map[string]string{},
+			expected: map[string]api.Routes{"3000": api.AllRoutes},
+		},
+		{
+			name:  "dedicated ports",
+			ports: map[string]string{"probe-port": "8081", "metrics-port": "9090", "admin-port": "8080"},
+			expected: map[string]api.Routes{
+				"3000": api.AppRoutes,
+				"8081": api.ProbeRoutes,
+				"9090": api.MetricsRoutes,
+				"8080": api.AdminRoutes,
+			},
+		},
+		{
+			name:  "shared ports",
+			ports: map[string]string{"probe-port": "8081", "metrics-port": "8081", "admin-port": "3000"},
+			expected: map[string]api.Routes{
+				"3000": api.AppRoutes | api.AdminRoutes,
+				"8081": api.ProbeRoutes | api.MetricsRoutes,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viper.Reset()
+			viper.Set("port", "3000")
+			for flag, port := range tt.ports {
+				viper.Set(flag, port)
+			}
+
+			require.Equal(t, tt.expected, listeners())
+		})
+	}
+}
+
+func TestAdminPort(t *testing.T) {
+	viper.Reset()
+	viper.Set("port", "3000")
+	require.Equal(t, "3000", adminPort())
+
+	viper.Set("admin-port", "8080")
+	require.Equal(t, "8080", adminPort())
+	viper.Reset()
+}
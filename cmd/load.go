@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+	"github.com/pixelfactoryio/crashlooper/internal/services/load"
+)
+
+// newLoadCmd create the load command
+func newLoadCmd(serverFlags *pflag.FlagSet) (*cobra.Command, error) {
+	loadCmd := &cobra.Command{
+		Use:   "load",
+		Short: "Send HTTP requests to a target at a controlled rate",
+		Long: `Send HTTP requests to --target at a constant --rate for --duration, or
+following the --ramp schedule. Latency histograms and error counts are logged
+every --report-interval and exposed on /metrics served on --port.`,
+		RunE: startLoad,
+	}
+
+	loadCmd.Flags().String("target", "", "URL requests are sent to (e.g. http://my-service:8080/)")
+	if err := viper.BindPFlag("load-target", loadCmd.Flags().Lookup("target")); err != nil {
+		return nil, err
+	}
+
+	loadCmd.Flags().String("method", "GET", "HTTP method of the requests")
+	if err := viper.BindPFlag("load-method", loadCmd.Flags().Lookup("method")); err != nil {
+		return nil, err
+	}
+
+	loadCmd.Flags().Float64("rate", 10, "Requests per second")
+	if err := viper.BindPFlag("load-rate", loadCmd.Flags().Lookup("rate")); err != nil {
+		return nil, err
+	}
+
+	loadCmd.Flags().Duration("duration", time.Minute, "Duration of the load (0 means forever)")
+	if err := viper.BindPFlag("load-duration", loadCmd.Flags().Lookup("duration")); err != nil {
+		return nil, err
+	}
+
+	loadCmd.Flags().String("ramp", "", "Ramp schedule of duration:rate stages, overrides --rate and --duration (e.g. 30s:10,2m:10,30s:100)")
+	if err := viper.BindPFlag("load-ramp", loadCmd.Flags().Lookup("ramp")); err != nil {
+		return nil, err
+	}
+
+	loadCmd.Flags().Int("concurrency", 10, "Maximum number of requests in flight")
+	if err := viper.BindPFlag("load-concurrency", loadCmd.Flags().Lookup("concurrency")); err != nil {
+		return nil, err
+	}
+
+	loadCmd.Flags().Duration("timeout", 5*time.Second, "Timeout of each request")
+	if err := viper.BindPFlag("load-timeout", loadCmd.Flags().Lookup("timeout")); err != nil {
+		return nil, err
+	}
+
+	loadCmd.Flags().Duration("report-interval", 10*time.Second, "Interval between load reports (0 means only the final summary)")
+	if err := viper.BindPFlag("load-report-interval", loadCmd.Flags().Lookup("report-interval")); err != nil {
+		return nil, err
+	}
+
+	// the metrics are served on the bind port of the server
+	loadCmd.Flags().AddFlag(serverFlags.Lookup("port"))
+
+	return loadCmd, nil
+}
+
+func startLoad(c *cobra.Command, args []string) error {
+	target, err := parseTarget(viper.GetString("load-target"))
+	if err != nil {
+		return err
+	}
+
+	stages, err := loadStages()
+	if err != nil {
+		return err
+	}
+
+	concurrency := viper.GetInt("load-concurrency")
+	if concurrency < 1 {
+		return errors.New("concurrency must be at least 1")
+	}
+
+	pod, err := loadPod()
+	if err != nil {
+		return err
+	}
+	logger := newLogger(pod)
+
+	l := load.New(
+		logger,
+		target.String(),
+		viper.GetString("load-method"),
+		concurrency,
+		stages,
+		viper.GetDuration("load-timeout"),
+		viper.GetDuration("load-report-interval"),
+		prometheus.DefaultRegisterer,
+	)
+
+	// serve the metrics while the load is running
+	go serveHTTP(logger, viper.GetString("port"), newLoadRouter(logger, pod))
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	l.Run(ctx)
+	return nil
+}
+
+// loadStages returns the ramp schedule, or a constant rate schedule when no ramp is set.
+func loadStages() ([]load.Stage, error) {
+	if ramp := viper.GetString("load-ramp"); ramp != "" {
+		return load.ParseStages(ramp)
+	}
+
+	rate := viper.GetFloat64("load-rate")
+	if rate <= 0 {
+		return nil, errors.New("rate must be positive")
+	}
+
+	duration := viper.GetDuration("load-duration")
+	if duration < 0 {
+		return nil, errors.New("duration must not be negative")
+	}
+
+	return load.ConstantStages(rate, duration), nil
+}
+
+func parseTarget(rawURL string) (*url.URL, error) {
+	if rawURL == "" {
+		return nil, errors.New("a target is required")
+	}
+
+	target, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid target")
+	}
+
+	if target.Scheme != "http" && target.Scheme != "https" || target.Host == "" {
+		return nil, errors.Errorf("invalid target %q, expected an http(s) URL", rawURL)
+	}
+
+	return target, nil
+}
+
+// newLoadRouter returns the router of the load generator, serving only the
+// metrics as the control API has no service to drive.
+func newLoadRouter(logger *log.DefaultLogger, pod *podinfo.Info) http.Handler {
+	return api.NewRouter(logger, api.WithPod(pod), api.WithRoutes(api.MetricsRoutes))
+}
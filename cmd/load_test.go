@@ -0,0 +1,120 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+	"github.com/pixelfactoryio/crashlooper/internal/services/load"
+)
+
+func TestNewLoadCmd(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := newLoadCmd(testServerFlags(t))
+
+	require.NoError(t, err)
+	require.NotNil(t, cmd)
+	require.Equal(t, "load", cmd.Use)
+	require.NotNil(t, cmd.RunE)
+
+	tests := map[string]string{
+		"target":          "",
+		"method":          "GET",
+		"rate":            "10",
+		"duration":        "1m0s",
+		"ramp":            "",
+		"concurrency":     "10",
+		"timeout":         "5s",
+		"report-interval": "10s",
+		"port":            "3000",
+	}
+	for name, def := range tests {
+		flag := cmd.Flags().Lookup(name)
+		require.NotNil(t, flag, name)
+		require.Equal(t, def, flag.DefValue, name)
+	}
+}
+
+func TestNewLoadCmd_FlagBinding(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := newLoadCmd(testServerFlags(t))
+	require.NoError(t, err)
+
+	require.NoError(t, cmd.Flags().Set("target", "http://localhost:8080"))
+	require.NoError(t, cmd.Flags().Set("rate", "50"))
+	require.NoError(t, cmd.Flags().Set("ramp", "30s:10"))
+	require.NoError(t, cmd.Flags().Set("concurrency", "4"))
+
+	require.Equal(t, "http://localhost:8080", viper.GetString("load-target"))
+	require.Equal(t, float64(50), viper.GetFloat64("load-rate"))
+	require.Equal(t, "30s:10", viper.GetString("load-ramp"))
+	require.Equal(t, 4, viper.GetInt("load-concurrency"))
+}
+
+func TestLoadStages(t *testing.T) {
+	viper.Reset()
+
+	_, err := newLoadCmd(testServerFlags(t))
+	require.NoError(t, err)
+
+	stages, err := loadStages()
+	require.NoError(t, err)
+	require.Equal(t, load.ConstantStages(10, time.Minute), stages)
+
+	viper.Set("load-ramp", "30s:10,1m:100")
+	stages, err = loadStages()
+	require.NoError(t, err)
+	require.Equal(t, []load.Stage{{Duration: 30 * time.Second, Rate: 10}, {Duration: time.Minute, Rate: 100}}, stages)
+
+	viper.Set("load-ramp", "invalid")
+	_, err = loadStages()
+	require.Error(t, err)
+
+	viper.Set("load-ramp", "")
+	viper.Set("load-rate", 0)
+	_, err = loadStages()
+	require.Error(t, err)
+}
+
+func TestParseTarget(t *testing.T) {
+	target, err := parseTarget("http://localhost:8080/path")
+	require.NoError(t, err)
+	require.Equal(t, "localhost:8080", target.Host)
+
+	for _, s := range []string{"", "localhost:8080", "ftp://localhost", "http://", "://bad"} {
+		_, err := parseTarget(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestStartLoad_RequiresTarget(t *testing.T) {
+	viper.Reset()
+
+	_, err := newLoadCmd(testServerFlags(t))
+	require.NoError(t, err)
+
+	require.Error(t, startLoad(nil, nil))
+}
+
+func TestNewLoadRouter(t *testing.T) {
+	router := newLoadRouter(log.New(log.WithLevel("info")), &podinfo.Info{})
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// the control API and the dashboard are not served
+	for _, path := range []string{"/api/status", "/api/crash", "/events", "/"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, http.StatusNotFound, rec.Code, path)
+	}
+}
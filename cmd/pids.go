@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// pidsSettings returns the threads or processes target set by the flags.
+func pidsSettings() (pids.Settings, error) {
+	settings := pids.Settings{
+		Mode:      pids.Mode(viper.GetString("pids-mode")),
+		Target:    viper.GetInt("pids-target"),
+		Increment: viper.GetInt("pids-increment"),
+		Interval:  chaos.Duration(viper.GetDuration("pids-interval")),
+	}
+	switch settings.Mode {
+	case pids.ModeThreads, pids.ModeProcesses:
+	default:
+		return pids.Settings{}, errors.Errorf("unknown pids mode %q, must be one of threads or processes", settings.Mode)
+	}
+	if settings.Target < 0 || settings.Increment < 0 || settings.Interval < 0 {
+		return pids.Settings{}, errors.New("the pids target, increment and interval must not be negative")
+	}
+	if settings.Target > 0 && (settings.Increment == 0 || time.Duration(settings.Interval) == 0) {
+		return pids.Settings{}, errors.New("the pids target requires an increment and an interval")
+	}
+	return settings, nil
+}
+
+// pidsOptions returns the options of the pids service, the processes being
+// crashlooper children of the supervisor sleeping until they are killed.
+func pidsOptions(bus events.Publisher) ([]pids.Option, error) {
+	opts := []pids.Option{pids.WithEvents(bus), pids.WithCgroupRoot(viper.GetString("cgroup-root"))}
+
+	command, err := sleeperCommand()
+	if err != nil {
+		return nil, err
+	}
+	if command != nil {
+		opts = append(opts, pids.WithCommand(command))
+	} else if viper.GetString("pids-mode") == string(pids.ModeProcesses) {
+		return nil, errors.New("the pids processes mode requires a unix system")
+	}
+	return opts, nil
+}
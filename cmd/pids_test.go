@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+func TestPidsSettings(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := NewRootCmd()
+	require.NoError(t, err)
+
+	// the defaults spawn nothing
+	settings, err := pidsSettings()
+	require.NoError(t, err)
+	require.Equal(t, pids.Settings{Mode: pids.ModeThreads, Increment: 10, Interval: chaos.Duration(time.Second)}, settings)
+
+	require.NoError(t, cmd.Flags().Set("pids-mode", "processes"))
+	require.NoError(t, cmd.Flags().Set("pids-target", "200"))
+	settings, err = pidsSettings()
+	require.NoError(t, err)
+	require.Equal(t, pids.ModeProcesses, settings.Mode)
+	require.Equal(t, 200, settings.Target)
+
+	require.NoError(t, cmd.Flags().Set("pids-increment", "0"))
+	_, err = pidsSettings()
+	require.Error(t, err)
+
+	require.NoError(t, cmd.Flags().Set("pids-mode", "forks"))
+	_, err = pidsSettings()
+	require.Error(t, err)
+}
+
+func TestPidsOptions(t *testing.T) {
+	viper.Reset()
+
+	opts, err := pidsOptions(events.NewBus())
+	require.NoError(t, err)
+	if runtime.GOOS == "windows" {
+		require.Len(t, opts, 2)
+		return
+	}
+	// the processes are spawned as sleeping children of the supervisor
+	require.Len(t, opts, 3)
+
+	command, err := sleeperCommand()
+	require.NoError(t, err)
+	require.Equal(t, []string{"supervisor", "child", "--behavior", "exit", "--lifetime", "0s", "--exit-code", "0"}, command[1:])
+}
@@ -0,0 +1,109 @@
+package cmd
+
+import (
+	"net/url"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
+)
+
+// newProxyCmd create the proxy command
+func newProxyCmd(serverFlags *pflag.FlagSet) (*cobra.Command, error) {
+	proxyCmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Forward requests to an upstream while injecting faults",
+		Long: `Forward every request to an upstream service while applying the faults
+set through the /api/faults control API. Only the health checks are served by
+crashlooper on --port, the control API, the event stream, the dashboard and
+the metrics are served on --admin-port (3001 by default).`,
+		RunE: startProxy,
+	}
+
+	proxyCmd.Flags().String("upstream", "", "Upstream URL requests are forwarded to (e.g. http://localhost:8080)")
+	if err := viper.BindPFlag("upstream", proxyCmd.Flags().Lookup("upstream")); err != nil {
+		return nil, err
+	}
+
+	proxyCmd.Flags().Bool("preserve-host", false, "Forward the Host header of the client instead of the one of the upstream")
+	if err := viper.BindPFlag("proxy-preserve-host", proxyCmd.Flags().Lookup("preserve-host")); err != nil {
+		return nil, err
+	}
+
+	proxyCmd.Flags().AddFlagSet(serverFlags)
+
+	return proxyCmd, nil
+}
+
+// defaultProxyAdminPort serves the routes of crashlooper in proxy mode when
+// --admin-port is not set.
+const defaultProxyAdminPort = "3001"
+
+func startProxy(c *cobra.Command, args []string) error {
+	upstream, err := parseUpstream(viper.GetString("upstream"))
+	if err != nil {
+		return err
+	}
+	if err := setProxyPorts(); err != nil {
+		return err
+	}
+
+	pod, err := loadPod()
+	if err != nil {
+		return err
+	}
+	logger := newLogger(pod)
+	logger.Info("Proxying requests", fields.String("upstream", upstream.String()))
+
+	svcs, err := startServices(logger, pod)
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(logger, svcs.tracerProvider)
+	routerOpts := append(svcs.routerOpts, api.WithAppHandler(handlers.NewProxyHandler(logger, upstream, viper.GetBool("proxy-preserve-host"))))
+
+	return serve(logger, routerOpts, svcs.tlsConfig)
+}
+
+func parseUpstream(rawURL string) (*url.URL, error) {
+	if rawURL == "" {
+		return nil, errors.New("an upstream is required")
+	}
+
+	upstream, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid upstream")
+	}
+
+	if upstream.Scheme != "http" && upstream.Scheme != "https" || upstream.Host == "" {
+		return nil, errors.Errorf("invalid upstream %q, expected an http(s) URL", rawURL)
+	}
+
+	return upstream, nil
+}
+
+// setProxyPorts moves the control API and the metrics off the bind port, so
+// that every path but the health checks is forwarded to the upstream.
+func setProxyPorts() error {
+	port := viper.GetString("port")
+	admin := viper.GetString("admin-port")
+	if admin == "" {
+		admin = defaultProxyAdminPort
+		viper.Set("admin-port", admin)
+	}
+	metrics := viper.GetString("metrics-port")
+	if metrics == "" {
+		metrics = admin
+		viper.Set("metrics-port", metrics)
+	}
+
+	if admin == port || metrics == port {
+		return errors.New("the proxy forwards the bind port, --admin-port and --metrics-port must differ from --port")
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+)
+
+func TestNewProxyCmd(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := newProxyCmd(testServerFlags(t))
+
+	require.NoError(t, err)
+	require.NotNil(t, cmd)
+	require.Equal(t, "proxy", cmd.Use)
+	require.NotNil(t, cmd.RunE)
+
+	flag := cmd.Flags().Lookup("upstream")
+	require.NotNil(t, flag)
+	require.Equal(t, "string", flag.Value.Type())
+	require.Equal(t, "", flag.DefValue)
+
+	flag = cmd.Flags().Lookup("preserve-host")
+	require.NotNil(t, flag)
+	require.Equal(t, "bool", flag.Value.Type())
+	require.Equal(t, "false", flag.DefValue)
+}
+
+func TestNewRootCmd_HasProxyCmd(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := NewRootCmd()
+	require.NoError(t, err)
+
+	proxyCmd, _, err := cmd.Find([]string{"proxy"})
+	require.NoError(t, err)
+	require.Equal(t, "proxy", proxyCmd.Use)
+
+	// the server flags are shared with the root command
+	require.Nil(t, proxyCmd.InheritedFlags().Lookup("port"))
+	require.NoError(t, proxyCmd.Flags().Set("port", "8080"))
+	require.Equal(t, "8080", viper.GetString("port"))
+	require.NotNil(t, proxyCmd.InheritedFlags().Lookup("log-level"))
+
+	// and not listed by the other commands
+	ctlCmd, _, err := cmd.Find([]string{"ctl"})
+	require.NoError(t, err)
+	require.Nil(t, ctlCmd.Flags().Lookup("port"))
+	require.Nil(t, ctlCmd.InheritedFlags().Lookup("port"))
+}
+
+func TestParseUpstream(t *testing.T) {
+	tests := []struct {
+		name     string
+		upstream string
+		expected string
+		wantErr  bool
+	}{
+		{name: "http upstream", upstream: "http://localhost:8080", expected: "http://localhost:8080"},
+		{name: "https upstream with path", upstream: "https://api.internal/v1", expected: "https://api.internal/v1"},
+		{name: "empty upstream", upstream: "", wantErr: true},
+		{name: "missing scheme", upstream: "localhost:8080", wantErr: true},
+		{name: "unsupported scheme", upstream: "tcp://localhost:8080", wantErr: true},
+		{name: "malformed", upstream: "http://[::1", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			upstream, err := parseUpstream(tt.upstream)
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, upstream.String())
+		})
+	}
+}
+
+func TestSetProxyPorts(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("port", "3000")
+
+	require.NoError(t, setProxyPorts())
+	require.Equal(t, "3001", viper.GetString("admin-port"))
+	require.Equal(t, "3001", viper.GetString("metrics-port"))
+	// only the application routes and the health checks are served on the bind port
+	require.Equal(t, api.AppRoutes|api.ProbeRoutes, listeners()["3000"])
+
+	viper.Set("metrics-port", "9090")
+	require.NoError(t, setProxyPorts())
+	require.Equal(t, "9090", viper.GetString("metrics-port"))
+
+	viper.Set("admin-port", "3000")
+	require.Error(t, setProxyPorts())
+}
+
+func TestProxy_ForwardsReservedPaths(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("upstream " + r.URL.Path))
+	}))
+	defer upstream.Close()
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("port", "3000")
+	require.NoError(t, setProxyPorts())
+
+	logger := log.New(log.WithLevel("info"))
+	router := api.NewRouter(
+		logger,
+		api.WithRoutes(listeners()["3000"]),
+		api.WithCrash(crash.New(logger, 0)),
+		api.WithAppHandler(handlers.NewProxyHandler(logger, upstreamURL, false)),
+	)
+
+	for _, path := range []string{"/metrics", "/api/crash", "/events", "/"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, "upstream "+path, rec.Body.String())
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checks/health", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.NotContains(t, rec.Body.String(), "upstream")
+}
@@ -1,22 +1,40 @@
 package cmd
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
 	"strings"
 	"time"
 
 	"github.com/alecthomas/units"
+	"github.com/gorilla/mux"
 	"github.com/pkg/errors"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
 	"go.pixelfactory.io/pkg/observability/log"
 	"go.pixelfactory.io/pkg/observability/log/fields"
 	"go.pixelfactory.io/pkg/server"
 	"go.pixelfactory.io/pkg/version"
+	"go.uber.org/zap/zapcore"
 
 	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/audit"
+	"github.com/pixelfactoryio/crashlooper/internal/auth"
+	"github.com/pixelfactoryio/crashlooper/internal/cgroup"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
 	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
 	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/internal/services/oom"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/internal/tracing"
+	"github.com/pixelfactoryio/crashlooper/internal/webhook"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
 )
 
 // Version is set by GoReleaser via ldflags
@@ -49,31 +67,308 @@ func NewRootCmd() (*cobra.Command, error) {
 		return nil, err
 	}
 
-	rootCmd.PersistentFlags().String("port", "3000", "Server bind port")
-	if err := viper.BindPFlag("port", rootCmd.PersistentFlags().Lookup("port")); err != nil {
+	rootCmd.PersistentFlags().String("podinfo-dir", podinfo.DefaultDir, "Kubernetes downward API volume describing the pod, the POD_NAME, POD_NAMESPACE, POD_UID, NODE_NAME and POD_IP variables take precedence")
+	if err := viper.BindPFlag("podinfo-dir", rootCmd.PersistentFlags().Lookup("podinfo-dir")); err != nil {
 		return nil, err
 	}
 
-	rootCmd.PersistentFlags().String("memory-target", "", "crashlooper memory usage target")
-	if err := viper.BindPFlag("memory-target", rootCmd.PersistentFlags().Lookup("memory-target")); err != nil {
+	rootCmd.Flags().String("port", "3000", "Server bind port")
+	if err := viper.BindPFlag("port", rootCmd.Flags().Lookup("port")); err != nil {
 		return nil, err
 	}
 
-	rootCmd.PersistentFlags().String("memory-increment", "", "crashlooper memory usage increment")
-	if err := viper.BindPFlag("memory-increment", rootCmd.PersistentFlags().Lookup("memory-increment")); err != nil {
+	rootCmd.Flags().String("memory-target", "", "crashlooper memory usage target")
+	if err := viper.BindPFlag("memory-target", rootCmd.Flags().Lookup("memory-target")); err != nil {
 		return nil, err
 	}
 
-	rootCmd.PersistentFlags().Duration("memory-increment-interval", 1*time.Second, "crashlooper memory usage increment interval")
-	if err := viper.BindPFlag("memory-increment-interval", rootCmd.PersistentFlags().Lookup("memory-increment-interval")); err != nil {
+	rootCmd.Flags().String("memory-increment", "", "crashlooper memory usage increment")
+	if err := viper.BindPFlag("memory-increment", rootCmd.Flags().Lookup("memory-increment")); err != nil {
 		return nil, err
 	}
 
-	rootCmd.PersistentFlags().Duration("crash-after", 0, "Server will crash itself after specified period (default=0 means never)")
-	if err := viper.BindPFlag("crash-after", rootCmd.PersistentFlags().Lookup("crash-after")); err != nil {
+	rootCmd.Flags().Duration("memory-increment-interval", 1*time.Second, "crashlooper memory usage increment interval")
+	if err := viper.BindPFlag("memory-increment-interval", rootCmd.Flags().Lookup("memory-increment-interval")); err != nil {
 		return nil, err
 	}
 
+	rootCmd.Flags().Duration("memory-watch-interval", 1*time.Second, "cgroup memory events polling interval (0 means disabled)")
+	if err := viper.BindPFlag("memory-watch-interval", rootCmd.Flags().Lookup("memory-watch-interval")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("pids-mode", string(pids.ModeThreads), "Spawn threads or processes to reach the pids target")
+	if err := viper.BindPFlag("pids-mode", rootCmd.Flags().Lookup("pids-mode")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Int("pids-target", 0, "Number of threads or processes spawned, up to the cgroup pids limit (default=0 means none)")
+	if err := viper.BindPFlag("pids-target", rootCmd.Flags().Lookup("pids-target")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Int("pids-increment", 10, "Number of threads or processes spawned per interval")
+	if err := viper.BindPFlag("pids-increment", rootCmd.Flags().Lookup("pids-increment")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Duration("pids-interval", 1*time.Second, "Threads or processes spawn interval")
+	if err := viper.BindPFlag("pids-interval", rootCmd.Flags().Lookup("pids-interval")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("cgroup-root", cgroup.DefaultRoot, "cgroup filesystem mount point")
+	if err := viper.BindPFlag("cgroup-root", rootCmd.Flags().Lookup("cgroup-root")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Duration("crash-after", 0, "Server will crash itself after specified period (default=0 means never)")
+	if err := viper.BindPFlag("crash-after", rootCmd.Flags().Lookup("crash-after")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("auth-token", "", "Bearer token required to modify the state through the control API")
+	if err := viper.BindPFlag("auth-token", rootCmd.Flags().Lookup("auth-token")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("auth-token-file", "", "File listing the bearer tokens required to modify the state through the control API, one per line")
+	if err := viper.BindPFlag("auth-token-file", rootCmd.Flags().Lookup("auth-token-file")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("auth-client-ca", "", "PEM certificate authorities of the client certificates accepted by the control API")
+	if err := viper.BindPFlag("auth-client-ca", rootCmd.Flags().Lookup("auth-client-ca")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("tls-port", "", "HTTPS bind port (default empty means disabled)")
+	if err := viper.BindPFlag("tls-port", rootCmd.Flags().Lookup("tls-port")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("tls-cert", "", "PEM certificate served over HTTPS (default is a certificate signed by a generated CA)")
+	if err := viper.BindPFlag("tls-cert", rootCmd.Flags().Lookup("tls-cert")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("tls-key", "", "PEM private key of the certificate served over HTTPS")
+	if err := viper.BindPFlag("tls-key", rootCmd.Flags().Lookup("tls-key")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().StringSlice("tls-hosts", []string{"localhost", "127.0.0.1"}, "Names and IP addresses of the generated certificates")
+	if err := viper.BindPFlag("tls-hosts", rootCmd.Flags().Lookup("tls-hosts")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("tls-mode", string(certs.ModeValid), "Certificate served over HTTPS (valid, expired, not-yet-valid, wrong-host or self-signed)")
+	if err := viper.BindPFlag("tls-mode", rootCmd.Flags().Lookup("tls-mode")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Duration("tls-rotate-interval", 0, "Certificate rotation interval (default=0 means never)")
+	if err := viper.BindPFlag("tls-rotate-interval", rootCmd.Flags().Lookup("tls-rotate-interval")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Bool("h2c", false, "Serve HTTP/2 without TLS (h2c) on the bind port, in addition to HTTP/1")
+	if err := viper.BindPFlag("h2c", rootCmd.Flags().Lookup("h2c")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Uint32("http2-max-concurrent-streams", 0, "Maximum number of concurrent HTTP/2 streams per connection (default=0 means 250)")
+	if err := viper.BindPFlag("http2-max-concurrent-streams", rootCmd.Flags().Lookup("http2-max-concurrent-streams")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("grpc-port", "", "gRPC bind port of the health and echo services (default empty means disabled)")
+	if err := viper.BindPFlag("grpc-port", rootCmd.Flags().Lookup("grpc-port")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("otlp-endpoint", "", "OpenTelemetry collector URL the traces are exported to, such as http://localhost:4317 (default empty means disabled)")
+	if err := viper.BindPFlag("otlp-endpoint", rootCmd.Flags().Lookup("otlp-endpoint")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("otlp-protocol", string(tracing.ProtocolGRPC), "OTLP protocol of the trace export (grpc or http)")
+	if err := viper.BindPFlag("otlp-protocol", rootCmd.Flags().Lookup("otlp-protocol")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Float64("trace-sample-ratio", 1, "Ratio of the traces started by crashlooper that are sampled")
+	if err := viper.BindPFlag("trace-sample-ratio", rootCmd.Flags().Lookup("trace-sample-ratio")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("audit-log", "", "File the fault audit log is appended to, - for stdout (default empty means disabled)")
+	if err := viper.BindPFlag("audit-log", rootCmd.Flags().Lookup("audit-log")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("audit-format", string(audit.FormatJSON), "Format of the fault audit log (json or ecs)")
+	if err := viper.BindPFlag("audit-format", rootCmd.Flags().Lookup("audit-format")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().StringSlice("webhook-url", nil, "Webhooks the fault events are posted to (default empty means disabled)")
+	if err := viper.BindPFlag("webhook-url", rootCmd.Flags().Lookup("webhook-url")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().StringArray("webhook-header", nil, "Header added to the webhook requests, as \"Name: value\" (repeatable)")
+	if err := viper.BindPFlag("webhook-header", rootCmd.Flags().Lookup("webhook-header")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("webhook-template", webhook.DefaultTemplate, "Go template of the webhook request body, executed with the event")
+	if err := viper.BindPFlag("webhook-template", rootCmd.Flags().Lookup("webhook-template")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().StringSlice("webhook-phases", []string{"scheduled", "started", "triggered"}, "Fault event types posted to the webhooks, crashes are always posted before exiting")
+	if err := viper.BindPFlag("webhook-phases", rootCmd.Flags().Lookup("webhook-phases")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Duration("webhook-timeout", 5*time.Second, "Timeout of each webhook request")
+	if err := viper.BindPFlag("webhook-timeout", rootCmd.Flags().Lookup("webhook-timeout")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Int("webhook-retries", 3, "Number of times a failed webhook request is retried")
+	if err := viper.BindPFlag("webhook-retries", rootCmd.Flags().Lookup("webhook-retries")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Duration("webhook-exit-timeout", 2*time.Second, "Time a crash waits for the webhooks before exiting")
+	if err := viper.BindPFlag("webhook-exit-timeout", rootCmd.Flags().Lookup("webhook-exit-timeout")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Bool("kube-events", false, "Record the fault events as Kubernetes Events of the pod, using the in-cluster config")
+	if err := viper.BindPFlag("kube-events", rootCmd.Flags().Lookup("kube-events")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Duration("kube-events-timeout", 2*time.Second, "Timeout of the Kubernetes Event creation, also bounding the time a crash waits for its Event")
+	if err := viper.BindPFlag("kube-events-timeout", rootCmd.Flags().Lookup("kube-events-timeout")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("crash-coordination", "", "Coordinate the crashes across replicas so that only one crashes at a time: lease (Kubernetes Lease) or file (local lock file)")
+	if err := viper.BindPFlag("crash-coordination", rootCmd.Flags().Lookup("crash-coordination")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("crash-coordination-lease", "crashlooper-crash", "Name of the Lease coordinating the crashes, in the pod namespace")
+	if err := viper.BindPFlag("crash-coordination-lease", rootCmd.Flags().Lookup("crash-coordination-lease")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("crash-coordination-file", filepath.Join(os.TempDir(), "crashlooper-crash"), "Path of the file coordinating the crashes of local processes")
+	if err := viper.BindPFlag("crash-coordination-file", rootCmd.Flags().Lookup("crash-coordination-file")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Duration("crash-coordination-hold", 30*time.Second, "Time a replica holds the crash slot, covering its restart, before another replica may crash")
+	if err := viper.BindPFlag("crash-coordination-hold", rootCmd.Flags().Lookup("crash-coordination-hold")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Duration("rota-interval", 0, "Crash one replica every interval, round robin across the replicas discovered with --rota-dns or --rota-peers (default=0 means never)")
+	if err := viper.BindPFlag("rota-interval", rootCmd.Flags().Lookup("rota-interval")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("rota-dns", "", "DNS name resolving to the addresses of the replicas of the rota, such as a headless Service")
+	if err := viper.BindPFlag("rota-dns", rootCmd.Flags().Lookup("rota-dns")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().StringSlice("rota-peers", nil, "Addresses (host:port) of the replicas of the rota, this one included")
+	if err := viper.BindPFlag("rota-peers", rootCmd.Flags().Lookup("rota-peers")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("rota-port", "", "Port of the control API of the replicas discovered with --rota-dns (default empty means the admin port, or the bind port)")
+	if err := viper.BindPFlag("rota-port", rootCmd.Flags().Lookup("rota-port")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("rota-id", "", "Identity of the replica in the rota (default empty means the pod name, or the hostname and port)")
+	if err := viper.BindPFlag("rota-id", rootCmd.Flags().Lookup("rota-id")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("scenario", "", "JSON scenario run against the control API from the start (see ctl scenario run)")
+	if err := viper.BindPFlag("scenario", rootCmd.Flags().Lookup("scenario")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("probe-port", "", "Health checks bind port (default empty means the bind port)")
+	if err := viper.BindPFlag("probe-port", rootCmd.Flags().Lookup("probe-port")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("metrics-port", "", "Metrics bind port (default empty means the bind port)")
+	if err := viper.BindPFlag("metrics-port", rootCmd.Flags().Lookup("metrics-port")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().String("admin-port", "", "Control API, event stream and dashboard bind port (default empty means the bind port)")
+	if err := viper.BindPFlag("admin-port", rootCmd.Flags().Lookup("admin-port")); err != nil {
+		return nil, err
+	}
+
+	rootCmd.Flags().Bool("probe-faults", false, "Apply the faults to the health checks too")
+	if err := viper.BindPFlag("probe-faults", rootCmd.Flags().Lookup("probe-faults")); err != nil {
+		return nil, err
+	}
+
+	// the server flags are not inherited, the commands starting the server
+	// share them instead
+	serverFlags := rootCmd.LocalNonPersistentFlags()
+
+	proxyCmd, err := newProxyCmd(serverFlags)
+	if err != nil {
+		return nil, err
+	}
+	rootCmd.AddCommand(proxyCmd)
+
+	tcpProxyCmd, err := newTCPProxyCmd(serverFlags)
+	if err != nil {
+		return nil, err
+	}
+	rootCmd.AddCommand(tcpProxyCmd)
+
+	loadCmd, err := newLoadCmd(serverFlags)
+	if err != nil {
+		return nil, err
+	}
+	rootCmd.AddCommand(loadCmd)
+
+	for _, newCmd := range []func() (*cobra.Command, error){newJobCmd, newInitCmd} {
+		completionCmd, err := newCmd()
+		if err != nil {
+			return nil, err
+		}
+		rootCmd.AddCommand(completionCmd)
+	}
+
+	supervisorCmd, err := newSupervisorCmd()
+	if err != nil {
+		return nil, err
+	}
+	rootCmd.AddCommand(supervisorCmd)
+
+	ctlCmd, err := newCtlCmd()
+	if err != nil {
+		return nil, err
+	}
+	rootCmd.AddCommand(ctlCmd)
+
 	return rootCmd, nil
 }
 
@@ -89,41 +384,261 @@ func Execute() error {
 }
 
 func start(c *cobra.Command, args []string) error {
-	// Setup logger
+	pod, err := loadPod()
+	if err != nil {
+		return err
+	}
+	logger := newLogger(pod)
+
+	svcs, err := startServices(logger, pod)
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(logger, svcs.tracerProvider)
+
+	return serve(logger, svcs.routerOpts, svcs.tlsConfig)
+}
+
+// loadPod returns the identity of the pod from the downward API.
+func loadPod() (*podinfo.Info, error) {
+	pod, err := podinfo.Load(viper.GetString("podinfo-dir"))
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid pod info")
+	}
+	return pod, nil
+}
+
+// replicaIdentity returns the name of pod, or its hostname and port outside
+// Kubernetes, as local processes share the hostname.
+func replicaIdentity(pod *podinfo.Info, port string) string {
+	if pod.Name != "" {
+		return pod.Name
+	}
+	return net.JoinHostPort(pod.Hostname, port)
+}
+
+// newLogger returns the logger shared by every crashlooper mode, identifying
+// pod in every line.
+func newLogger(pod *podinfo.Info) *log.DefaultLogger {
 	logger := log.New(
 		log.WithLevel(viper.GetString("log-level")),
 	)
 
-	logger = logger.With(fields.Service("crashlooper", viper.GetString("revision")))
+	podFields := []zapcore.Field{fields.Service("crashlooper", viper.GetString("revision"))}
+	for _, f := range []struct{ key, value string }{
+		{"kubernetes.pod.name", pod.Name},
+		{"kubernetes.namespace", pod.Namespace},
+		{"kubernetes.node.name", pod.Node},
+		{"kubernetes.pod.ip", pod.IP},
+	} {
+		if f.value != "" {
+			podFields = append(podFields, fields.String(f.key, f.value))
+		}
+	}
+	if len(pod.Labels) > 0 {
+		podFields = append(podFields, fields.Any("kubernetes.labels", pod.Labels))
+	}
 
-	router := api.NewRouter(logger)
+	return logger.With(podFields...)
+}
 
-	// Setup server
-	httpSrv, err := server.NewServer(
-		server.WithLogger(logger),
-		server.WithRouter(router),
-		server.WithPort(viper.GetString("port")),
-	)
+// services are the services shared by the serving modes.
+type services struct {
+	// routerOpts expose the services, their events and the control APIs.
+	routerOpts []api.Option
+	// tlsConfig serves HTTPS on the TLS port, it is nil when TLS is disabled.
+	tlsConfig *tls.Config
+	// tracerProvider exports the spans, it is nil when tracing is disabled.
+	tracerProvider *sdktrace.TracerProvider
+	// bus publishes the events of the services.
+	bus *events.Bus
+}
+
+// startServices starts the crash, memory, pids, OOM, certificate and gRPC services,
+// the trace export and the scenario enabled by the flags.
+func startServices(logger *log.DefaultLogger, pod *podinfo.Info) (*services, error) {
+	authenticator, err := newAuthenticator()
 	if err != nil {
-		return errors.Wrap(err, "unable to initializing http server")
+		return nil, err
 	}
 
-	crashAfter := viper.GetDuration("crash-after")
-	if crashAfter != 0 {
-		c := crash.New(logger, crashAfter)
-		go c.Start()
+	// the audit log, webhooks and Kubernetes Events follow the events as
+	// they are published, so that crashes are recorded before exiting
+	var busOpts []events.Option
+	for _, newHooks := range []func(*log.DefaultLogger, *podinfo.Info) ([]events.Option, error){
+		newAuditLog,
+		newWebhooks,
+		newKubeEvents,
+	} {
+		opts, err := newHooks(logger, pod)
+		if err != nil {
+			return nil, err
+		}
+		busOpts = append(busOpts, opts...)
 	}
+	bus := events.NewBus(busOpts...)
+
+	tp, err := newTracerProvider(logger, pod)
+	if err != nil {
+		return nil, err
+	}
+	// the spans, the fault span events included, are flushed before crashing
+	exit := func(code int) {
+		shutdownTracing(logger, tp)
+		os.Exit(code)
+	}
+
+	// the crash, memory and pids services always run so that they can be controlled at runtime
+	crashOpts := []crash.Option{crash.WithEvents(bus), crash.WithExit(exit)}
+	coordinator, err := newCoordinator(logger, pod)
+	if err != nil {
+		return nil, err
+	}
+	if coordinator != nil {
+		crashOpts = append(crashOpts, crash.WithCoordinator(coordinator, coordinationRetry))
+	}
+	c := crash.New(logger, viper.GetDuration("crash-after"), crashOpts...)
+	c.Start()
 
 	memTarget := viper.GetString("memory-target")
 	memInc := viper.GetString("memory-increment")
 	memIncInterval := viper.GetDuration("memory-increment-interval")
 
+	var target, inc units.Base2Bytes
 	if memTarget != "" && memInc != "" {
-		inc, _ := units.ParseBase2Bytes(memInc)
-		target, _ := units.ParseBase2Bytes(memTarget)
+		inc, _ = units.ParseBase2Bytes(memInc)
+		target, _ = units.ParseBase2Bytes(memTarget)
+	}
+
+	m := memory.New(logger, target, inc, memIncInterval, memory.WithEvents(bus))
+	go m.Start()
+
+	pidsTarget, err := pidsSettings()
+	if err != nil {
+		return nil, err
+	}
+	pidsOpts, err := pidsOptions(bus)
+	if err != nil {
+		return nil, err
+	}
+	p := pids.New(logger, pidsTarget, pidsOpts...)
+	go p.Start()
+
+	faults := chaos.NewController(
+		chaos.WithNotify(events.FaultNotifier(bus)),
+		chaos.WithExitFunc(func(code int) {
+			logger.Info("Crashing", fields.Int("exit_code", code))
+			exit(code)
+		}),
+	)
+
+	routerOpts := []api.Option{
+		api.WithCrash(c),
+		api.WithMemory(m),
+		api.WithPids(p),
+		api.WithFaults(faults),
+		api.WithEvents(bus),
+		api.WithPod(pod),
+	}
+
+	r, err := newRota(logger, pod, c)
+	if err != nil {
+		return nil, err
+	}
+	if r != nil {
+		routerOpts = append(routerOpts, api.WithRota(r))
+		go r.Start()
+	}
+	if tp != nil {
+		routerOpts = append(routerOpts, api.WithTracing(tp))
+	}
+	if viper.GetBool("probe-faults") {
+		routerOpts = append(routerOpts, api.WithProbeFaults())
+	}
+
+	if viper.GetString("grpc-port") != "" {
+		go serveGRPC(logger, faults)
+	}
 
-		m := memory.New(logger, target, inc, memIncInterval)
-		go m.Start()
+	tlsConfig, tlsOpt, err := newTLSConfig(logger, bus, authenticator.ClientCAs())
+	if err != nil {
+		return nil, err
+	}
+	if tlsOpt != nil {
+		routerOpts = append(routerOpts, tlsOpt)
+	}
+
+	if authenticator.Enabled() {
+		logger.Info("Authenticating the control API requests")
+		routerOpts = append(routerOpts, api.WithAuth(authenticator))
+	} else {
+		logger.Warn("The control API is not authenticated, anybody reaching crashlooper can control it")
+	}
+
+	memWatchInterval := viper.GetDuration("memory-watch-interval")
+	if memWatchInterval != 0 {
+		o := oom.New(logger, viper.GetString("cgroup-root"), memWatchInterval, m.Allocated)
+		routerOpts = append(routerOpts, api.WithMemoryStats(o))
+		go o.Start()
+	}
+
+	if err := startScenario(logger); err != nil {
+		return nil, err
+	}
+
+	return &services{routerOpts: routerOpts, tlsConfig: tlsConfig, tracerProvider: tp, bus: bus}, nil
+}
+
+// newAuthenticator returns the authenticator of the control API requests
+// configured by the flags.
+func newAuthenticator() (*auth.Authenticator, error) {
+	opts := []auth.Option{auth.WithTokens(viper.GetString("auth-token"))}
+
+	if path := viper.GetString("auth-token-file"); path != "" {
+		if _, err := os.Stat(path); err != nil {
+			return nil, errors.Wrap(err, "invalid auth token file")
+		}
+		opts = append(opts, auth.WithTokenFile(path))
+	}
+
+	if path := viper.GetString("auth-client-ca"); path != "" {
+		pool, err := auth.LoadCertPool(path)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid auth client CA")
+		}
+		opts = append(opts, auth.WithClientCAs(pool))
+	}
+
+	return auth.New(opts...), nil
+}
+
+// serve starts the http servers of the routers built with routerOpts on the
+// configured ports, and the https server on the TLS port if tlsConfig is not
+// nil. The https server serves the routes of the bind port.
+func serve(logger *log.DefaultLogger, routerOpts []api.Option, tlsConfig *tls.Config) error {
+	port := viper.GetString("port")
+
+	var router *mux.Router
+	for p, routes := range listeners() {
+		opts := append(append([]api.Option{}, routerOpts...), api.WithRoutes(routes))
+		if p == port {
+			router = api.NewRouter(logger, opts...)
+			continue
+		}
+		go serveHTTP(logger, p, withH2C(api.NewRouter(logger, opts...)))
+	}
+
+	if tlsConfig != nil {
+		go serveTLS(logger, router, tlsConfig)
+	}
+
+	httpSrv, err := server.NewServer(
+		server.WithLogger(logger),
+		server.WithRouter(withH2C(router)),
+		server.WithPort(port),
+	)
+	if err != nil {
+		return errors.Wrap(err, "unable to initializing http server")
 	}
 
 	// Start http server
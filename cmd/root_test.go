@@ -2,13 +2,25 @@ package cmd
 
 import (
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
 )
 
+// testServerFlags returns the server flags of the root command.
+func testServerFlags(t *testing.T) *pflag.FlagSet {
+	t.Helper()
+	cmd, err := NewRootCmd()
+	require.NoError(t, err)
+	return cmd.LocalNonPersistentFlags()
+}
+
 func TestInitConfig(t *testing.T) {
 	// Reset viper for clean state
 	viper.Reset()
@@ -86,16 +98,236 @@ func TestNewRootCmd_Flags(t *testing.T) {
 			flagName:     "memory-increment-interval",
 			expectedType: "duration",
 		},
+		{
+			name:         "memory-watch-interval flag exists",
+			flagName:     "memory-watch-interval",
+			expectedType: "duration",
+		},
+		{
+			name:         "pids-mode flag exists",
+			flagName:     "pids-mode",
+			expectedType: "string",
+		},
+		{
+			name:         "pids-target flag exists",
+			flagName:     "pids-target",
+			expectedType: "int",
+		},
+		{
+			name:         "pids-increment flag exists",
+			flagName:     "pids-increment",
+			expectedType: "int",
+		},
+		{
+			name:         "pids-interval flag exists",
+			flagName:     "pids-interval",
+			expectedType: "duration",
+		},
+		{
+			name:         "cgroup-root flag exists",
+			flagName:     "cgroup-root",
+			expectedType: "string",
+		},
 		{
 			name:         "crash-after flag exists",
 			flagName:     "crash-after",
 			expectedType: "duration",
 		},
+		{
+			name:         "auth-token flag exists",
+			flagName:     "auth-token",
+			expectedType: "string",
+		},
+		{
+			name:         "auth-token-file flag exists",
+			flagName:     "auth-token-file",
+			expectedType: "string",
+		},
+		{
+			name:         "auth-client-ca flag exists",
+			flagName:     "auth-client-ca",
+			expectedType: "string",
+		},
+		{
+			name:         "h2c flag exists",
+			flagName:     "h2c",
+			expectedType: "bool",
+		},
+		{
+			name:         "http2-max-concurrent-streams flag exists",
+			flagName:     "http2-max-concurrent-streams",
+			expectedType: "uint32",
+		},
+		{
+			name:         "tls-port flag exists",
+			flagName:     "tls-port",
+			expectedType: "string",
+		},
+		{
+			name:         "tls-hosts flag exists",
+			flagName:     "tls-hosts",
+			expectedType: "stringSlice",
+		},
+		{
+			name:         "tls-mode flag exists",
+			flagName:     "tls-mode",
+			expectedType: "string",
+		},
+		{
+			name:         "tls-rotate-interval flag exists",
+			flagName:     "tls-rotate-interval",
+			expectedType: "duration",
+		},
+		{
+			name:         "otlp-endpoint flag exists",
+			flagName:     "otlp-endpoint",
+			expectedType: "string",
+		},
+		{
+			name:         "otlp-protocol flag exists",
+			flagName:     "otlp-protocol",
+			expectedType: "string",
+		},
+		{
+			name:         "trace-sample-ratio flag exists",
+			flagName:     "trace-sample-ratio",
+			expectedType: "float64",
+		},
+		{
+			name:         "audit-log flag exists",
+			flagName:     "audit-log",
+			expectedType: "string",
+		},
+		{
+			name:         "audit-format flag exists",
+			flagName:     "audit-format",
+			expectedType: "string",
+		},
+		{
+			name:         "webhook-url flag exists",
+			flagName:     "webhook-url",
+			expectedType: "stringSlice",
+		},
+		{
+			name:         "webhook-header flag exists",
+			flagName:     "webhook-header",
+			expectedType: "stringArray",
+		},
+		{
+			name:         "webhook-template flag exists",
+			flagName:     "webhook-template",
+			expectedType: "string",
+		},
+		{
+			name:         "webhook-phases flag exists",
+			flagName:     "webhook-phases",
+			expectedType: "stringSlice",
+		},
+		{
+			name:         "webhook-timeout flag exists",
+			flagName:     "webhook-timeout",
+			expectedType: "duration",
+		},
+		{
+			name:         "webhook-retries flag exists",
+			flagName:     "webhook-retries",
+			expectedType: "int",
+		},
+		{
+			name:         "webhook-exit-timeout flag exists",
+			flagName:     "webhook-exit-timeout",
+			expectedType: "duration",
+		},
+		{
+			name:         "kube-events flag exists",
+			flagName:     "kube-events",
+			expectedType: "bool",
+		},
+		{
+			name:         "kube-events-timeout flag exists",
+			flagName:     "kube-events-timeout",
+			expectedType: "duration",
+		},
+		{
+			name:         "crash-coordination flag exists",
+			flagName:     "crash-coordination",
+			expectedType: "string",
+		},
+		{
+			name:         "crash-coordination-lease flag exists",
+			flagName:     "crash-coordination-lease",
+			expectedType: "string",
+		},
+		{
+			name:         "crash-coordination-file flag exists",
+			flagName:     "crash-coordination-file",
+			expectedType: "string",
+		},
+		{
+			name:         "crash-coordination-hold flag exists",
+			flagName:     "crash-coordination-hold",
+			expectedType: "duration",
+		},
+		{
+			name:         "rota-interval flag exists",
+			flagName:     "rota-interval",
+			expectedType: "duration",
+		},
+		{
+			name:         "rota-dns flag exists",
+			flagName:     "rota-dns",
+			expectedType: "string",
+		},
+		{
+			name:         "rota-peers flag exists",
+			flagName:     "rota-peers",
+			expectedType: "stringSlice",
+		},
+		{
+			name:         "rota-port flag exists",
+			flagName:     "rota-port",
+			expectedType: "string",
+		},
+		{
+			name:         "rota-id flag exists",
+			flagName:     "rota-id",
+			expectedType: "string",
+		},
+		{
+			name:         "scenario flag exists",
+			flagName:     "scenario",
+			expectedType: "string",
+		},
+		{
+			name:         "podinfo-dir flag exists",
+			flagName:     "podinfo-dir",
+			expectedType: "string",
+		},
+		{
+			name:         "probe-port flag exists",
+			flagName:     "probe-port",
+			expectedType: "string",
+		},
+		{
+			name:         "metrics-port flag exists",
+			flagName:     "metrics-port",
+			expectedType: "string",
+		},
+		{
+			name:         "admin-port flag exists",
+			flagName:     "admin-port",
+			expectedType: "string",
+		},
+		{
+			name:         "probe-faults flag exists",
+			flagName:     "probe-faults",
+			expectedType: "bool",
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			flag := cmd.PersistentFlags().Lookup(tt.flagName)
+			flag := cmd.Flags().Lookup(tt.flagName)
 			require.NotNil(t, flag, "flag %s should exist", tt.flagName)
 			require.Equal(t, tt.expectedType, flag.Value.Type())
 		})
@@ -109,17 +341,23 @@ func TestNewRootCmd_DefaultValues(t *testing.T) {
 	require.NoError(t, err)
 
 	// Check default values
-	logLevelFlag := cmd.PersistentFlags().Lookup("log-level")
+	logLevelFlag := cmd.Flags().Lookup("log-level")
 	require.Equal(t, "info", logLevelFlag.DefValue)
 
-	portFlag := cmd.PersistentFlags().Lookup("port")
+	portFlag := cmd.Flags().Lookup("port")
 	require.Equal(t, "3000", portFlag.DefValue)
 
-	crashAfterFlag := cmd.PersistentFlags().Lookup("crash-after")
+	crashAfterFlag := cmd.Flags().Lookup("crash-after")
 	require.Equal(t, "0s", crashAfterFlag.DefValue)
 
-	memIncrementIntervalFlag := cmd.PersistentFlags().Lookup("memory-increment-interval")
+	memIncrementIntervalFlag := cmd.Flags().Lookup("memory-increment-interval")
 	require.Equal(t, "1s", memIncrementIntervalFlag.DefValue)
+
+	memWatchIntervalFlag := cmd.Flags().Lookup("memory-watch-interval")
+	require.Equal(t, "1s", memWatchIntervalFlag.DefValue)
+
+	cgroupRootFlag := cmd.Flags().Lookup("cgroup-root")
+	require.Equal(t, "/sys/fs/cgroup", cgroupRootFlag.DefValue)
 }
 
 func TestNewRootCmd_FlagBinding(t *testing.T) {
@@ -129,9 +367,9 @@ func TestNewRootCmd_FlagBinding(t *testing.T) {
 	require.NoError(t, err)
 
 	// Set flags
-	cmd.PersistentFlags().Set("log-level", "debug")
-	cmd.PersistentFlags().Set("port", "8080")
-	cmd.PersistentFlags().Set("crash-after", "5s")
+	cmd.Flags().Set("log-level", "debug")
+	cmd.Flags().Set("port", "8080")
+	cmd.Flags().Set("crash-after", "5s")
 
 	// Initialize config to bind flags
 	initConfig()
@@ -149,9 +387,9 @@ func TestNewRootCmd_MemoryFlags(t *testing.T) {
 	require.NoError(t, err)
 
 	// Set memory flags
-	cmd.PersistentFlags().Set("memory-target", "100MB")
-	cmd.PersistentFlags().Set("memory-increment", "10MB")
-	cmd.PersistentFlags().Set("memory-increment-interval", "500ms")
+	cmd.Flags().Set("memory-target", "100MB")
+	cmd.Flags().Set("memory-increment", "10MB")
+	cmd.Flags().Set("memory-increment-interval", "500ms")
 
 	initConfig()
 
@@ -187,7 +425,7 @@ func TestNewRootCmd_ViperBinding(t *testing.T) {
 	}
 
 	for _, flagName := range flags {
-		flag := cmd.PersistentFlags().Lookup(flagName)
+		flag := cmd.Flags().Lookup(flagName)
 		require.NotNil(t, flag, "flag %s should exist", flagName)
 	}
 }
@@ -201,7 +439,7 @@ func TestNewRootCmd_FlagTypes(t *testing.T) {
 	// Test string flags
 	stringFlags := []string{"log-level", "port", "memory-target", "memory-increment"}
 	for _, flagName := range stringFlags {
-		flag := cmd.PersistentFlags().Lookup(flagName)
+		flag := cmd.Flags().Lookup(flagName)
 		require.NotNil(t, flag)
 		require.Equal(t, "string", flag.Value.Type())
 	}
@@ -209,7 +447,7 @@ func TestNewRootCmd_FlagTypes(t *testing.T) {
 	// Test duration flags
 	durationFlags := []string{"memory-increment-interval", "crash-after"}
 	for _, flagName := range durationFlags {
-		flag := cmd.PersistentFlags().Lookup(flagName)
+		flag := cmd.Flags().Lookup(flagName)
 		require.NotNil(t, flag)
 		require.Equal(t, "duration", flag.Value.Type())
 	}
@@ -250,3 +488,51 @@ func TestInitConfig_EnvKeyReplacer(t *testing.T) {
 	// Verify the value is accessible with hyphenated key
 	require.Equal(t, "debug", viper.GetString("log-level"))
 }
+
+func TestNewAuthenticator(t *testing.T) {
+	viper.Reset()
+
+	a, err := newAuthenticator()
+	require.NoError(t, err)
+	require.False(t, a.Enabled())
+
+	// The token is usually provided by the environment
+	os.Setenv("CRASHLOOPER_AUTH_TOKEN", "secret")
+	defer os.Unsetenv("CRASHLOOPER_AUTH_TOKEN")
+	initConfig()
+
+	a, err = newAuthenticator()
+	require.NoError(t, err)
+	require.True(t, a.Enabled())
+
+	viper.Set("auth-token-file", "/nonexistent/tokens")
+	_, err = newAuthenticator()
+	require.ErrorContains(t, err, "invalid auth token file")
+
+	viper.Set("auth-token-file", "")
+	viper.Set("auth-client-ca", "/nonexistent/ca.pem")
+	_, err = newAuthenticator()
+	require.ErrorContains(t, err, "invalid auth client CA")
+}
+
+func TestLoadPod(t *testing.T) {
+	viper.Reset()
+	t.Setenv("POD_NAME", "crashlooper-7d9f")
+
+	dir := t.TempDir()
+	viper.Set("podinfo-dir", dir)
+
+	pod, err := loadPod()
+	require.NoError(t, err)
+	require.Equal(t, "crashlooper-7d9f", pod.Name)
+	require.NotNil(t, newLogger(pod))
+
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "labels"), []byte("app\n"), 0o600))
+	_, err = loadPod()
+	require.ErrorContains(t, err, "invalid pod info")
+}
+
+func TestReplicaIdentity(t *testing.T) {
+	require.Equal(t, "web-0", replicaIdentity(&podinfo.Info{Name: "web-0", Hostname: "node"}, "3000"))
+	require.Equal(t, "node:3000", replicaIdentity(&podinfo.Info{Hostname: "node"}, "3000"))
+}
@@ -0,0 +1,58 @@
+package cmd
+
+import (
+	"net"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+	"github.com/pixelfactoryio/crashlooper/internal/rota"
+)
+
+// newRota returns the crash rota configured by the flags, crashing with
+// crasher, or nil when the rota is disabled.
+func newRota(logger *log.DefaultLogger, pod *podinfo.Info, crasher rota.Crasher) (*rota.Rota, error) {
+	interval := viper.GetDuration("rota-interval")
+	if interval == 0 {
+		return nil, nil
+	}
+
+	// the replicas query the rota API of each other on the port serving it
+	port := viper.GetString("rota-port")
+	if port == "" {
+		port = adminPort()
+	}
+
+	// the rota API of the peers is queried over plain HTTP
+	if tlsPort := viper.GetString("tls-port"); tlsPort != "" {
+		if port == tlsPort {
+			return nil, errors.New("--rota-port cannot be the --tls-port, the rota is queried over plain HTTP")
+		}
+		for _, peer := range viper.GetStringSlice("rota-peers") {
+			if _, p, err := net.SplitHostPort(peer); err == nil && p == tlsPort {
+				return nil, errors.Errorf("rota peer %q is the --tls-port, the rota is queried over plain HTTP", peer)
+			}
+		}
+	}
+
+	var discovery rota.Discovery
+	switch name, peers := viper.GetString("rota-dns"), viper.GetStringSlice("rota-peers"); {
+	case name != "" && len(peers) != 0:
+		return nil, errors.New("--rota-dns and --rota-peers are mutually exclusive")
+	case name != "":
+		discovery = rota.DNS(name, port)
+	case len(peers) != 0:
+		discovery = rota.Static(peers...)
+	default:
+		return nil, errors.New("the rota requires --rota-dns or --rota-peers")
+	}
+
+	id := viper.GetString("rota-id")
+	if id == "" {
+		id = replicaIdentity(pod, port)
+	}
+
+	return rota.New(logger, id, interval, discovery, crasher)
+}
@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+)
+
+func TestNewRota(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	pod := &podinfo.Info{Hostname: "localhost"}
+	crasher := crash.New(logger, 0)
+
+	viper.Reset()
+	r, err := newRota(logger, pod, crasher)
+	require.NoError(t, err)
+	require.Nil(t, r)
+
+	viper.Set("rota-interval", 2*time.Minute)
+	_, err = newRota(logger, pod, crasher)
+	require.Error(t, err)
+
+	viper.Set("rota-dns", "crashlooper-peers")
+	viper.Set("rota-peers", []string{"localhost:3000"})
+	_, err = newRota(logger, pod, crasher)
+	require.Error(t, err)
+
+	viper.Set("rota-dns", "")
+	viper.Set("port", "3001")
+	r, err = newRota(logger, pod, crasher)
+	require.NoError(t, err)
+	require.Equal(t, "localhost:3001", r.View().ID)
+
+	pod.Name = "crashlooper-7d9f"
+	r, err = newRota(logger, pod, crasher)
+	require.NoError(t, err)
+	require.Equal(t, "crashlooper-7d9f", r.View().ID)
+	viper.Reset()
+}
+
+func TestNewRota_TLSPort(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	pod := &podinfo.Info{Hostname: "localhost"}
+	crasher := crash.New(logger, 0)
+
+	viper.Reset()
+	defer viper.Reset()
+	viper.Set("rota-interval", 2*time.Minute)
+	viper.Set("rota-dns", "crashlooper-peers")
+	viper.Set("port", "3000")
+	viper.Set("tls-port", "3443")
+	_, err := newRota(logger, pod, crasher)
+	require.NoError(t, err)
+
+	// the peers are queried over plain HTTP
+	viper.Set("rota-port", "3443")
+	_, err = newRota(logger, pod, crasher)
+	require.ErrorContains(t, err, "--tls-port")
+
+	viper.Set("rota-port", "")
+	viper.Set("rota-dns", "")
+	viper.Set("rota-peers", []string{"10.0.0.1:3443", "10.0.0.2:3443"})
+	_, err = newRota(logger, pod, crasher)
+	require.ErrorContains(t, err, "--tls-port")
+}
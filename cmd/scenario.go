@@ -0,0 +1,74 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/client"
+)
+
+// scenarioWait bounds the time the scenario waits for the control API.
+const scenarioWait = 30 * time.Second
+
+// startScenario runs the scenario of the --scenario file, if any, against
+// the control API of this crashlooper once it is served. The scenario is
+// read before serving so that an invalid one fails the start.
+func startScenario(logger *log.DefaultLogger) error {
+	path := viper.GetString("scenario")
+	if path == "" {
+		return nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return errors.Wrap(err, "unable to open scenario")
+	}
+	defer f.Close()
+
+	s, err := client.LoadScenario(f)
+	if err != nil {
+		return err
+	}
+
+	c, err := client.New("http://localhost:"+adminPort(), client.WithToken(viper.GetString("auth-token")))
+	if err != nil {
+		return err
+	}
+
+	go runScenario(logger, c, s)
+	return nil
+}
+
+// runScenario runs s with c once the control API answers.
+func runScenario(logger *log.DefaultLogger, c *client.Client, s *client.Scenario) {
+	ctx := context.Background()
+
+	deadline := time.Now().Add(scenarioWait)
+	for {
+		_, err := c.Status(ctx)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			logger.Error("Unable to run scenario, the control API is not available", fields.Error(err))
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+
+	logger.Info("Running scenario", fields.String("name", s.Name), fields.Int("steps", len(s.Steps)))
+	err := c.RunScenario(ctx, s, func(step client.Step) {
+		logger.Info("Scenario step applied", fields.String("step", step.String()))
+	})
+	if err != nil {
+		logger.Error("Scenario failed", fields.Error(err))
+		return
+	}
+	logger.Info("Scenario completed", fields.String("name", s.Name))
+}
@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/client"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+func TestStartScenario(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+
+	viper.Reset()
+	require.NoError(t, startScenario(logger))
+
+	viper.Set("scenario", filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, startScenario(logger))
+
+	path := filepath.Join(t.TempDir(), "scenario.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"name": "empty", "steps": []}`), 0o600))
+	viper.Set("scenario", path)
+	require.Error(t, startScenario(logger))
+	viper.Reset()
+}
+
+func TestRunScenario(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	server := startCtlServer(t)
+
+	s, err := client.LoadScenario(strings.NewReader(`{
+		"name": "latency",
+		"steps": [{"at": "0s", "fault": {"kind": "latency", "probability": 1, "delay": "10ms"}}]
+	}`))
+	require.NoError(t, err)
+	c, err := client.New(server)
+	require.NoError(t, err)
+
+	runScenario(logger, c, s)
+
+	faults, err := c.Faults(context.Background())
+	require.NoError(t, err)
+	require.Len(t, faults, 1)
+	require.Equal(t, chaos.KindLatency, faults[0].Kind)
+	require.Equal(t, chaos.Duration(10*time.Millisecond), faults[0].Delay)
+}
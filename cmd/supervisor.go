@@ -0,0 +1,158 @@
+//go:build unix
+
+package cmd
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/supervisor"
+)
+
+// newSupervisorCmd create the supervisor command
+func newSupervisorCmd() (*cobra.Command, error) {
+	cmd := &cobra.Command{
+		Use:   "supervisor",
+		Short: "Spawn child processes that exit, crash, get orphaned or ignore the signals",
+		Long: `Spawn --children child processes, one every --spawn-interval, behaving as
+--child-behavior: exit with --child-exit-code, kill themselves with SIGKILL,
+leave an orphan behind, or ignore SIGTERM. The children are reaped unless
+--reap=false leaves them as zombies, and --signals sets how SIGTERM is
+handled, to test the PID 1 duties of an image, or of tini or dumb-init.
+No HTTP server is started.`,
+		RunE: func(c *cobra.Command, args []string) error {
+			return startSupervisor()
+		},
+	}
+
+	cmd.Flags().Int("children", 3, "Number of children spawned, 0 spawns them until the exit")
+	cmd.Flags().Duration("spawn-interval", time.Second, "Interval between the children spawned")
+	cmd.Flags().String("child-behavior", string(supervisor.BehaviorExit), "Behavior of the children: exit, kill, orphan or ignore-signals")
+	cmd.Flags().Duration("child-lifetime", 5*time.Second, "Time before the children, or the orphans, exit (0 never exits)")
+	cmd.Flags().Int("child-exit-code", 1, "Exit code of the children")
+	cmd.Flags().Bool("reap", true, "Reap the exited children, otherwise they are left as zombies")
+	cmd.Flags().Bool("subreaper", false, "Reparent the orphans to crashlooper rather than to PID 1, linux only")
+	cmd.Flags().String("signals", string(supervisor.SignalsForward), "SIGTERM handling: forward to the children, exit without them, or ignore")
+	cmd.Flags().Duration("grace", 10*time.Second, "Time the children are given to exit once SIGTERM is forwarded")
+	cmd.Flags().Duration("report-interval", 10*time.Second, "Interval the children are reported, 0 disables the reports")
+
+	for _, name := range []string{
+		"children", "spawn-interval", "child-behavior", "child-lifetime", "child-exit-code",
+		"reap", "subreaper", "signals", "grace", "report-interval",
+	} {
+		if err := viper.BindPFlag("supervisor-"+name, cmd.Flags().Lookup(name)); err != nil {
+			return nil, err
+		}
+	}
+
+	cmd.AddCommand(newSupervisorChildCmd())
+	return cmd, nil
+}
+
+// newSupervisorChildCmd creates the command run by the children of the
+// supervisor, the crashlooper binary being executed again.
+func newSupervisorChildCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "child",
+		Short:  "Run as a child of the supervisor",
+		Hidden: true,
+		RunE: func(c *cobra.Command, args []string) error {
+			behavior, _ := c.Flags().GetString("behavior")
+			lifetime, _ := c.Flags().GetDuration("lifetime")
+			exitCode, _ := c.Flags().GetInt("exit-code")
+			cfg := supervisor.ChildConfig{Behavior: supervisor.Behavior(behavior), Lifetime: lifetime, ExitCode: exitCode}
+			if err := cfg.Validate(); err != nil {
+				return err
+			}
+
+			command, err := childCommand()
+			if err != nil {
+				return err
+			}
+			if code := supervisor.RunChild(cfg, command); code != 0 {
+				os.Exit(code)
+			}
+			return nil
+		},
+	}
+	cmd.Flags().String("behavior", string(supervisor.BehaviorExit), "Behavior of the child")
+	cmd.Flags().Duration("lifetime", 0, "Time before the child exits")
+	cmd.Flags().Int("exit-code", 0, "Exit code of the child")
+	return cmd
+}
+
+func startSupervisor() error {
+	cfg, err := supervisorConfig()
+	if err != nil {
+		return err
+	}
+	command, err := childCommand()
+	if err != nil {
+		return err
+	}
+
+	pod, err := loadPod()
+	if err != nil {
+		return err
+	}
+	logger := newLogger(pod)
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	code, err := supervisor.New(logger, cfg, command).Run(signals)
+	if err != nil {
+		return err
+	}
+	if code != 0 {
+		os.Exit(code)
+	}
+	return nil
+}
+
+// supervisorConfig returns the configuration of the supervisor set by the
+// flags.
+func supervisorConfig() (supervisor.Config, error) {
+	cfg := supervisor.Config{
+		Children:      viper.GetInt("supervisor-children"),
+		SpawnInterval: viper.GetDuration("supervisor-spawn-interval"),
+		Child: supervisor.ChildConfig{
+			Behavior: supervisor.Behavior(viper.GetString("supervisor-child-behavior")),
+			Lifetime: viper.GetDuration("supervisor-child-lifetime"),
+			ExitCode: viper.GetInt("supervisor-child-exit-code"),
+		},
+		Reap:           viper.GetBool("supervisor-reap"),
+		Subreaper:      viper.GetBool("supervisor-subreaper"),
+		Signals:        supervisor.SignalHandling(viper.GetString("supervisor-signals")),
+		Grace:          viper.GetDuration("supervisor-grace"),
+		ReportInterval: viper.GetDuration("supervisor-report-interval"),
+	}
+	return cfg, cfg.Validate()
+}
+
+// childCommand returns the command line of the children of the supervisor,
+// executing the crashlooper binary again.
+func childCommand() ([]string, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to locate the crashlooper binary")
+	}
+	return []string{exe, "supervisor", "child"}, nil
+}
+
+// sleeperCommand returns the command line of a child of the supervisor
+// sleeping until it is killed.
+func sleeperCommand() ([]string, error) {
+	command, err := childCommand()
+	if err != nil {
+		return nil, err
+	}
+	return append(command, supervisor.ChildConfig{Behavior: supervisor.BehaviorExit}.Args()...), nil
+}
@@ -0,0 +1,26 @@
+//go:build !unix
+
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// newSupervisorCmd create the supervisor command, which requires the
+// process model of unix.
+func newSupervisorCmd() (*cobra.Command, error) {
+	return &cobra.Command{
+		Use:   "supervisor",
+		Short: "Spawn child processes that exit, crash, get orphaned or ignore the signals (unix only)",
+		RunE: func(c *cobra.Command, args []string) error {
+			return errors.New("the supervisor mode requires a unix system")
+		},
+	}, nil
+}
+
+// sleeperCommand returns no command, the children of the supervisor
+// requiring unix.
+func sleeperCommand() ([]string, error) {
+	return nil, nil
+}
@@ -0,0 +1,75 @@
+//go:build unix
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/supervisor"
+)
+
+func TestNewSupervisorCmd(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := newSupervisorCmd()
+	require.NoError(t, err)
+	require.Equal(t, "supervisor", cmd.Use)
+
+	tests := map[string]string{
+		"children":        "3",
+		"spawn-interval":  "1s",
+		"child-behavior":  "exit",
+		"child-lifetime":  "5s",
+		"child-exit-code": "1",
+		"reap":            "true",
+		"subreaper":       "false",
+		"signals":         "forward",
+		"grace":           "10s",
+		"report-interval": "10s",
+	}
+	for name, def := range tests {
+		flag := cmd.Flags().Lookup(name)
+		require.NotNil(t, flag, name)
+		require.Equal(t, def, flag.DefValue, name)
+	}
+
+	child, _, err := cmd.Find([]string{"child"})
+	require.NoError(t, err)
+	require.True(t, child.Hidden)
+	for _, name := range []string{"behavior", "lifetime", "exit-code"} {
+		require.NotNil(t, child.Flags().Lookup(name), name)
+	}
+}
+
+func TestSupervisorConfig(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := newSupervisorCmd()
+	require.NoError(t, err)
+	require.NoError(t, cmd.Flags().Set("child-behavior", "orphan"))
+	require.NoError(t, cmd.Flags().Set("reap", "false"))
+	require.NoError(t, cmd.Flags().Set("signals", "ignore"))
+
+	cfg, err := supervisorConfig()
+	require.NoError(t, err)
+	require.Equal(t, 3, cfg.Children)
+	require.Equal(t, time.Second, cfg.SpawnInterval)
+	require.Equal(t, supervisor.ChildConfig{Behavior: supervisor.BehaviorOrphan, Lifetime: 5 * time.Second, ExitCode: 1}, cfg.Child)
+	require.False(t, cfg.Reap)
+	require.Equal(t, supervisor.SignalsIgnore, cfg.Signals)
+
+	require.NoError(t, cmd.Flags().Set("child-behavior", "fork"))
+	_, err = supervisorConfig()
+	require.Error(t, err)
+}
+
+func TestChildCommand(t *testing.T) {
+	command, err := childCommand()
+	require.NoError(t, err)
+	require.Len(t, command, 3)
+	require.Equal(t, []string{"supervisor", "child"}, command[1:])
+}
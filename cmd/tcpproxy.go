@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/services/tcpproxy"
+)
+
+// newTCPProxyCmd create the tcp-proxy command
+func newTCPProxyCmd(serverFlags *pflag.FlagSet) (*cobra.Command, error) {
+	tcpProxyCmd := &cobra.Command{
+		Use:   "tcp-proxy",
+		Short: "Forward TCP connections to an upstream while injecting faults",
+		Long: `Forward TCP connections accepted on --listen to an upstream such as a
+database or a cache. Latency, bandwidth, disconnect, blackhole and half-open
+faults are controlled at runtime through the /api/tcp/faults API served on --port.`,
+		RunE: startTCPProxy,
+	}
+
+	// the viper keys are prefixed to not collide with the proxy command flags
+	tcpProxyCmd.Flags().String("listen", ":15432", "Address TCP connections are accepted on")
+	if err := viper.BindPFlag("tcp-listen", tcpProxyCmd.Flags().Lookup("listen")); err != nil {
+		return nil, err
+	}
+
+	tcpProxyCmd.Flags().String("upstream", "", "Upstream address connections are forwarded to (e.g. localhost:5432)")
+	if err := viper.BindPFlag("tcp-upstream", tcpProxyCmd.Flags().Lookup("upstream")); err != nil {
+		return nil, err
+	}
+
+	tcpProxyCmd.Flags().AddFlagSet(serverFlags)
+
+	return tcpProxyCmd, nil
+}
+
+func startTCPProxy(c *cobra.Command, args []string) error {
+	upstream := viper.GetString("tcp-upstream")
+	if upstream == "" {
+		return errors.New("an upstream is required")
+	}
+
+	pod, err := loadPod()
+	if err != nil {
+		return err
+	}
+	logger := newLogger(pod)
+
+	svcs, err := startServices(logger, pod)
+	if err != nil {
+		return err
+	}
+	defer shutdownTracing(logger, svcs.tracerProvider)
+
+	p := tcpproxy.New(logger, viper.GetString("tcp-listen"), upstream, tcpproxy.WithEvents(svcs.bus))
+	go func() {
+		if err := p.Start(); err != nil {
+			logger.Fatal("TCP proxy stopped", fields.Error(err))
+		}
+	}()
+
+	routerOpts := append(svcs.routerOpts, api.WithTCPFaults(p))
+
+	return serve(logger, routerOpts, svcs.tlsConfig)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTCPProxyCmd(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := newTCPProxyCmd(testServerFlags(t))
+
+	require.NoError(t, err)
+	require.NotNil(t, cmd)
+	require.Equal(t, "tcp-proxy", cmd.Use)
+	require.NotNil(t, cmd.RunE)
+
+	listenFlag := cmd.Flags().Lookup("listen")
+	require.NotNil(t, listenFlag)
+	require.Equal(t, ":15432", listenFlag.DefValue)
+
+	upstreamFlag := cmd.Flags().Lookup("upstream")
+	require.NotNil(t, upstreamFlag)
+	require.Equal(t, "", upstreamFlag.DefValue)
+}
+
+func TestNewTCPProxyCmd_FlagBinding(t *testing.T) {
+	viper.Reset()
+
+	cmd, err := newTCPProxyCmd(testServerFlags(t))
+	require.NoError(t, err)
+
+	require.NoError(t, cmd.Flags().Set("listen", ":6380"))
+	require.NoError(t, cmd.Flags().Set("upstream", "localhost:6379"))
+
+	require.Equal(t, ":6380", viper.GetString("tcp-listen"))
+	require.Equal(t, "localhost:6379", viper.GetString("tcp-upstream"))
+}
+
+func TestStartTCPProxy_RequiresUpstream(t *testing.T) {
+	viper.Reset()
+
+	_, err := newTCPProxyCmd(testServerFlags(t))
+	require.NoError(t, err)
+
+	require.Error(t, startTCPProxy(nil, nil))
+}
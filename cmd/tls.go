@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	stdlog "log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// newTLSConfig starts the certificate manager and returns the TLS configuration
+// serving its certificates, with the router option exposing its control API.
+// It returns a nil configuration when TLS is disabled.
+func newTLSConfig(logger *log.DefaultLogger, bus events.Publisher, clientCAs *x509.CertPool) (*tls.Config, api.Option, error) {
+	if viper.GetString("tls-port") == "" {
+		return nil, nil, nil
+	}
+	// the control API of a separate admin port is served without TLS, out of
+	// reach of the client certificates
+	if clientCAs != nil && adminPort() != viper.GetString("port") {
+		return nil, nil, errors.New("--auth-client-ca cannot be combined with a separate --admin-port")
+	}
+
+	opts := []certs.Option{
+		certs.WithEvents(bus),
+		certs.WithSettings(certs.Settings{
+			Mode:           certs.Mode(viper.GetString("tls-mode")),
+			RotateInterval: chaos.Duration(viper.GetDuration("tls-rotate-interval")),
+		}),
+	}
+
+	certFile, keyFile := viper.GetString("tls-cert"), viper.GetString("tls-key")
+	if (certFile == "") != (keyFile == "") {
+		return nil, nil, errors.New("both a TLS certificate and key are required")
+	}
+	if certFile != "" {
+		opts = append(opts, certs.WithFiles(certFile, keyFile))
+	}
+
+	m, err := certs.New(logger, viper.GetStringSlice("tls-hosts"), opts...)
+	if err != nil {
+		return nil, nil, errors.Wrap(err, "unable to create certificate manager")
+	}
+	go m.Start()
+
+	tlsConfig := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: m.GetCertificate,
+	}
+	if clientCAs != nil {
+		tlsConfig.ClientCAs = clientCAs
+		tlsConfig.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+
+	return tlsConfig, api.WithTLS(m), nil
+}
+
+// newTLSServer returns the HTTPS server of handler on the configured TLS port.
+func newTLSServer(logger log.Logger, handler http.Handler, tlsConfig *tls.Config) *http.Server {
+	return &http.Server{
+		Addr:              ":" + viper.GetString("tls-port"),
+		Handler:           handler,
+		TLSConfig:         tlsConfig,
+		HTTP2:             http2Config(),
+		ReadHeaderTimeout: 10 * time.Second,
+		// handshake errors are expected while serving faulty certificates
+		ErrorLog: stdlog.New(debugWriter{logger}, "", 0),
+	}
+}
+
+// serveTLS serves handler over HTTPS until the process exits.
+func serveTLS(logger log.Logger, handler http.Handler, tlsConfig *tls.Config) {
+	srv := newTLSServer(logger, handler, tlsConfig)
+	logger.Info("Serving HTTPS", fields.String("addr", srv.Addr))
+
+	if err := srv.ListenAndServeTLS("", ""); err != nil && err != http.ErrServerClosed {
+		logger.Fatal("HTTPS server stopped", fields.Error(err))
+	}
+}
+
+// debugWriter writes the standard library server logs at debug level.
+type debugWriter struct {
+	logger log.Logger
+}
+
+func (w debugWriter) Write(p []byte) (int, error) {
+	w.logger.Debug(strings.TrimSpace(string(p)))
+	return len(p), nil
+}
@@ -0,0 +1,144 @@
+package cmd
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+)
+
+// startTLSServer serves a router over HTTPS with the TLS configuration of the flags
+// and returns its address.
+func startTLSServer(t *testing.T) string {
+	t.Helper()
+
+	logger := log.New(log.WithLevel("info"))
+	tlsConfig, tlsOpt, err := newTLSConfig(logger, events.NewBus(), nil)
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig)
+
+	srv := newTLSServer(logger, api.NewRouter(logger, tlsOpt), tlsConfig)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	go func() { _ = srv.ServeTLS(ln, "", "") }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return ln.Addr().String()
+}
+
+func get(t *testing.T, tlsConfig *tls.Config, url string) (*http.Response, error) {
+	t.Helper()
+
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: tlsConfig, ForceAttemptHTTP2: true}}
+	resp, err := client.Get(url)
+	if err == nil {
+		t.Cleanup(func() { resp.Body.Close() })
+	}
+	return resp, err
+}
+
+func TestNewTLSConfig_Disabled(t *testing.T) {
+	viper.Reset()
+
+	tlsConfig, tlsOpt, err := newTLSConfig(log.New(log.WithLevel("info")), events.NewBus(), nil)
+	require.NoError(t, err)
+	require.Nil(t, tlsConfig)
+	require.Nil(t, tlsOpt)
+}
+
+func TestNewTLSConfig_Errors(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+
+	viper.Reset()
+	viper.Set("tls-port", "3443")
+	viper.Set("tls-hosts", []string{"localhost"})
+	viper.Set("tls-mode", "revoked")
+	_, _, err := newTLSConfig(logger, events.NewBus(), nil)
+	require.Error(t, err)
+
+	viper.Set("tls-mode", "valid")
+	viper.Set("tls-cert", "tls.crt")
+	_, _, err = newTLSConfig(logger, events.NewBus(), nil)
+	require.ErrorContains(t, err, "both a TLS certificate and key are required")
+
+	viper.Set("tls-cert", "")
+	viper.Set("port", "3000")
+	viper.Set("admin-port", "8080")
+	_, _, err = newTLSConfig(logger, events.NewBus(), x509.NewCertPool())
+	require.ErrorContains(t, err, "--admin-port")
+}
+
+func TestNewTLSConfig_AdminPort(t *testing.T) {
+	viper.Reset()
+	viper.Set("tls-port", "3443")
+	viper.Set("tls-hosts", []string{"localhost"})
+	viper.Set("tls-mode", "valid")
+	viper.Set("port", "3000")
+	viper.Set("admin-port", "8080")
+
+	tlsConfig, tlsOpt, err := newTLSConfig(log.New(log.WithLevel("info")), events.NewBus(), nil)
+	require.NoError(t, err)
+	require.NotNil(t, tlsConfig)
+	require.NotNil(t, tlsOpt)
+}
+
+func TestNewTLSConfig_ClientCAs(t *testing.T) {
+	viper.Reset()
+	viper.Set("tls-port", "3443")
+	viper.Set("tls-hosts", []string{"localhost"})
+	viper.Set("tls-mode", "valid")
+
+	pool := x509.NewCertPool()
+	tlsConfig, _, err := newTLSConfig(log.New(log.WithLevel("info")), events.NewBus(), pool)
+	require.NoError(t, err)
+	require.Same(t, pool, tlsConfig.ClientCAs)
+	require.Equal(t, tls.VerifyClientCertIfGiven, tlsConfig.ClientAuth)
+}
+
+func TestServeTLS(t *testing.T) {
+	viper.Reset()
+	viper.Set("tls-port", "0")
+	viper.Set("tls-hosts", []string{"127.0.0.1"})
+	viper.Set("tls-mode", "valid")
+	addr := startTLSServer(t)
+
+	// the CA is not trusted yet
+	_, err := get(t, &tls.Config{}, "https://"+addr+"/checks/health")
+	require.Error(t, err)
+
+	resp, err := get(t, &tls.Config{InsecureSkipVerify: true}, "https://"+addr+"/api/tls/ca.pem")
+	require.NoError(t, err)
+	ca, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(ca))
+	trusted := &tls.Config{RootCAs: roots}
+
+	resp, err = get(t, trusted, "https://"+addr+"/checks/health")
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, resp.ProtoMajor)
+
+	// serving an expired certificate
+	req, err := http.NewRequest(http.MethodPut, "https://"+addr+"/api/tls", strings.NewReader(`{"mode": "expired"}`))
+	require.NoError(t, err)
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: trusted, ForceAttemptHTTP2: true}}
+	resp, err = client.Do(req)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	_, err = get(t, trusted, "https://"+addr+"/checks/health")
+	require.ErrorContains(t, err, "expired")
+}
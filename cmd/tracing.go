@@ -0,0 +1,57 @@
+package cmd
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+	"github.com/pixelfactoryio/crashlooper/internal/tracing"
+)
+
+// shutdownTimeout bounds the time spent flushing the spans on exit.
+const shutdownTimeout = 2 * time.Second
+
+// newTracerProvider returns the tracer provider exporting the spans to the
+// configured collector, or nil when tracing is disabled.
+func newTracerProvider(logger *log.DefaultLogger, pod *podinfo.Info) (*sdktrace.TracerProvider, error) {
+	endpoint := viper.GetString("otlp-endpoint")
+	if endpoint == "" {
+		return nil, nil
+	}
+
+	tp, err := tracing.NewProvider(context.Background(), tracing.Config{
+		Endpoint:    endpoint,
+		Protocol:    tracing.Protocol(viper.GetString("otlp-protocol")),
+		SampleRatio: viper.GetFloat64("trace-sample-ratio"),
+		Version:     getVersionString(),
+		Pod:         pod,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info(
+		"Exporting traces",
+		fields.String("endpoint", endpoint),
+		fields.String("protocol", viper.GetString("otlp-protocol")),
+	)
+	return tp, nil
+}
+
+// shutdownTracing flushes the spans of tp, if any.
+func shutdownTracing(logger log.Logger, tp *sdktrace.TracerProvider) {
+	if tp == nil {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := tp.Shutdown(ctx); err != nil {
+		logger.Warn("Unable to flush traces", fields.Error(err))
+	}
+}
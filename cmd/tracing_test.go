@@ -0,0 +1,35 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+func TestNewTracerProvider(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	pod := &podinfo.Info{Hostname: "localhost"}
+
+	viper.Reset()
+	tp, err := newTracerProvider(logger, pod)
+	require.NoError(t, err)
+	require.Nil(t, tp)
+	shutdownTracing(logger, tp)
+
+	viper.Set("otlp-endpoint", "localhost:4317")
+	viper.Set("otlp-protocol", "grpc")
+	_, err = newTracerProvider(logger, pod)
+	require.Error(t, err)
+
+	viper.Set("otlp-endpoint", "http://127.0.0.1:4318")
+	viper.Set("otlp-protocol", "http")
+	viper.Set("trace-sample-ratio", 1)
+	tp, err = newTracerProvider(logger, pod)
+	require.NoError(t, err)
+	require.NotNil(t, tp)
+	shutdownTracing(logger, tp)
+}
@@ -0,0 +1,50 @@
+package cmd
+
+import (
+	"github.com/pkg/errors"
+	"github.com/spf13/viper"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+	"github.com/pixelfactoryio/crashlooper/internal/webhook"
+)
+
+// newWebhooks returns the event bus options notifying the webhooks
+// configured by the flags, none when no webhook is configured.
+func newWebhooks(logger *log.DefaultLogger, pod *podinfo.Info) ([]events.Option, error) {
+	urls := viper.GetStringSlice("webhook-url")
+	if len(urls) == 0 {
+		return nil, nil
+	}
+
+	headers, err := webhook.ParseHeaders(viper.GetStringSlice("webhook-header"))
+	if err != nil {
+		return nil, err
+	}
+
+	var phases []events.Type
+	for _, p := range viper.GetStringSlice("webhook-phases") {
+		t, ok := events.ParseType(p)
+		if !ok {
+			return nil, errors.Errorf("unknown webhook phase %q, must be one of scheduled, started, progress, triggered or cancelled", p)
+		}
+		phases = append(phases, t)
+	}
+
+	n, err := webhook.New(logger, webhook.Config{
+		URLs:        urls,
+		Headers:     headers,
+		Template:    viper.GetString("webhook-template"),
+		Phases:      phases,
+		Timeout:     viper.GetDuration("webhook-timeout"),
+		Retries:     viper.GetInt("webhook-retries"),
+		ExitTimeout: viper.GetDuration("webhook-exit-timeout"),
+	}, webhook.WithPod(pod))
+	if err != nil {
+		return nil, err
+	}
+
+	go n.Start()
+	return []events.Option{events.WithHook(n.Notify)}, nil
+}
@@ -0,0 +1,59 @@
+package cmd
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+func TestNewWebhooks(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	pod := &podinfo.Info{Hostname: "localhost"}
+
+	viper.Reset()
+	opts, err := newWebhooks(logger, pod)
+	require.NoError(t, err)
+	require.Empty(t, opts)
+
+	received := make(chan string, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		received <- r.Header.Get("X-Team") + " " + string(b)
+	}))
+	defer srv.Close()
+
+	viper.Set("webhook-url", []string{srv.URL})
+	viper.Set("webhook-header", []string{"invalid"})
+	viper.Set("webhook-timeout", time.Second)
+	viper.Set("webhook-exit-timeout", time.Second)
+	_, err = newWebhooks(logger, pod)
+	require.Error(t, err)
+
+	viper.Set("webhook-header", []string{"X-Team: chaos"})
+	viper.Set("webhook-phases", []string{"scheduled", "triggerd"})
+	_, err = newWebhooks(logger, pod)
+	require.ErrorContains(t, err, `unknown webhook phase "triggerd"`)
+
+	viper.Set("webhook-template", "{{.Source}} {{.Type}}")
+	viper.Set("webhook-phases", []string{"scheduled"})
+	opts, err = newWebhooks(logger, pod)
+	require.NoError(t, err)
+
+	events.NewBus(opts...).Publish(events.Event{Type: events.TypeScheduled, Source: "crash"})
+	select {
+	case got := <-received:
+		require.Equal(t, "chaos crash scheduled", got)
+	case <-time.After(time.Second):
+		t.Fatal("webhook not notified")
+	}
+	viper.Reset()
+}
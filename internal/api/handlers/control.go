@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+	"github.com/pixelfactoryio/crashlooper/internal/rota"
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/internal/services/tcpproxy"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// Controls groups the controllers of a running crashlooper, any of them may be nil.
+type Controls struct {
+	Crash     CrashController
+	Memory    MemoryController
+	Pids      PidsController
+	Faults    *chaos.Controller
+	TCPFaults TCPFaultsController
+	TLS       TLSController
+	Rota      RotaController
+	// Pod identifies the pod running crashlooper.
+	Pod *podinfo.Info
+	// Probes is true when the health checks are served on the port of the
+	// status API.
+	Probes bool
+}
+
+// ControlStatus is the state of the controls of a running crashlooper.
+type ControlStatus struct {
+	StartedAt time.Time        `json:"started_at"`
+	Uptime    chaos.Duration   `json:"uptime"`
+	Pod       *podinfo.Info    `json:"pod,omitempty"`
+	Probes    bool             `json:"probes"`
+	Crash     *crash.Schedule  `json:"crash,omitempty"`
+	Memory    *memory.Status   `json:"memory,omitempty"`
+	Pids      *pids.Status     `json:"pids,omitempty"`
+	Faults    []chaos.Fault    `json:"faults"`
+	TCPFaults *tcpproxy.Faults `json:"tcp_faults,omitempty"`
+	TLS       *certs.Status    `json:"tls,omitempty"`
+	Rota      *rota.View       `json:"rota,omitempty"`
+}
+
+type controlStatusHandler struct {
+	startedAt time.Time
+	controls  Controls
+}
+
+// NewControlStatusHandler returns a new controlStatusHandler instance.
+func NewControlStatusHandler(startedAt time.Time, controls Controls) http.Handler {
+	return &controlStatusHandler{startedAt, controls}
+}
+
+// ServeHTTP respond with the state of every control.
+func (h *controlStatusHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	status := ControlStatus{
+		StartedAt: h.startedAt,
+		Uptime:    chaos.Duration(time.Since(h.startedAt).Truncate(time.Second)),
+		Pod:       h.controls.Pod,
+		Probes:    h.controls.Probes,
+		Faults:    []chaos.Fault{},
+	}
+	if h.controls.Crash != nil {
+		schedule := h.controls.Crash.Pending()
+		status.Crash = &schedule
+	}
+	if h.controls.Memory != nil {
+		memStatus := h.controls.Memory.Status()
+		status.Memory = &memStatus
+	}
+	if h.controls.Pids != nil {
+		pidsStatus := h.controls.Pids.Status()
+		status.Pids = &pidsStatus
+	}
+	if h.controls.Faults != nil {
+		status.Faults = h.controls.Faults.Faults()
+	}
+	if h.controls.TCPFaults != nil {
+		tcpFaults := h.controls.TCPFaults.Faults()
+		status.TCPFaults = &tcpFaults
+	}
+	if h.controls.TLS != nil {
+		tlsStatus := h.controls.TLS.Status()
+		status.TLS = &tlsStatus
+	}
+	if h.controls.Rota != nil {
+		view := h.controls.Rota.View()
+		status.Rota = &view
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(status)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+func TestNewControlStatusHandler(t *testing.T) {
+	handler := NewControlStatusHandler(time.Now(), Controls{})
+	require.NotNil(t, handler)
+	require.IsType(t, &controlStatusHandler{}, handler)
+}
+
+func TestControlStatusHandler_ServeHTTP(t *testing.T) {
+	faults := chaos.NewController()
+	require.NoError(t, faults.Set(chaos.Fault{Kind: chaos.KindError, Probability: 0.5, StatusCode: 503}))
+
+	crashCtrl := &fakeCrash{}
+	_, err := crashCtrl.Schedule(context.Background(), time.Minute, 1)
+	require.NoError(t, err)
+
+	memCtrl := &fakeMemory{settings: memory.Settings{Target: units.GiB, Increment: 100 * units.MiB}}
+
+	startedAt := time.Now().Add(-time.Hour)
+	handler := NewControlStatusHandler(startedAt, Controls{
+		Crash:     crashCtrl,
+		Memory:    memCtrl,
+		Pids:      &fakePids{settings: pids.Settings{Mode: pids.ModeThreads, Target: 100}},
+		Faults:    faults,
+		TCPFaults: &memoryTCPFaults{},
+		TLS:       &fakeTLS{settings: certs.Settings{Mode: certs.ModeExpired}},
+		Rota:      fakeRota{},
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var status ControlStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	require.WithinDuration(t, startedAt, status.StartedAt, time.Millisecond)
+	require.GreaterOrEqual(t, time.Duration(status.Uptime), time.Hour)
+	require.NotNil(t, status.Crash)
+	require.True(t, status.Crash.Scheduled)
+	require.NotNil(t, status.Memory)
+	require.Equal(t, units.GiB, status.Memory.Target)
+	require.NotNil(t, status.Pids)
+	require.Equal(t, 50, status.Pids.Spawned)
+	require.Equal(t, faults.Faults(), status.Faults)
+	require.NotNil(t, status.TCPFaults)
+	require.NotNil(t, status.TLS)
+	require.Equal(t, certs.ModeExpired, status.TLS.Mode)
+	require.NotNil(t, status.Rota)
+	require.Equal(t, "crashlooper-x2k1", status.Rota.Next.ID)
+}
+
+func TestControlStatusHandler_ServeHTTP_NoControls(t *testing.T) {
+	handler := NewControlStatusHandler(time.Now(), Controls{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var response map[string]interface{}
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Equal(t, []interface{}{}, response["faults"])
+	require.NotContains(t, response, "crash")
+	require.NotContains(t, response, "memory")
+	require.NotContains(t, response, "pids")
+	require.NotContains(t, response, "tcp_faults")
+	require.NotContains(t, response, "tls")
+	require.NotContains(t, response, "rota")
+}
+
+func TestControlStatusHandler_ServeHTTP_MethodNotAllowed(t *testing.T) {
+	handler := NewControlStatusHandler(time.Now(), Controls{})
+
+	req := httptest.NewRequest(http.MethodPost, "/api/status", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
@@ -0,0 +1,73 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// CrashController schedules and cancels the crash of the process.
+type CrashController interface {
+	Pending() crash.Schedule
+	Schedule(ctx context.Context, after time.Duration, exitCode int) (crash.Schedule, error)
+	Cancel(ctx context.Context) bool
+}
+
+// CrashRequest is the body of the requests scheduling a crash.
+type CrashRequest struct {
+	After chaos.Duration `json:"after"`
+	// ExitCode defaults to 1.
+	ExitCode *int `json:"exit_code,omitempty"`
+}
+
+type crashHandler struct {
+	controller CrashController
+}
+
+// NewCrashHandler returns a new crashHandler instance.
+func NewCrashHandler(controller CrashController) http.Handler {
+	return &crashHandler{controller}
+}
+
+// ServeHTTP returns the pending crash on GET, schedules one on PUT and POST
+// and cancels it on DELETE.
+func (h *crashHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut, http.MethodPost:
+		var req CrashRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		exitCode := 1
+		if req.ExitCode != nil {
+			exitCode = *req.ExitCode
+		}
+		if _, err := h.controller.Schedule(r.Context(), time.Duration(req.After), exitCode); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		if !h.controller.Cancel(r.Context()) {
+			http.Error(w, "no crash scheduled", http.StatusNotFound)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(h.controller.Pending())
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
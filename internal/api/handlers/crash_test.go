@@ -0,0 +1,118 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+)
+
+type fakeCrash struct {
+	schedule crash.Schedule
+	after    time.Duration
+}
+
+func (f *fakeCrash) Pending() crash.Schedule {
+	return f.schedule
+}
+
+func (f *fakeCrash) Schedule(_ context.Context, after time.Duration, exitCode int) (crash.Schedule, error) {
+	if after < 0 || exitCode > 255 {
+		return crash.Schedule{}, fmt.Errorf("invalid crash")
+	}
+	f.after = after
+	f.schedule = crash.Schedule{Scheduled: true, At: time.Now().Add(after), ExitCode: exitCode}
+	return f.schedule, nil
+}
+
+func (f *fakeCrash) Cancel(context.Context) bool {
+	scheduled := f.schedule.Scheduled
+	f.schedule = crash.Schedule{}
+	return scheduled
+}
+
+func TestNewCrashHandler(t *testing.T) {
+	handler := NewCrashHandler(&fakeCrash{})
+	require.NotNil(t, handler)
+	require.IsType(t, &crashHandler{}, handler)
+}
+
+func TestCrashHandler_ServeHTTP(t *testing.T) {
+	controller := &fakeCrash{}
+	handler := NewCrashHandler(controller)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/crash", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"scheduled": false}`, rec.Body.String())
+
+	req = httptest.NewRequest(http.MethodPut, "/api/crash", strings.NewReader(`{"after": "30s", "exit_code": 137}`))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Equal(t, 30*time.Second, controller.after)
+
+	var response crash.Schedule
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.True(t, response.Scheduled)
+	require.Equal(t, 137, response.ExitCode)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/crash", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.False(t, controller.schedule.Scheduled)
+}
+
+func TestCrashHandler_ServeHTTP_DefaultExitCode(t *testing.T) {
+	controller := &fakeCrash{}
+	handler := NewCrashHandler(controller)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/crash", strings.NewReader(`{}`))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, time.Duration(0), controller.after)
+	require.Equal(t, 1, controller.schedule.ExitCode)
+}
+
+func TestCrashHandler_ServeHTTP_Invalid(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+	}{
+		{"invalid json", http.MethodPut, `{`, http.StatusBadRequest},
+		{"invalid exit code", http.MethodPut, `{"exit_code": 300}`, http.StatusBadRequest},
+		{"negative delay", http.MethodPut, `{"after": "-1s"}`, http.StatusBadRequest},
+		{"nothing to cancel", http.MethodDelete, ``, http.StatusNotFound},
+		{"method not allowed", http.MethodPatch, ``, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewCrashHandler(&fakeCrash{})
+
+			req := httptest.NewRequest(tt.method, "/api/crash", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
@@ -1,9 +1,13 @@
 package handlers
 
 import (
+	_ "embed"
 	"net/http"
 )
 
+//go:embed dashboard/index.html
+var dashboard []byte
+
 type defaultHandler struct{}
 
 // NewDefaultHandler returns a new defaultHandler instance.
@@ -11,21 +15,12 @@ func NewDefaultHandler() http.Handler {
 	return &defaultHandler{}
 }
 
-// ServeHTTP respond with default index.
+// ServeHTTP respond with the dashboard, it drives the control API from the browser.
 func (h *defaultHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
 	w.WriteHeader(http.StatusOK)
 
-	_, err := w.Write([]byte(`
-	<html>
-		<head>
-			<title>CrashLooper</title>
-		</head>
-		<body>
-			<h1>CrashLooper</h1>
-			<p><a href='/shutdown'>Shutdown</a></p>
-		</body>
-	</html>`,
-	))
+	_, err := w.Write(dashboard)
 
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
@@ -46,7 +46,7 @@ func TestDefaultHandler_ServeHTTP(t *testing.T) {
 			require.Equal(t, tt.expectedStatus, rec.Code)
 			require.Contains(t, rec.Body.String(), tt.expectedBody)
 			require.Contains(t, rec.Body.String(), "<title>CrashLooper</title>")
-			require.Contains(t, rec.Body.String(), "<a href='/shutdown'>Shutdown</a>")
+			require.Equal(t, "text/html; charset=utf-8", rec.Header().Get("Content-Type"))
 		})
 	}
 }
@@ -68,3 +68,20 @@ func TestDefaultHandler_ServeHTTP_ResponseFormat(t *testing.T) {
 	require.Contains(t, body, "<h1>CrashLooper</h1>")
 	require.Contains(t, body, "</html>")
 }
+
+func TestDefaultHandler_ServeHTTP_Dashboard(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+
+	handler := NewDefaultHandler()
+	handler.ServeHTTP(rec, req)
+
+	body := rec.Body.String()
+	// The dashboard drives the control API
+	require.Contains(t, body, `"/api/status"`)
+	require.Contains(t, body, `"/api/crash"`)
+	require.Contains(t, body, `"/api/memory"`)
+	require.Contains(t, body, `"/api/faults/"`)
+	require.Contains(t, body, `"/checks/health"`)
+	require.Contains(t, body, `new EventSource("/events")`)
+}
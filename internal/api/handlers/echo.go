@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+const (
+	// maxEchoBody is the size of the request body echoed, the rest is discarded.
+	maxEchoBody = 1 << 20
+	// maxEchoSize is the largest response size requested with the size parameter.
+	maxEchoSize = 100 << 20
+)
+
+// Echo describes the request received by the echo handler.
+type Echo struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	Proto      string      `json:"proto"`
+	Host       string      `json:"host"`
+	RemoteAddr string      `json:"remote_addr"`
+	Headers    http.Header `json:"headers"`
+	Body       string      `json:"body,omitempty"`
+	// BodyTruncated is set when the body is larger than the echoed part.
+	BodyTruncated bool          `json:"body_truncated,omitempty"`
+	TLS           *EchoTLS      `json:"tls,omitempty"`
+	Pod           *podinfo.Info `json:"pod"`
+}
+
+// EchoTLS describes the TLS connection of a request.
+type EchoTLS struct {
+	Version            string   `json:"version"`
+	CipherSuite        string   `json:"cipher_suite"`
+	ServerName         string   `json:"server_name,omitempty"`
+	NegotiatedProtocol string   `json:"negotiated_protocol,omitempty"`
+	PeerCertificates   []string `json:"peer_certificates,omitempty"`
+}
+
+type echoHandler struct {
+	pod *podinfo.Info
+}
+
+// NewEchoHandler returns a new echoHandler instance, identifying the pod
+// answering the requests with pod.
+func NewEchoHandler(pod *podinfo.Info) http.Handler {
+	return &echoHandler{pod: pod}
+}
+
+// ServeHTTP responds with the description of the request. The status
+// parameter sets the response status code, and the size parameter pads the
+// response with spaces to the given number of bytes.
+func (h *echoHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	code, size, err := echoParams(r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxEchoBody+1))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	e := Echo{
+		Method:     r.Method,
+		URL:        r.URL.String(),
+		Proto:      r.Proto,
+		Host:       r.Host,
+		RemoteAddr: r.RemoteAddr,
+		Headers:    r.Header,
+		TLS:        echoTLS(r.TLS),
+		Pod:        h.pod,
+	}
+	if len(body) > maxEchoBody {
+		body, e.BodyTruncated = body[:maxEchoBody], true
+	}
+	e.Body = string(body)
+
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(e); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if pad := size - buf.Len(); pad > 0 {
+		buf.Write(bytes.Repeat([]byte{' '}, pad))
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(buf.Len()))
+	w.WriteHeader(code)
+	_, _ = buf.WriteTo(w)
+}
+
+// echoParams returns the response status code and size requested by r.
+func echoParams(r *http.Request) (int, int, error) {
+	code, size := http.StatusOK, 0
+	q := r.URL.Query()
+
+	if v := q.Get("status"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 200 || n > 599 {
+			return 0, 0, fmt.Errorf("invalid status %q, must be in [200, 599]", v)
+		}
+		code = n
+	}
+
+	if v := q.Get("size"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 || n > maxEchoSize {
+			return 0, 0, fmt.Errorf("invalid size %q, must be in [0, %d]", v, maxEchoSize)
+		}
+		size = n
+	}
+
+	return code, size, nil
+}
+
+func echoTLS(state *tls.ConnectionState) *EchoTLS {
+	if state == nil {
+		return nil
+	}
+
+	t := &EchoTLS{
+		Version:            tls.VersionName(state.Version),
+		CipherSuite:        tls.CipherSuiteName(state.CipherSuite),
+		ServerName:         state.ServerName,
+		NegotiatedProtocol: state.NegotiatedProtocol,
+	}
+	for _, cert := range state.PeerCertificates {
+		t.PeerCertificates = append(t.PeerCertificates, cert.Subject.String())
+	}
+	return t
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+var testPod = &podinfo.Info{Name: "crashlooper-7d9f", Namespace: "crashlooper", Hostname: "crashlooper-7d9f"}
+
+func TestNewEchoHandler(t *testing.T) {
+	handler := NewEchoHandler(testPod)
+	require.NotNil(t, handler)
+	require.IsType(t, &echoHandler{}, handler)
+	require.Equal(t, testPod, handler.(*echoHandler).pod)
+}
+
+func TestEchoHandler_ServeHTTP(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/echo?debug=1", strings.NewReader("hello"))
+	req.Header.Set("X-Request-Id", "abc")
+	rec := httptest.NewRecorder()
+
+	NewEchoHandler(testPod).ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var e Echo
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&e))
+	require.Equal(t, http.MethodPost, e.Method)
+	require.Equal(t, "/echo?debug=1", e.URL)
+	require.Equal(t, "HTTP/1.1", e.Proto)
+	require.Equal(t, "example.com", e.Host)
+	require.Equal(t, "192.0.2.1:1234", e.RemoteAddr)
+	require.Equal(t, "abc", e.Headers.Get("X-Request-Id"))
+	require.Equal(t, "hello", e.Body)
+	require.Nil(t, e.TLS)
+	require.Equal(t, testPod, e.Pod)
+}
+
+func TestEchoHandler_LargeBody(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPut, "/echo", strings.NewReader(strings.Repeat("a", maxEchoBody+10)))
+	rec := httptest.NewRecorder()
+
+	NewEchoHandler(testPod).ServeHTTP(rec, req)
+
+	var e Echo
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&e))
+	require.Len(t, e.Body, maxEchoBody)
+	require.True(t, e.BodyTruncated)
+}
+
+func TestEchoHandler_TLS(t *testing.T) {
+	srv := httptest.NewTLSServer(NewEchoHandler(testPod))
+	defer srv.Close()
+
+	resp, err := srv.Client().Get(srv.URL + "/echo")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var e Echo
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&e))
+	require.NotNil(t, e.TLS)
+	require.Equal(t, "TLS 1.3", e.TLS.Version)
+	require.NotEmpty(t, e.TLS.CipherSuite)
+}
+
+func TestEchoHandler_Params(t *testing.T) {
+	tests := []struct {
+		name           string
+		query          string
+		expectedStatus int
+		expectedSize   int
+	}{
+		{
+			name:           "status",
+			query:          "?status=418",
+			expectedStatus: http.StatusTeapot,
+		},
+		{
+			name:           "size",
+			query:          "?size=4096",
+			expectedStatus: http.StatusOK,
+			expectedSize:   4096,
+		},
+		{
+			name:           "size smaller than the description",
+			query:          "?size=1",
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "invalid status",
+			query:          "?status=99",
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid size",
+			query:          "?size=-1",
+			expectedStatus: http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			NewEchoHandler(testPod).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/echo"+tt.query, nil))
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			if tt.expectedSize > 0 {
+				require.Equal(t, tt.expectedSize, rec.Body.Len())
+				require.True(t, json.Valid(rec.Body.Bytes()))
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+)
+
+// readEvent reads the next event of an SSE stream and returns its fields.
+func readEvent(t *testing.T, r *bufio.Reader) map[string]string {
+	t.Helper()
+
+	fields := make(map[string]string)
+	for {
+		line, err := r.ReadString('\n')
+		require.NoError(t, err)
+
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return fields
+		}
+		k, v, _ := strings.Cut(line, ": ")
+		fields[k] = v
+	}
+}
+
+func TestNewEventsHandler(t *testing.T) {
+	handler := NewEventsHandler(events.NewBus())
+	require.NotNil(t, handler)
+	require.IsType(t, &eventsHandler{}, handler)
+}
+
+func TestEventsHandler_ServeHTTP(t *testing.T) {
+	bus := events.NewBus()
+	bus.Publish(events.Event{Type: events.TypeScheduled, Source: "crash"})
+
+	srv := httptest.NewServer(NewEventsHandler(bus))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	r := bufio.NewReader(resp.Body)
+
+	// recent events are replayed
+	e := readEvent(t, r)
+	require.Equal(t, "1", e["id"])
+	require.Equal(t, "scheduled", e["event"])
+	require.Contains(t, e["data"], `"source":"crash"`)
+
+	bus.Publish(events.Event{Type: events.TypeProgress, Source: "memory", Data: map[string]interface{}{"allocated": "1MiB"}})
+
+	e = readEvent(t, r)
+	require.Equal(t, "2", e["id"])
+	require.Equal(t, "progress", e["event"])
+	require.Contains(t, e["data"], `"allocated":"1MiB"`)
+}
+
+func TestEventsHandler_ServeHTTP_LastEventIDAndSource(t *testing.T) {
+	bus := events.NewBus()
+	bus.Publish(events.Event{Type: events.TypeScheduled, Source: "crash"})
+	bus.Publish(events.Event{Type: events.TypeStarted, Source: "memory"})
+	bus.Publish(events.Event{Type: events.TypeCancelled, Source: "crash"})
+	bus.Publish(events.Event{Type: events.TypeTriggered, Source: "crash"})
+
+	srv := httptest.NewServer(NewEventsHandler(bus))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"?source=crash", nil)
+	require.NoError(t, err)
+	req.Header.Set("Last-Event-ID", "1")
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	r := bufio.NewReader(resp.Body)
+	require.Equal(t, "cancelled", readEvent(t, r)["event"])
+	require.Equal(t, "triggered", readEvent(t, r)["event"])
+}
+
+func TestEventsHandler_ServeHTTP_Invalid(t *testing.T) {
+	handler := NewEventsHandler(events.NewBus())
+
+	req := httptest.NewRequest(http.MethodPost, "/events", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Last-Event-ID", "abc")
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadRequest, rec.Code)
+}
@@ -0,0 +1,39 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pixelfactoryio/crashlooper/internal/cgroup"
+)
+
+// MemoryStatsProvider provides the last memory statistics read from the cgroup.
+type MemoryStatsProvider interface {
+	Stats() *cgroup.MemoryStats
+}
+
+type memoryHandler struct {
+	provider MemoryStatsProvider
+}
+
+// NewMemoryHandler returns a new memoryHandler instance.
+func NewMemoryHandler(provider MemoryStatsProvider) http.Handler {
+	return &memoryHandler{provider}
+}
+
+// ServeHTTP respond with the cgroup memory statistics and events.
+func (h *memoryHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	stats := h.provider.Stats()
+	if stats == nil {
+		http.Error(w, "cgroup memory statistics unavailable", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(stats)
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
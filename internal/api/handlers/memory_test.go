@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/cgroup"
+)
+
+type staticMemoryStats struct {
+	stats *cgroup.MemoryStats
+}
+
+func (s *staticMemoryStats) Stats() *cgroup.MemoryStats {
+	return s.stats
+}
+
+func TestNewMemoryHandler(t *testing.T) {
+	handler := NewMemoryHandler(&staticMemoryStats{})
+	require.NotNil(t, handler)
+	require.IsType(t, &memoryHandler{}, handler)
+}
+
+func TestMemoryHandler_ServeHTTP(t *testing.T) {
+	provider := &staticMemoryStats{
+		stats: &cgroup.MemoryStats{
+			Version: cgroup.V2,
+			Current: 1024,
+			Limit:   2048,
+			Events:  cgroup.MemoryEvents{High: 1, OOMKill: 2},
+		},
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/memory", nil)
+	rec := httptest.NewRecorder()
+
+	handler := NewMemoryHandler(provider)
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var response cgroup.MemoryStats
+	err := json.NewDecoder(rec.Body).Decode(&response)
+	require.NoError(t, err)
+	require.Equal(t, *provider.stats, response)
+}
+
+func TestMemoryHandler_ServeHTTP_Unavailable(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/memory", nil)
+	rec := httptest.NewRecorder()
+
+	handler := NewMemoryHandler(&staticMemoryStats{})
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+}
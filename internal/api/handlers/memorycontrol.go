@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+)
+
+// MemoryController reads and replaces the memory usage target.
+type MemoryController interface {
+	Status() memory.Status
+	Set(context.Context, memory.Settings) error
+}
+
+type memoryControlHandler struct {
+	controller MemoryController
+}
+
+// NewMemoryControlHandler returns a new memoryControlHandler instance.
+func NewMemoryControlHandler(controller MemoryController) http.Handler {
+	return &memoryControlHandler{controller}
+}
+
+// ServeHTTP returns the memory usage target on GET, replaces it on PUT and releases the memory on DELETE.
+func (h *memoryControlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var settings memory.Settings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.controller.Set(r.Context(), settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		if err := h.controller.Set(r.Context(), memory.Settings{}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(h.controller.Status())
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+type fakeMemory struct {
+	settings memory.Settings
+}
+
+func (f *fakeMemory) Status() memory.Status {
+	return memory.Status{Settings: f.settings, Allocated: f.settings.Target / 2}
+}
+
+func (f *fakeMemory) Set(_ context.Context, settings memory.Settings) error {
+	if settings.Target < 0 {
+		return fmt.Errorf("invalid target")
+	}
+	f.settings = settings
+	return nil
+}
+
+func TestNewMemoryControlHandler(t *testing.T) {
+	handler := NewMemoryControlHandler(&fakeMemory{})
+	require.NotNil(t, handler)
+	require.IsType(t, &memoryControlHandler{}, handler)
+}
+
+func TestMemoryControlHandler_ServeHTTP(t *testing.T) {
+	controller := &fakeMemory{}
+	handler := NewMemoryControlHandler(controller)
+
+	body := `{"target": "1GiB", "increment": "100MiB", "interval": "1s"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/memory", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, memory.Settings{
+		Target:    units.GiB,
+		Increment: 100 * units.MiB,
+		Interval:  chaos.Duration(time.Second),
+	}, controller.settings)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/memory", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var response memory.Status
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Equal(t, controller.Status(), response)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/memory", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, memory.Settings{}, controller.settings)
+}
+
+func TestMemoryControlHandler_ServeHTTP_Invalid(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+	}{
+		{"invalid json", http.MethodPut, `{`, http.StatusBadRequest},
+		{"invalid size", http.MethodPut, `{"target": "lots"}`, http.StatusBadRequest},
+		{"negative target", http.MethodPut, `{"target": -1}`, http.StatusBadRequest},
+		{"method not allowed", http.MethodPost, `{}`, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewMemoryControlHandler(&fakeMemory{})
+
+			req := httptest.NewRequest(tt.method, "/api/memory", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+)
+
+// PidsController reads and replaces the threads or processes target.
+type PidsController interface {
+	Status() pids.Status
+	Set(context.Context, pids.Settings) error
+}
+
+type pidsControlHandler struct {
+	controller PidsController
+}
+
+// NewPidsControlHandler returns a new pidsControlHandler instance.
+func NewPidsControlHandler(controller PidsController) http.Handler {
+	return &pidsControlHandler{controller}
+}
+
+// ServeHTTP returns the threads or processes target on GET, replaces it on PUT and releases them on DELETE.
+func (h *pidsControlHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var settings pids.Settings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.controller.Set(r.Context(), settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		if err := h.controller.Set(r.Context(), pids.Settings{}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(h.controller.Status())
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/cgroup"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+type fakePids struct {
+	settings pids.Settings
+}
+
+func (f *fakePids) Status() pids.Status {
+	return pids.Status{
+		Settings: f.settings,
+		Spawned:  f.settings.Target / 2,
+		Cgroup:   &cgroup.PidsStats{Version: cgroup.V2, Current: 12, Limit: 1024},
+	}
+}
+
+func (f *fakePids) Set(_ context.Context, settings pids.Settings) error {
+	if settings.Target < 0 {
+		return fmt.Errorf("invalid target")
+	}
+	f.settings = settings
+	return nil
+}
+
+func TestNewPidsControlHandler(t *testing.T) {
+	handler := NewPidsControlHandler(&fakePids{})
+	require.NotNil(t, handler)
+	require.IsType(t, &pidsControlHandler{}, handler)
+}
+
+func TestPidsControlHandler_ServeHTTP(t *testing.T) {
+	controller := &fakePids{}
+	handler := NewPidsControlHandler(controller)
+
+	body := `{"mode": "processes", "target": 100, "increment": 10, "interval": "1s"}`
+	req := httptest.NewRequest(http.MethodPut, "/api/pids", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, pids.Settings{
+		Mode:      pids.ModeProcesses,
+		Target:    100,
+		Increment: 10,
+		Interval:  chaos.Duration(time.Second),
+	}, controller.settings)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/pids", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var response pids.Status
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Equal(t, controller.Status(), response)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/pids", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, pids.Settings{}, controller.settings)
+}
+
+func TestPidsControlHandler_ServeHTTP_Invalid(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+	}{
+		{"invalid json", http.MethodPut, `{`, http.StatusBadRequest},
+		{"invalid target", http.MethodPut, `{"target": "lots"}`, http.StatusBadRequest},
+		{"negative target", http.MethodPut, `{"target": -1}`, http.StatusBadRequest},
+		{"method not allowed", http.MethodPost, `{}`, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewPidsControlHandler(&fakePids{})
+
+			req := httptest.NewRequest(tt.method, "/api/pids", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
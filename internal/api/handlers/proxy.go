@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+)
+
+// NewProxyHandler returns a reverse proxy forwarding every request to upstream.
+// The Host header of the requests is the one of upstream, unless preserveHost
+// passes the Host of the client through.
+func NewProxyHandler(logger log.Logger, upstream *url.URL, preserveHost bool) http.Handler {
+	return &httputil.ReverseProxy{
+		Rewrite: func(r *httputil.ProxyRequest) {
+			r.SetURL(upstream)
+			r.SetXForwarded()
+			if preserveHost {
+				r.Out.Host = r.In.Host
+			}
+		},
+		ErrorHandler: func(w http.ResponseWriter, r *http.Request, err error) {
+			logger.Error("Unable to reach upstream", fields.String("upstream", upstream.String()), fields.Error(err))
+			w.WriteHeader(http.StatusBadGateway)
+		},
+	}
+}
@@ -0,0 +1,80 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+)
+
+func TestNewProxyHandler(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream-Path", r.URL.Path)
+		w.Header().Set("X-Upstream-Query", r.URL.RawQuery)
+		w.Header().Set("X-Upstream-Forwarded-For", r.Header.Get("X-Forwarded-For"))
+		w.Header().Set("X-Upstream-Host", r.Host)
+		w.WriteHeader(http.StatusTeapot)
+		_, _ = w.Write([]byte("from upstream"))
+	}))
+	defer upstream.Close()
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	logger := log.New(log.WithLevel("info"))
+	proxy := httptest.NewServer(NewProxyHandler(logger, upstreamURL, false))
+	defer proxy.Close()
+
+	resp, err := http.Get(proxy.URL + "/api/users?page=2")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusTeapot, resp.StatusCode)
+	require.Equal(t, "from upstream", string(body))
+	require.Equal(t, "/api/users", resp.Header.Get("X-Upstream-Path"))
+	require.Equal(t, "page=2", resp.Header.Get("X-Upstream-Query"))
+	require.Equal(t, "127.0.0.1", resp.Header.Get("X-Upstream-Forwarded-For"))
+	require.Equal(t, upstreamURL.Host, resp.Header.Get("X-Upstream-Host"))
+}
+
+func TestNewProxyHandler_PreserveHost(t *testing.T) {
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("X-Upstream-Host", r.Host)
+	}))
+	defer upstream.Close()
+
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+
+	logger := log.New(log.WithLevel("info"))
+	handler := NewProxyHandler(logger, upstreamURL, true)
+
+	req := httptest.NewRequest(http.MethodGet, "http://shop.example.com/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, "shop.example.com", rec.Header().Get("X-Upstream-Host"))
+}
+
+func TestNewProxyHandler_UpstreamDown(t *testing.T) {
+	upstream := httptest.NewServer(http.NotFoundHandler())
+	upstreamURL, err := url.Parse(upstream.URL)
+	require.NoError(t, err)
+	upstream.Close()
+
+	logger := log.New(log.WithLevel("info"))
+	handler := NewProxyHandler(logger, upstreamURL, false)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusBadGateway, rec.Code)
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/pixelfactoryio/crashlooper/internal/rota"
+)
+
+// RotaController reports the crash rota as seen by the replica.
+type RotaController interface {
+	View() rota.View
+}
+
+type rotaHandler struct {
+	controller RotaController
+}
+
+// NewRotaHandler returns a new rotaHandler instance.
+func NewRotaHandler(controller RotaController) http.Handler {
+	return &rotaHandler{controller}
+}
+
+// ServeHTTP respond with the crash rota, the other replicas learn the
+// identity of the replica from it.
+func (h *rotaHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(h.controller.View())
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
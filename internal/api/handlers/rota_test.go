@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/rota"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+type fakeRota struct{}
+
+func (fakeRota) View() rota.View {
+	return rota.View{
+		ID:       "crashlooper-7d9f",
+		Interval: chaos.Duration(2 * time.Minute),
+		Members:  []string{"crashlooper-7d9f", "crashlooper-x2k1"},
+		Next:     rota.Turn{At: time.Unix(120, 0), ID: "crashlooper-x2k1"},
+	}
+}
+
+func TestNewRotaHandler(t *testing.T) {
+	handler := NewRotaHandler(fakeRota{})
+	require.NotNil(t, handler)
+	require.IsType(t, &rotaHandler{}, handler)
+}
+
+func TestRotaHandler_ServeHTTP(t *testing.T) {
+	handler := NewRotaHandler(fakeRota{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/rota", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var view rota.View
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &view))
+	require.Equal(t, "crashlooper-7d9f", view.ID)
+	require.Equal(t, chaos.Duration(2*time.Minute), view.Interval)
+	require.Len(t, view.Members, 2)
+	require.Equal(t, "crashlooper-x2k1", view.Next.ID)
+	require.True(t, view.Next.At.Equal(time.Unix(120, 0)))
+
+	req = httptest.NewRequest(http.MethodPost, "/api/rota", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+	require.Equal(t, http.MethodGet, rec.Header().Get("Allow"))
+}
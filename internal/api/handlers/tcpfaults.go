@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/tcpproxy"
+)
+
+// TCPFaultsController reads and replaces the faults of the TCP proxy.
+type TCPFaultsController interface {
+	Faults() tcpproxy.Faults
+	SetFaults(context.Context, tcpproxy.Faults) error
+}
+
+type tcpFaultsHandler struct {
+	controller TCPFaultsController
+}
+
+// NewTCPFaultsHandler returns a new tcpFaultsHandler instance.
+func NewTCPFaultsHandler(controller TCPFaultsController) http.Handler {
+	return &tcpFaultsHandler{controller}
+}
+
+// ServeHTTP returns the TCP proxy faults on GET, replaces them on PUT and removes them on DELETE.
+func (h *tcpFaultsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var faults tcpproxy.Faults
+		if err := json.NewDecoder(r.Body).Decode(&faults); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.controller.SetFaults(r.Context(), faults); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	case http.MethodDelete:
+		if err := h.controller.SetFaults(r.Context(), tcpproxy.Faults{}); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(h.controller.Faults())
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/tcpproxy"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+type memoryTCPFaults struct {
+	faults tcpproxy.Faults
+}
+
+func (m *memoryTCPFaults) Faults() tcpproxy.Faults {
+	return m.faults
+}
+
+func (m *memoryTCPFaults) SetFaults(_ context.Context, f tcpproxy.Faults) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+	m.faults = f
+	return nil
+}
+
+func TestNewTCPFaultsHandler(t *testing.T) {
+	handler := NewTCPFaultsHandler(&memoryTCPFaults{})
+	require.NotNil(t, handler)
+	require.IsType(t, &tcpFaultsHandler{}, handler)
+}
+
+func TestTCPFaultsHandler_ServeHTTP(t *testing.T) {
+	controller := &memoryTCPFaults{}
+	handler := NewTCPFaultsHandler(controller)
+
+	body := `{"latency": "100ms", "bandwidth": "64KiB", "disconnect_probability": 0.1, "half_open": true}`
+	req := httptest.NewRequest(http.MethodPut, "/api/tcp/faults", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, tcpproxy.Faults{
+		Latency:               chaos.Duration(100 * time.Millisecond),
+		Bandwidth:             64 * units.KiB,
+		DisconnectProbability: 0.1,
+		HalfOpen:              true,
+	}, controller.faults)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/tcp/faults", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var response tcpproxy.Faults
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&response))
+	require.Equal(t, controller.faults, response)
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/tcp/faults", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, tcpproxy.Faults{}, controller.faults)
+}
+
+func TestTCPFaultsHandler_ServeHTTP_Invalid(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+	}{
+		{
+			name:           "malformed body",
+			method:         http.MethodPut,
+			body:           `{`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "invalid faults",
+			method:         http.MethodPut,
+			body:           `{"disconnect_probability": 3}`,
+			expectedStatus: http.StatusBadRequest,
+		},
+		{
+			name:           "unsupported method",
+			method:         http.MethodPatch,
+			expectedStatus: http.StatusMethodNotAllowed,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler := NewTCPFaultsHandler(&memoryTCPFaults{})
+
+			req := httptest.NewRequest(tt.method, "/api/tcp/faults", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
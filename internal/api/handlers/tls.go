@@ -0,0 +1,76 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+)
+
+// TLSController reads and replaces the certificate served over TLS.
+type TLSController interface {
+	Status() certs.Status
+	Set(context.Context, certs.Settings) error
+	CA() []byte
+}
+
+type tlsHandler struct {
+	controller TLSController
+}
+
+// NewTLSHandler returns a new tlsHandler instance.
+func NewTLSHandler(controller TLSController) http.Handler {
+	return &tlsHandler{controller}
+}
+
+// ServeHTTP returns the certificate served on GET and replaces its settings on PUT.
+func (h *tlsHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPut:
+		var settings certs.Settings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err := h.controller.Set(r.Context(), settings); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	err := json.NewEncoder(w).Encode(h.controller.Status())
+
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type tlsCAHandler struct {
+	controller TLSController
+}
+
+// NewTLSCAHandler returns a new tlsCAHandler instance.
+func NewTLSCAHandler(controller TLSController) http.Handler {
+	return &tlsCAHandler{controller}
+}
+
+// ServeHTTP respond with the PEM certificate of the CA signing the generated certificates.
+func (h *tlsCAHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/x-pem-file")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(h.controller.CA())
+}
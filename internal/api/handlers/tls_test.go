@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+type fakeTLS struct {
+	settings certs.Settings
+}
+
+func (f *fakeTLS) Status() certs.Status {
+	return certs.Status{Settings: f.settings, Source: "generated", Serial: "2a"}
+}
+
+func (f *fakeTLS) Set(_ context.Context, settings certs.Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+	f.settings = settings
+	return nil
+}
+
+func (f *fakeTLS) CA() []byte {
+	return []byte("-----BEGIN CERTIFICATE-----\n")
+}
+
+func TestNewTLSHandler(t *testing.T) {
+	handler := NewTLSHandler(&fakeTLS{})
+	require.NotNil(t, handler)
+	require.IsType(t, &tlsHandler{}, handler)
+}
+
+func TestTLSHandler_ServeHTTP(t *testing.T) {
+	controller := &fakeTLS{settings: certs.Settings{Mode: certs.ModeValid}}
+	handler := NewTLSHandler(controller)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tls", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var status certs.Status
+	require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &status))
+	require.Equal(t, certs.ModeValid, status.Mode)
+	require.Equal(t, "2a", status.Serial)
+
+	req = httptest.NewRequest(http.MethodPut, "/api/tls", strings.NewReader(`{"mode": "expired", "rotate_interval": "1m"}`))
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, certs.Settings{Mode: certs.ModeExpired, RotateInterval: chaos.Duration(time.Minute)}, controller.settings)
+}
+
+func TestTLSHandler_ServeHTTP_Errors(t *testing.T) {
+	handler := NewTLSHandler(&fakeTLS{})
+
+	tests := []struct {
+		name           string
+		method         string
+		body           string
+		expectedStatus int
+	}{
+		{"invalid json", http.MethodPut, `{`, http.StatusBadRequest},
+		{"unknown mode", http.MethodPut, `{"mode": "revoked"}`, http.StatusBadRequest},
+		{"unsupported method", http.MethodDelete, ``, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(tt.method, "/api/tls", strings.NewReader(tt.body))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+		})
+	}
+}
+
+func TestTLSCAHandler_ServeHTTP(t *testing.T) {
+	handler := NewTLSCAHandler(&fakeTLS{})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tls/ca.pem", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/x-pem-file", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), "BEGIN CERTIFICATE")
+
+	req = httptest.NewRequest(http.MethodPost, "/api/tls/ca.pem", nil)
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
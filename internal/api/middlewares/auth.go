@@ -0,0 +1,43 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+)
+
+// Authenticator authenticates the incoming HTTP requests.
+type Authenticator interface {
+	Authenticate(r *http.Request) error
+}
+
+// Auth rejects the requests modifying the state which are not authenticated
+// by a. Read-only requests are always allowed.
+func Auth(logger log.Logger, a Authenticator) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			switch r.Method {
+			case http.MethodGet, http.MethodHead, http.MethodOptions:
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if err := a.Authenticate(r); err != nil {
+				logger.Warn(
+					"Unauthorized request",
+					fields.String("method", r.Method),
+					fields.String("path", r.URL.EscapedPath()),
+					fields.Error(err),
+				)
+				w.Header().Set("WWW-Authenticate", `Bearer realm="crashlooper"`)
+				http.Error(w, http.StatusText(http.StatusUnauthorized), http.StatusUnauthorized)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,89 @@
+package middlewares
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+)
+
+type fakeAuthenticator struct {
+	calls int
+}
+
+func (a *fakeAuthenticator) Authenticate(r *http.Request) error {
+	a.calls++
+	if r.Header.Get("Authorization") != "Bearer secret" {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+func TestAuth(t *testing.T) {
+	tests := []struct {
+		name           string
+		method         string
+		authorization  string
+		expectedStatus int
+		expectedCalls  int
+	}{
+		{
+			name:           "read-only request",
+			method:         http.MethodGet,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "head request",
+			method:         http.MethodHead,
+			expectedStatus: http.StatusOK,
+		},
+		{
+			name:           "authenticated mutation",
+			method:         http.MethodPut,
+			authorization:  "Bearer secret",
+			expectedStatus: http.StatusOK,
+			expectedCalls:  1,
+		},
+		{
+			name:           "unauthenticated mutation",
+			method:         http.MethodDelete,
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  1,
+		},
+		{
+			name:           "wrong token",
+			method:         http.MethodPost,
+			authorization:  "Bearer wrong",
+			expectedStatus: http.StatusUnauthorized,
+			expectedCalls:  1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := &fakeAuthenticator{}
+			called := false
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				called = true
+			})
+
+			req := httptest.NewRequest(tt.method, "/api/crash", nil)
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+			rec := httptest.NewRecorder()
+
+			Auth(log.New(log.WithLevel("debug")), a)(next).ServeHTTP(rec, req)
+
+			require.Equal(t, tt.expectedStatus, rec.Code)
+			require.Equal(t, tt.expectedCalls, a.calls)
+			require.Equal(t, tt.expectedStatus == http.StatusOK, called)
+			if tt.expectedStatus == http.StatusUnauthorized {
+				require.Equal(t, `Bearer realm="crashlooper"`, rec.Header().Get("WWW-Authenticate"))
+			}
+		})
+	}
+}
@@ -26,6 +26,12 @@ func (rw *responseWriter) Status() int {
 	return rw.status
 }
 
+// Unwrap returns the original http.ResponseWriter, so that http.ResponseController
+// can reach its optional interfaces such as http.Flusher and http.Hijacker.
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func (rw *responseWriter) WriteHeader(code int) {
 	if rw.wroteHeader {
 		return
@@ -42,6 +48,10 @@ func Logging(logger log.Logger) func(http.Handler) http.Handler {
 		fn := func(w http.ResponseWriter, r *http.Request) {
 			defer func() {
 				if err := recover(); err != nil {
+					// http.ErrAbortHandler is used to abort a response on purpose
+					if err == http.ErrAbortHandler {
+						panic(err)
+					}
 					w.WriteHeader(http.StatusInternalServerError)
 					logger.Error("Internal Server Error", fields.Any("error", err))
 				}
@@ -252,3 +252,31 @@ func TestLogging_Middleware_PanicCallsLogger(t *testing.T) {
 	require.Equal(t, "Internal Server Error", mockLog.lastMessage)
 	require.Equal(t, http.StatusInternalServerError, rec.Code)
 }
+
+func TestResponseWriter_Unwrap(t *testing.T) {
+	rec := httptest.NewRecorder()
+	wrapped := wrapResponseWriter(rec)
+
+	require.Equal(t, rec, wrapped.Unwrap())
+
+	// http.ResponseController reaches the Flusher of the original writer
+	err := http.NewResponseController(wrapped).Flush()
+	require.NoError(t, err)
+	require.True(t, rec.Flushed)
+}
+
+func TestLogging_Middleware_RepanicsAbortHandler(t *testing.T) {
+	logger := log.New(log.WithLevel("debug"))
+	middleware := Logging(logger)
+
+	handler := middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic(http.ErrAbortHandler)
+	}))
+
+	req := httptest.NewRequest(http.MethodGet, "/test", nil)
+	rec := httptest.NewRecorder()
+
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		handler.ServeHTTP(rec, req)
+	})
+}
@@ -0,0 +1,20 @@
+package middlewares
+
+import (
+	"net/http"
+)
+
+// HeaderPod is the response header identifying the pod answering a request.
+const HeaderPod = "X-Crashlooper-Pod"
+
+// Pod sets the HeaderPod header of every response to pod.
+func Pod(pod string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(HeaderPod, pod)
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,21 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPod(t *testing.T) {
+	handler := Pod("crashlooper/crashlooper-7d9f")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusTeapot)
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusTeapot, rec.Code)
+	require.Equal(t, "crashlooper/crashlooper-7d9f", rec.Header().Get(HeaderPod))
+}
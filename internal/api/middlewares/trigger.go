@@ -0,0 +1,24 @@
+package middlewares
+
+import (
+	"net/http"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+)
+
+// Trigger sets the trigger of the faults applied by a request, read from its
+// events.HeaderTrigger header, in the request context. Requests can only
+// claim the api and scenario triggers, api being the default.
+func Trigger() func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			t, ok := events.ParseTrigger(r.Header.Get(events.HeaderTrigger))
+			if !ok || (t != events.TriggerAPI && t != events.TriggerScenario) {
+				t = events.TriggerAPI
+			}
+			next.ServeHTTP(w, r.WithContext(events.WithTrigger(r.Context(), t)))
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+)
+
+func TestTrigger(t *testing.T) {
+	tests := []struct {
+		header string
+		want   events.Trigger
+	}{
+		{"", events.TriggerAPI},
+		{"scenario", events.TriggerScenario},
+		{"api", events.TriggerAPI},
+		// only the flags and the rota apply faults with their triggers
+		{"flag", events.TriggerAPI},
+		{"rota", events.TriggerAPI},
+		{"cron", events.TriggerAPI},
+	}
+
+	for _, tt := range tests {
+		var got events.Trigger
+		handler := Trigger()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			got = events.TriggerFrom(r.Context())
+		}))
+
+		req := httptest.NewRequest(http.MethodPut, "/api/crash", nil)
+		req.Header.Set(events.HeaderTrigger, tt.header)
+		handler.ServeHTTP(httptest.NewRecorder(), req)
+
+		require.Equal(t, tt.want, got, tt.header)
+	}
+}
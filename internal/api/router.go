@@ -1,26 +1,268 @@
 package api
 
 import (
-	"github.com/gorilla/mux"
-	// "github.com/prometheus/client_golang/prometheus"
+	"net/http"
+	"os"
+	"time"
 
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
 	"go.pixelfactory.io/pkg/observability/log"
 
 	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
 	"github.com/pixelfactoryio/crashlooper/internal/api/middlewares"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+	"github.com/pixelfactoryio/crashlooper/internal/rota"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// Routes selects the groups of routes served by a router, so that each
+// group can be served on its own port.
+type Routes uint8
+
+const (
+	// AppRoutes are the application routes, the faults apply to them.
+	AppRoutes Routes = 1 << iota
+	// ProbeRoutes are the health checks.
+	ProbeRoutes
+	// MetricsRoutes are the Prometheus metrics.
+	MetricsRoutes
+	// AdminRoutes are the status and control APIs, the event stream and the
+	// dashboard.
+	AdminRoutes
+
+	// AllRoutes are every group of routes.
+	AllRoutes = AppRoutes | ProbeRoutes | MetricsRoutes | AdminRoutes
 )
 
+type config struct {
+	// served are the groups of routes registered on the router.
+	served     Routes
+	routes     []func(*mux.Router)
+	appHandler http.Handler
+	// appMiddlewares only apply to the application routes, never to the
+	// health checks or the control API.
+	appMiddlewares []mux.MiddlewareFunc
+	// controls are reported by the status API.
+	controls handlers.Controls
+	// auth authenticates the requests modifying the state through the
+	// optional routes, the health checks and metrics are always open.
+	auth middlewares.Authenticator
+	// probeFaults applies the appMiddlewares to the health checks too.
+	probeFaults bool
+	// tracerProvider traces the requests when set.
+	tracerProvider trace.TracerProvider
+}
+
+// Option registers optional handlers on the router.
+type Option func(*config)
+
+// WithPod identifies the pod answering the requests with info, in the
+// X-Crashlooper-Pod header of every response, the echo handler and the
+// status API. The hostname identifies it by default.
+func WithPod(info *podinfo.Info) Option {
+	return func(c *config) {
+		c.controls.Pod = info
+	}
+}
+
+// WithMemoryStats registers the cgroup memory statistics handler.
+func WithMemoryStats(provider handlers.MemoryStatsProvider) Option {
+	return func(c *config) {
+		c.routes = append(c.routes, func(router *mux.Router) {
+			router.Path("/memory").Handler(handlers.NewMemoryHandler(provider))
+		})
+	}
+}
+
+// WithFaults applies the faults of ctrl to the application routes and
+// registers the faults control API under /api/faults.
+func WithFaults(ctrl *chaos.Controller) Option {
+	return func(c *config) {
+		c.controls.Faults = ctrl
+		c.appMiddlewares = append(c.appMiddlewares, chaos.Middleware(ctrl))
+		c.routes = append(c.routes, func(router *mux.Router) {
+			router.PathPrefix("/api/faults").Handler(http.StripPrefix("/api/faults", ctrl))
+		})
+	}
+}
+
+// WithTCPFaults registers the TCP proxy faults control API under /api/tcp/faults.
+func WithTCPFaults(controller handlers.TCPFaultsController) Option {
+	return func(c *config) {
+		c.controls.TCPFaults = controller
+		c.routes = append(c.routes, func(router *mux.Router) {
+			router.Path("/api/tcp/faults").Handler(handlers.NewTCPFaultsHandler(controller))
+		})
+	}
+}
+
+// WithCrash registers the crash control API under /api/crash.
+func WithCrash(controller handlers.CrashController) Option {
+	return func(c *config) {
+		c.controls.Crash = controller
+		c.routes = append(c.routes, func(router *mux.Router) {
+			router.Path("/api/crash").Handler(handlers.NewCrashHandler(controller))
+		})
+	}
+}
+
+// WithMemory registers the memory usage control API under /api/memory.
+func WithMemory(controller handlers.MemoryController) Option {
+	return func(c *config) {
+		c.controls.Memory = controller
+		c.routes = append(c.routes, func(router *mux.Router) {
+			router.Path("/api/memory").Handler(handlers.NewMemoryControlHandler(controller))
+		})
+	}
+}
+
+// WithPids registers the threads or processes control API under /api/pids.
+func WithPids(controller handlers.PidsController) Option {
+	return func(c *config) {
+		c.controls.Pids = controller
+		c.routes = append(c.routes, func(router *mux.Router) {
+			router.Path("/api/pids").Handler(handlers.NewPidsControlHandler(controller))
+		})
+	}
+}
+
+// WithTLS registers the TLS certificate control API under /api/tls.
+func WithTLS(controller handlers.TLSController) Option {
+	return func(c *config) {
+		c.controls.TLS = controller
+		c.routes = append(c.routes, func(router *mux.Router) {
+			router.Path("/api/tls").Handler(handlers.NewTLSHandler(controller))
+			router.Path("/api/tls/ca.pem").Handler(handlers.NewTLSCAHandler(controller))
+		})
+	}
+}
+
+// WithRota registers the crash rota API under /api/rota.
+func WithRota(controller handlers.RotaController) Option {
+	return func(c *config) {
+		c.controls.Rota = controller
+		c.routes = append(c.routes, func(router *mux.Router) {
+			router.Path(rota.Path).Handler(handlers.NewRotaHandler(controller))
+		})
+	}
+}
+
+// WithEvents registers the fault lifecycle events stream under /events.
+func WithEvents(subscriber handlers.EventSubscriber) Option {
+	return func(c *config) {
+		c.routes = append(c.routes, func(router *mux.Router) {
+			router.Path("/events").Handler(handlers.NewEventsHandler(subscriber))
+		})
+	}
+}
+
+// WithAuth requires the requests modifying the state through the control
+// API to be authenticated by a.
+func WithAuth(a middlewares.Authenticator) Option {
+	return func(c *config) {
+		c.auth = a
+	}
+}
+
+// WithRoutes only registers the given groups of routes, every group is
+// registered by default.
+func WithRoutes(r Routes) Option {
+	return func(c *config) {
+		c.served = r
+	}
+}
+
+// WithProbeFaults applies the faults to the health checks too, so that they
+// can make the probes fail.
+func WithProbeFaults() Option {
+	return func(c *config) {
+		c.probeFaults = true
+	}
+}
+
+// WithTracing starts a server span per request with tp. The faults applied
+// to a request are recorded as events of its span.
+func WithTracing(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		c.tracerProvider = tp
+	}
+}
+
+// WithAppHandler replaces the default handler serving the application routes.
+func WithAppHandler(h http.Handler) Option {
+	return func(c *config) {
+		c.appHandler = h
+	}
+}
+
 // NewRouter returns a new mux.Router.
-// It creates and register the metrics handler, the status handler and the default handler.
-func NewRouter(logger log.Logger) *mux.Router {
+// It creates and register the metrics handler, the status handlers, the optional handlers, the echo handler and the default handler.
+func NewRouter(logger log.Logger, opts ...Option) *mux.Router {
+	cfg := &config{
+		served: AllRoutes,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	if cfg.controls.Pod == nil {
+		hostname, _ := os.Hostname()
+		cfg.controls.Pod = &podinfo.Info{Hostname: hostname}
+	}
+
 	router := mux.NewRouter()
 	router.Use(middlewares.Logging(logger))
+	if cfg.tracerProvider != nil {
+		router.Use(middlewares.Tracing(cfg.tracerProvider))
+	}
+	router.Use(middlewares.Pod(cfg.controls.Pod.String()))
+
+	if cfg.served&MetricsRoutes != 0 {
+		router.Path("/metrics").Handler(promhttp.Handler())
+	}
+
+	if cfg.served&ProbeRoutes != 0 {
+		probes := router.PathPrefix("/checks/health").Subrouter()
+		if cfg.probeFaults {
+			probes.Use(cfg.appMiddlewares...)
+		}
+		probes.NewRoute().Handler(handlers.NewStatusHandler())
+	}
+
+	if cfg.served&AdminRoutes != 0 {
+		cfg.controls.Probes = cfg.served&ProbeRoutes != 0
+		router.Path("/api/status").Handler(handlers.NewControlStatusHandler(time.Now(), cfg.controls))
+
+		control := router.NewRoute().Subrouter()
+		if cfg.auth != nil {
+			control.Use(middlewares.Auth(logger, cfg.auth))
+		}
+		control.Use(middlewares.Trigger())
+		for _, route := range cfg.routes {
+			route(control)
+		}
+
+		// the dashboard is served without faults when the application
+		// routes are served on another port
+		if cfg.served&AppRoutes == 0 {
+			router.Path("/").Handler(handlers.NewDefaultHandler())
+		}
+	}
 
-	statusHandler := handlers.NewStatusHandler()
-	router.PathPrefix("/checks/health").Handler(statusHandler)
+	if cfg.served&AppRoutes != 0 {
+		app := router.PathPrefix("/").Subrouter()
+		app.Use(cfg.appMiddlewares...)
 
-	defaultHandler := handlers.NewDefaultHandler()
-	router.PathPrefix("/").Handler(defaultHandler)
+		appHandler := cfg.appHandler
+		if appHandler == nil {
+			// the echo handler is not registered in front of a replaced
+			// handler, which may serve /echo itself
+			app.Path("/echo").Handler(handlers.NewEchoHandler(cfg.controls.Pod))
+			appHandler = handlers.NewDefaultHandler()
+		}
+		app.PathPrefix("/").Handler(appHandler)
+	}
 
 	return router
 }
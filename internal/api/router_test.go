@@ -1,12 +1,32 @@
 package api
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
+	"github.com/pixelfactoryio/crashlooper/internal/api/middlewares"
+	"github.com/pixelfactoryio/crashlooper/internal/auth"
+	"github.com/pixelfactoryio/crashlooper/internal/cgroup"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+	"github.com/pixelfactoryio/crashlooper/internal/rota"
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/internal/services/tcpproxy"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
 )
 
 func TestNewRouter(t *testing.T) {
@@ -16,6 +36,19 @@ func TestNewRouter(t *testing.T) {
 	require.NotNil(t, router)
 }
 
+func TestNewRouter_MetricsEndpoint(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	router := NewRouter(logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "go_goroutines")
+}
+
 func TestNewRouter_HealthEndpoint(t *testing.T) {
 	logger := log.New(log.WithLevel("info"))
 	router := NewRouter(logger)
@@ -185,3 +218,422 @@ func TestNewRouter_MiddlewareApplied(t *testing.T) {
 	// Verify the request was successful
 	require.Equal(t, http.StatusOK, rec.Code)
 }
+
+type staticMemoryStats struct{}
+
+func (s *staticMemoryStats) Stats() *cgroup.MemoryStats {
+	return &cgroup.MemoryStats{Version: cgroup.V2, Current: 1024}
+}
+
+func TestNewRouter_WithMemoryStats(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	router := NewRouter(logger, WithMemoryStats(&staticMemoryStats{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/memory", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+	require.Contains(t, rec.Body.String(), `"version":"v2"`)
+}
+
+func TestNewRouter_WithoutMemoryStats(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	router := NewRouter(logger)
+
+	req := httptest.NewRequest(http.MethodGet, "/memory", nil)
+	rec := httptest.NewRecorder()
+
+	router.ServeHTTP(rec, req)
+
+	// Falls back to the default handler
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "<html>")
+}
+
+func TestNewRouter_WithFaults(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	ctrl := chaos.NewController()
+	router := NewRouter(logger, WithFaults(ctrl))
+
+	// Set an error fault through the control API
+	body := strings.NewReader(`{"probability": 1, "status_code": 503}`)
+	req := httptest.NewRequest(http.MethodPut, "/api/faults/error", body)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	// Application routes are affected
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	// Health checks and the control API are not
+	req = httptest.NewRequest(http.MethodGet, "/checks/health", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/faults", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"kind":"error"`)
+}
+
+func TestNewRouter_WithAppHandler(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	ctrl := chaos.NewController()
+	require.NoError(t, ctrl.Set(chaos.Fault{Kind: chaos.KindError, Probability: 1, StatusCode: 502}))
+
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	router := NewRouter(logger, WithAppHandler(app))
+
+	req := httptest.NewRequest(http.MethodGet, "/anything", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusAccepted, rec.Code)
+
+	// Faults apply to the replaced handler
+	router = NewRouter(logger, WithAppHandler(app), WithFaults(ctrl))
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusBadGateway, rec.Code)
+}
+
+type staticTCPFaults struct{}
+
+func (s *staticTCPFaults) Faults() tcpproxy.Faults {
+	return tcpproxy.Faults{Blackhole: true}
+}
+
+func (s *staticTCPFaults) SetFaults(context.Context, tcpproxy.Faults) error {
+	return nil
+}
+
+func TestNewRouter_WithTCPFaults(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	router := NewRouter(logger, WithTCPFaults(&staticTCPFaults{}))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/tcp/faults", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"blackhole":true`)
+}
+
+func TestNewRouter_WithCrash(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	router := NewRouter(logger, WithCrash(crash.New(logger, 0)))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/crash", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.JSONEq(t, `{"scheduled": false}`, rec.Body.String())
+}
+
+func TestNewRouter_WithMemory(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	router := NewRouter(logger, WithMemory(memory.New(logger, 0, 0, 0)))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/memory", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"allocated":"0B"`)
+}
+
+func TestNewRouter_WithPids(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	router := NewRouter(logger, WithPids(pids.New(logger, pids.Settings{})))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/pids", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"mode":"threads"`)
+	require.Contains(t, rec.Body.String(), `"spawned":0`)
+}
+
+func TestNewRouter_WithAuth(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	ctrl := chaos.NewController()
+	router := NewRouter(logger, WithFaults(ctrl), WithAuth(auth.New(auth.WithTokens("secret"))))
+
+	send := func(method, path, token string) int {
+		req := httptest.NewRequest(method, path, strings.NewReader(`{"probability": 1, "status_code": 503}`))
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	// Mutations require a valid token
+	require.Equal(t, http.StatusUnauthorized, send(http.MethodPut, "/api/faults/error", ""))
+	require.Equal(t, http.StatusUnauthorized, send(http.MethodPut, "/api/faults/error", "wrong"))
+	require.Empty(t, ctrl.Faults())
+	require.Equal(t, http.StatusOK, send(http.MethodPut, "/api/faults/error", "secret"))
+
+	// Probes, read-only status and the application routes are open
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/checks/health", ""))
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/status", ""))
+	require.Equal(t, http.StatusOK, send(http.MethodGet, "/api/faults", ""))
+	require.Equal(t, http.StatusServiceUnavailable, send(http.MethodPost, "/", ""))
+}
+
+func TestNewRouter_WithTLS(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	manager, err := certs.New(logger, []string{"localhost"})
+	require.NoError(t, err)
+	router := NewRouter(logger, WithTLS(manager))
+
+	req := httptest.NewRequest(http.MethodPut, "/api/tls", strings.NewReader(`{"mode": "self-signed"}`))
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"mode":"self-signed"`)
+
+	req = httptest.NewRequest(http.MethodGet, "/api/tls/ca.pem", nil)
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), "BEGIN CERTIFICATE")
+}
+
+func TestNewRouter_WithRota(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	r, err := rota.New(logger, "crashlooper-7d9f", time.Minute, rota.Static(), crash.New(logger, 0))
+	require.NoError(t, err)
+	router := NewRouter(logger, WithRota(r))
+
+	req := httptest.NewRequest(http.MethodGet, "/api/rota", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"members":["crashlooper-7d9f"]`)
+}
+
+func TestNewRouter_ControlStatus(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	faults := chaos.NewController()
+	require.NoError(t, faults.Set(chaos.Fault{Kind: chaos.KindLatency, Probability: 1, Delay: chaos.Duration(time.Millisecond)}))
+
+	router := NewRouter(
+		logger,
+		WithCrash(crash.New(logger, 0)),
+		WithMemory(memory.New(logger, 0, 0, 0)),
+		WithFaults(faults),
+		WithTCPFaults(&staticTCPFaults{}),
+	)
+
+	req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	var status handlers.ControlStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	require.NotNil(t, status.Crash)
+	require.NotNil(t, status.Memory)
+	require.Len(t, status.Faults, 1)
+	require.NotNil(t, status.TCPFaults)
+	require.True(t, status.TCPFaults.Blackhole)
+}
+
+func TestNewRouter_ControlStatus_Probes(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+
+	for _, tt := range []struct {
+		routes Routes
+		probes bool
+	}{
+		{AllRoutes, true},
+		// the probes are served on another port
+		{AdminRoutes, false},
+	} {
+		router := NewRouter(logger, WithRoutes(tt.routes))
+		req := httptest.NewRequest(http.MethodGet, "/api/status", nil)
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, req)
+
+		var status handlers.ControlStatus
+		require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+		require.Equal(t, tt.probes, status.Probes)
+	}
+}
+
+func TestNewRouter_WithEvents(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	bus := events.NewBus()
+	bus.Publish(events.Event{Type: events.TypeScheduled, Source: "crash"})
+
+	srv := httptest.NewServer(NewRouter(logger, WithEvents(bus)))
+	defer srv.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+	require.NoError(t, err)
+
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	require.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// events are flushed through the logging middleware
+	line, err := bufio.NewReader(resp.Body).ReadString('\n')
+	require.NoError(t, err)
+	require.Equal(t, "id: 1\n", line)
+}
+
+func TestNewRouter_WithRoutes(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	ctrl := chaos.NewController()
+	require.NoError(t, ctrl.Set(chaos.Fault{Kind: chaos.KindError, Probability: 1, StatusCode: 503}))
+
+	get := func(router http.Handler, path string) int {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec.Code
+	}
+
+	tests := []struct {
+		name     string
+		routes   Routes
+		expected map[string]int
+	}{
+		{
+			name:   "application",
+			routes: AppRoutes,
+			expected: map[string]int{
+				"/":              http.StatusServiceUnavailable,
+				"/checks/health": http.StatusServiceUnavailable,
+				"/metrics":       http.StatusServiceUnavailable,
+				"/api/faults":    http.StatusServiceUnavailable,
+			},
+		},
+		{
+			name:   "probes",
+			routes: ProbeRoutes,
+			expected: map[string]int{
+				"/":              http.StatusNotFound,
+				"/checks/health": http.StatusOK,
+				"/metrics":       http.StatusNotFound,
+				"/api/status":    http.StatusNotFound,
+			},
+		},
+		{
+			name:   "metrics",
+			routes: MetricsRoutes,
+			expected: map[string]int{
+				"/checks/health": http.StatusNotFound,
+				"/metrics":       http.StatusOK,
+			},
+		},
+		{
+			name:   "admin",
+			routes: AdminRoutes,
+			expected: map[string]int{
+				"/":              http.StatusOK,
+				"/anything":      http.StatusNotFound,
+				"/checks/health": http.StatusNotFound,
+				"/api/status":    http.StatusOK,
+				"/api/faults":    http.StatusOK,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			router := NewRouter(logger, WithFaults(ctrl), WithRoutes(tt.routes))
+			for path, code := range tt.expected {
+				require.Equal(t, code, get(router, path), path)
+			}
+		})
+	}
+}
+
+func TestNewRouter_WithProbeFaults(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	ctrl := chaos.NewController()
+	require.NoError(t, ctrl.Set(chaos.Fault{Kind: chaos.KindError, Probability: 1, StatusCode: 503, PathPrefix: "/checks/health"}))
+
+	router := NewRouter(logger, WithFaults(ctrl), WithProbeFaults())
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checks/health", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/faults", nil))
+	require.Equal(t, http.StatusOK, rec.Code)
+}
+
+func TestNewRouter_EchoEndpoint(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	ctrl := chaos.NewController()
+	router := NewRouter(logger, WithFaults(ctrl))
+
+	req := httptest.NewRequest(http.MethodGet, "/echo?status=201", nil)
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, req)
+	require.Equal(t, http.StatusCreated, rec.Code)
+
+	var e handlers.Echo
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&e))
+	require.Equal(t, "/echo?status=201", e.URL)
+
+	// the echo handler is an application route
+	require.NoError(t, ctrl.Set(chaos.Fault{Kind: chaos.KindError, Probability: 1, StatusCode: 503}))
+	rec = httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/echo", nil))
+	require.Equal(t, http.StatusServiceUnavailable, rec.Code)
+
+	// a replaced application handler serves /echo
+	app := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+	})
+	rec = httptest.NewRecorder()
+	NewRouter(logger, WithAppHandler(app)).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/echo", nil))
+	require.Equal(t, http.StatusAccepted, rec.Code)
+}
+
+func TestNewRouter_WithPod(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	pod := &podinfo.Info{Name: "crashlooper-7d9f", Namespace: "crashlooper", Node: "node-1"}
+	router := NewRouter(logger, WithPod(pod))
+
+	for _, path := range []string{"/", "/checks/health", "/metrics", "/api/status"} {
+		rec := httptest.NewRecorder()
+		router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		require.Equal(t, "crashlooper/crashlooper-7d9f", rec.Header().Get(middlewares.HeaderPod), path)
+	}
+
+	rec := httptest.NewRecorder()
+	router.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/status", nil))
+	var status handlers.ControlStatus
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&status))
+	require.Equal(t, pod, status.Pod)
+
+	// the hostname identifies the pod by default
+	rec = httptest.NewRecorder()
+	NewRouter(logger).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/checks/health", nil))
+	hostname, err := os.Hostname()
+	require.NoError(t, err)
+	require.Equal(t, hostname, rec.Header().Get(middlewares.HeaderPod))
+}
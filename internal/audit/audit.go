@@ -0,0 +1,173 @@
+// Package audit writes the fault lifecycle events as a dedicated log, one
+// JSON record per line with a stable schema, so that log pipelines can index
+// the chaos events apart from the request logs.
+package audit
+
+import (
+	"encoding/json"
+	"io"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+// Format is the schema of the records.
+type Format string
+
+const (
+	// FormatJSON writes Record objects.
+	FormatJSON Format = "json"
+	// FormatECS writes Elastic Common Schema documents, the fault being
+	// described under the crashlooper.fault field set.
+	FormatECS Format = "ecs"
+)
+
+// ecsVersion is the version of the Elastic Common Schema the records follow.
+const ecsVersion = "8.11.0"
+
+// Record is an audit record in FormatJSON. Type is the type of the fault:
+// crash, memory, pids, tls, tcp for the faults of the TCP proxy or faults,
+// the latter covering the faults of the chaos controller whose kind is in the
+// parameters. Phase is the lifecycle stage of the fault, and the records of a
+// fault share the same FaultID. Trigger is flag, api, scenario or rota, the
+// crashes of the rota being crash records.
+type Record struct {
+	Time       time.Time              `json:"time"`
+	EventID    uint64                 `json:"event_id"`
+	FaultID    string                 `json:"fault_id"`
+	Type       string                 `json:"type"`
+	Phase      events.Type            `json:"phase"`
+	Trigger    events.Trigger         `json:"trigger"`
+	Parameters map[string]interface{} `json:"parameters,omitempty"`
+	Message    string                 `json:"message,omitempty"`
+	Pod        *podinfo.Info          `json:"pod,omitempty"`
+}
+
+// Logger writes the audit records of the events.
+type Logger struct {
+	logger *log.DefaultLogger
+	format Format
+	pod    *podinfo.Info
+
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Option configures a Logger.
+type Option func(*Logger)
+
+// WithPod adds the identity of the pod to the records.
+func WithPod(pod *podinfo.Info) Option {
+	return func(l *Logger) {
+		l.pod = pod
+	}
+}
+
+// New returns a Logger writing the records to w in format.
+func New(logger *log.DefaultLogger, w io.Writer, format Format, opts ...Option) (*Logger, error) {
+	if format != FormatJSON && format != FormatECS {
+		return nil, errors.Errorf("unknown audit log format %q, must be json or ecs", format)
+	}
+
+	logger.Info("Creating audit log", fields.String("format", string(format)))
+	l := &Logger{
+		logger: logger,
+		format: format,
+		w:      w,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l, nil
+}
+
+// Log writes the record of e. It is meant to be used as an events.WithHook
+// function, so that the record of a crash is written before the process exits.
+func (l *Logger) Log(e events.Event) {
+	var v interface{}
+	if l.format == FormatECS {
+		v = l.ecs(e)
+	} else {
+		v = l.record(e)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		l.logger.Error("Unable to encode audit record", fields.Error(err))
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, err := l.w.Write(append(b, '\n')); err != nil {
+		l.logger.Error("Unable to write audit record", fields.Error(err))
+	}
+}
+
+func (l *Logger) record(e events.Event) Record {
+	return Record{
+		Time:       e.Time.UTC(),
+		EventID:    e.ID,
+		FaultID:    e.FaultID,
+		Type:       e.Source,
+		Phase:      e.Type,
+		Trigger:    e.Trigger,
+		Parameters: e.Data,
+		Message:    e.Message,
+		Pod:        l.pod,
+	}
+}
+
+// ecs returns the Elastic Common Schema document of e.
+func (l *Logger) ecs(e events.Event) map[string]interface{} {
+	fault := map[string]interface{}{
+		"id":      e.FaultID,
+		"type":    e.Source,
+		"phase":   e.Type,
+		"trigger": e.Trigger,
+	}
+	if len(e.Data) > 0 {
+		fault["parameters"] = e.Data
+	}
+
+	doc := map[string]interface{}{
+		"@timestamp": e.Time.UTC().Format(time.RFC3339Nano),
+		"ecs":        map[string]interface{}{"version": ecsVersion},
+		"event": map[string]interface{}{
+			"kind":     "event",
+			"category": []string{"configuration"},
+			"type":     []string{"change"},
+			"action":   e.Source + "-" + string(e.Type),
+			"id":       strconv.FormatUint(e.ID, 10),
+			"dataset":  "crashlooper.audit",
+			"provider": "crashlooper",
+		},
+		"service":     map[string]interface{}{"name": "crashlooper"},
+		"crashlooper": map[string]interface{}{"fault": fault},
+	}
+	if e.Message != "" {
+		doc["message"] = e.Message
+	}
+
+	if pod := l.pod; pod != nil {
+		if pod.Hostname != "" {
+			doc["host"] = map[string]interface{}{"hostname": pod.Hostname}
+		}
+		if pod.Name != "" {
+			doc["orchestrator"] = map[string]interface{}{
+				"type":      "kubernetes",
+				"namespace": pod.Namespace,
+				"resource":  map[string]interface{}{"type": "pod", "name": pod.Name},
+			}
+		}
+	}
+
+	return doc
+}
@@ -0,0 +1,114 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+var testEvent = events.Event{
+	ID:      7,
+	Time:    time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC),
+	Type:    events.TypeScheduled,
+	Source:  "crash",
+	FaultID: "5f1d3c2b4a697887",
+	Trigger: events.TriggerScenario,
+	Message: "Crash scheduled",
+	Data:    map[string]interface{}{"exit_code": 3},
+}
+
+func TestNew_InvalidFormat(t *testing.T) {
+	_, err := New(log.New(log.WithLevel("info")), &bytes.Buffer{}, "xml")
+	require.Error(t, err)
+}
+
+func TestLogger_Log_JSON(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(log.New(log.WithLevel("info")), &buf, FormatJSON,
+		WithPod(&podinfo.Info{Name: "crashlooper-7d9f", Namespace: "crashlooper"}))
+	require.NoError(t, err)
+
+	l.Log(testEvent)
+	l.Log(events.Event{ID: 8, Type: events.TypeCancelled, Source: "crash", FaultID: testEvent.FaultID, Trigger: events.TriggerAPI})
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	require.Len(t, lines, 2)
+	require.JSONEq(t, `{
+		"time": "2022-01-01T00:00:00Z",
+		"event_id": 7,
+		"fault_id": "5f1d3c2b4a697887",
+		"type": "crash",
+		"phase": "scheduled",
+		"trigger": "scenario",
+		"parameters": {"exit_code": 3},
+		"message": "Crash scheduled",
+		"pod": {"name": "crashlooper-7d9f", "namespace": "crashlooper"}
+	}`, lines[0])
+
+	var record Record
+	require.NoError(t, json.Unmarshal([]byte(lines[1]), &record))
+	require.Equal(t, events.TypeCancelled, record.Phase)
+	require.Equal(t, testEvent.FaultID, record.FaultID)
+}
+
+func TestLogger_Log_ECS(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(log.New(log.WithLevel("info")), &buf, FormatECS,
+		WithPod(&podinfo.Info{Name: "crashlooper-7d9f", Namespace: "crashlooper", Hostname: "crashlooper-7d9f"}))
+	require.NoError(t, err)
+
+	l.Log(testEvent)
+
+	require.JSONEq(t, `{
+		"@timestamp": "2022-01-01T00:00:00Z",
+		"ecs": {"version": "8.11.0"},
+		"message": "Crash scheduled",
+		"event": {
+			"kind": "event",
+			"category": ["configuration"],
+			"type": ["change"],
+			"action": "crash-scheduled",
+			"id": "7",
+			"dataset": "crashlooper.audit",
+			"provider": "crashlooper"
+		},
+		"service": {"name": "crashlooper"},
+		"host": {"hostname": "crashlooper-7d9f"},
+		"orchestrator": {
+			"type": "kubernetes",
+			"namespace": "crashlooper",
+			"resource": {"type": "pod", "name": "crashlooper-7d9f"}
+		},
+		"crashlooper": {"fault": {
+			"id": "5f1d3c2b4a697887",
+			"type": "crash",
+			"phase": "scheduled",
+			"trigger": "scenario",
+			"parameters": {"exit_code": 3}
+		}}
+	}`, buf.String())
+}
+
+func TestLogger_BusHook(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(log.New(log.WithLevel("info")), &buf, FormatJSON)
+	require.NoError(t, err)
+
+	bus := events.NewBus(events.WithHook(l.Log))
+	bus.Publish(events.Event{Type: events.TypeTriggered, Source: "crash", FaultID: "f1", Trigger: events.TriggerFlag})
+
+	// the record is written by the time Publish returns
+	var record Record
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+	require.Equal(t, uint64(1), record.EventID)
+	require.Equal(t, events.TriggerFlag, record.Trigger)
+	require.False(t, record.Time.IsZero())
+}
@@ -0,0 +1,160 @@
+package auth
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/subtle"
+	"crypto/x509"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// ErrMissingCredentials is returned when a request carries neither a bearer
+// token nor a client certificate.
+var ErrMissingCredentials = errors.New("missing credentials")
+
+// Authenticator authenticates requests with a bearer token or a client
+// certificate signed by a trusted certificate authority.
+type Authenticator struct {
+	tokens    []string
+	tokenFile string
+	clientCAs *x509.CertPool
+}
+
+// Option configures an Authenticator.
+type Option func(*Authenticator)
+
+// WithTokens accepts the given bearer tokens, empty ones are ignored.
+func WithTokens(tokens ...string) Option {
+	return func(a *Authenticator) {
+		for _, t := range tokens {
+			if t != "" {
+				a.tokens = append(a.tokens, t)
+			}
+		}
+	}
+}
+
+// WithTokenFile accepts the bearer tokens listed in path, one per line.
+// Blank lines and lines starting with # are ignored. The file is read on
+// each authentication so that rotated secrets apply without a restart.
+func WithTokenFile(path string) Option {
+	return func(a *Authenticator) {
+		a.tokenFile = path
+	}
+}
+
+// WithClientCAs accepts the client certificates signed by pool.
+func WithClientCAs(pool *x509.CertPool) Option {
+	return func(a *Authenticator) {
+		a.clientCAs = pool
+	}
+}
+
+// New returns an Authenticator configured by opts.
+func New(opts ...Option) *Authenticator {
+	a := &Authenticator{}
+	for _, opt := range opts {
+		opt(a)
+	}
+	return a
+}
+
+// Enabled reports whether any credentials are configured.
+func (a *Authenticator) Enabled() bool {
+	return len(a.tokens) > 0 || a.tokenFile != "" || a.clientCAs != nil
+}
+
+// ClientCAs returns the pool verifying the client certificates, if any.
+func (a *Authenticator) ClientCAs() *x509.CertPool {
+	return a.clientCAs
+}
+
+// Authenticate returns an error if r carries no valid credentials.
+func (a *Authenticator) Authenticate(r *http.Request) error {
+	if a.clientCAs != nil && r.TLS != nil && len(r.TLS.PeerCertificates) > 0 {
+		return a.verifyCertificate(r)
+	}
+
+	token, ok := bearerToken(r)
+	if !ok {
+		return ErrMissingCredentials
+	}
+
+	tokens, err := a.acceptedTokens()
+	if err != nil {
+		return err
+	}
+
+	valid := 0
+	for _, t := range tokens {
+		valid |= subtle.ConstantTimeCompare([]byte(token), []byte(t))
+	}
+	if valid != 1 {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+func (a *Authenticator) verifyCertificate(r *http.Request) error {
+	certs := r.TLS.PeerCertificates
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	_, err := certs[0].Verify(x509.VerifyOptions{
+		Roots:         a.clientCAs,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return errors.Wrap(err, "invalid client certificate")
+}
+
+func (a *Authenticator) acceptedTokens() ([]string, error) {
+	if a.tokenFile == "" {
+		return a.tokens, nil
+	}
+
+	b, err := os.ReadFile(a.tokenFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read token file")
+	}
+
+	tokens := append([]string{}, a.tokens...)
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		tokens = append(tokens, line)
+	}
+	return tokens, scanner.Err()
+}
+
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// LoadCertPool returns a pool of the PEM encoded certificates in path.
+func LoadCertPool(path string) (*x509.CertPool, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read certificates")
+	}
+
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(b) {
+		return nil, errors.Errorf("no certificate found in %s", path)
+	}
+	return pool, nil
+}
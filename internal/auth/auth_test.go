@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func newCA(t *testing.T) (*x509.Certificate, *ecdsa.PrivateKey) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert, key
+}
+
+func newClientCert(t *testing.T, ca *x509.Certificate, caKey *ecdsa.PrivateKey) *x509.Certificate {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(2),
+		Subject:      pkix.Name{CommonName: "client"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca, &key.PublicKey, caKey)
+	require.NoError(t, err)
+
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return cert
+}
+
+func newRequest(token string) *http.Request {
+	r := httptest.NewRequest(http.MethodPut, "/api/crash", nil)
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestAuthenticator_Enabled(t *testing.T) {
+	require.False(t, New().Enabled())
+	require.False(t, New(WithTokens("")).Enabled())
+	require.True(t, New(WithTokens("secret")).Enabled())
+	require.True(t, New(WithTokenFile("tokens")).Enabled())
+	require.True(t, New(WithClientCAs(x509.NewCertPool())).Enabled())
+}
+
+func TestAuthenticator_Token(t *testing.T) {
+	a := New(WithTokens("secret", "other"))
+
+	require.NoError(t, a.Authenticate(newRequest("secret")))
+	require.NoError(t, a.Authenticate(newRequest("other")))
+	require.EqualError(t, a.Authenticate(newRequest("wrong")), "invalid token")
+	require.ErrorIs(t, a.Authenticate(newRequest("")), ErrMissingCredentials)
+
+	r := newRequest("")
+	r.Header.Set("Authorization", "Basic c2VjcmV0")
+	require.ErrorIs(t, a.Authenticate(r), ErrMissingCredentials)
+
+	r = newRequest("")
+	r.Header.Set("Authorization", "bearer secret")
+	require.NoError(t, a.Authenticate(r))
+}
+
+func TestAuthenticator_TokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tokens")
+	require.NoError(t, os.WriteFile(path, []byte("# rotated weekly\nfirst\n\n  second  \n"), 0o600))
+
+	a := New(WithTokenFile(path))
+	require.NoError(t, a.Authenticate(newRequest("first")))
+	require.NoError(t, a.Authenticate(newRequest("second")))
+	require.Error(t, a.Authenticate(newRequest("# rotated weekly")))
+
+	// the file is read again, rotated tokens apply immediately
+	require.NoError(t, os.WriteFile(path, []byte("third\n"), 0o600))
+	require.Error(t, a.Authenticate(newRequest("first")))
+	require.NoError(t, a.Authenticate(newRequest("third")))
+
+	require.NoError(t, os.Remove(path))
+	require.ErrorContains(t, a.Authenticate(newRequest("third")), "unable to read token file")
+}
+
+func TestAuthenticator_ClientCertificate(t *testing.T) {
+	ca, caKey := newCA(t)
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+
+	a := New(WithClientCAs(pool))
+
+	r := newRequest("")
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{newClientCert(t, ca, caKey)}}
+	require.NoError(t, a.Authenticate(r))
+
+	other, otherKey := newCA(t)
+	r.TLS = &tls.ConnectionState{PeerCertificates: []*x509.Certificate{newClientCert(t, other, otherKey)}}
+	require.ErrorContains(t, a.Authenticate(r), "invalid client certificate")
+
+	// without a certificate the request falls back to the tokens
+	require.ErrorIs(t, a.Authenticate(newRequest("")), ErrMissingCredentials)
+}
+
+func TestLoadCertPool(t *testing.T) {
+	ca, _ := newCA(t)
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "ca.pem")
+	require.NoError(t, os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.Raw}), 0o600))
+
+	pool, err := LoadCertPool(path)
+	require.NoError(t, err)
+	require.NotNil(t, pool)
+
+	empty := filepath.Join(dir, "empty.pem")
+	require.NoError(t, os.WriteFile(empty, []byte("nothing"), 0o600))
+	_, err = LoadCertPool(empty)
+	require.ErrorContains(t, err, "no certificate found")
+
+	_, err = LoadCertPool(filepath.Join(dir, "missing.pem"))
+	require.Error(t, err)
+}
@@ -0,0 +1,301 @@
+// Package cgroup reads the memory and pids controller files exposed by
+// cgroup v1 and cgroup v2 hierarchies.
+package cgroup
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultRoot is the path where the cgroup filesystem is usually mounted.
+const DefaultRoot = "/sys/fs/cgroup"
+
+// Version identifies the cgroup hierarchy in use.
+type Version string
+
+const (
+	// V1 is the legacy cgroup hierarchy, one mount per controller.
+	V1 Version = "v1"
+	// V2 is the unified cgroup hierarchy.
+	V2 Version = "v2"
+)
+
+// ErrNotFound is returned when no memory controller can be found under root.
+var ErrNotFound = errors.New("no cgroup memory controller found")
+
+// ErrPidsNotFound is returned when no pids controller can be found under root.
+var ErrPidsNotFound = errors.New("no cgroup pids controller found")
+
+// MemoryEvents holds the memory event counters of a cgroup.
+// Low, High, Max, OOM and OOMKill map to the cgroup v2 memory.events keys.
+// On cgroup v1, Max is the memory.failcnt and OOMKill the oom_kill counter
+// from memory.oom_control; Low, High and OOM are always zero.
+type MemoryEvents struct {
+	Low     uint64 `json:"low"`
+	High    uint64 `json:"high"`
+	Max     uint64 `json:"max"`
+	OOM     uint64 `json:"oom"`
+	OOMKill uint64 `json:"oom_kill"`
+}
+
+// Pressure is one line of a PSI file.
+type Pressure struct {
+	Avg10  float64 `json:"avg10"`
+	Avg60  float64 `json:"avg60"`
+	Avg300 float64 `json:"avg300"`
+	Total  uint64  `json:"total"`
+}
+
+// PSI holds the memory pressure stall information of a cgroup.
+type PSI struct {
+	Some Pressure `json:"some"`
+	Full Pressure `json:"full"`
+}
+
+// MemoryStats is a snapshot of the memory controller of a cgroup.
+// A Limit of zero means the cgroup has no memory limit.
+type MemoryStats struct {
+	Version  Version      `json:"version"`
+	Current  uint64       `json:"current"`
+	Limit    uint64       `json:"limit"`
+	UnderOOM bool         `json:"under_oom"`
+	Events   MemoryEvents `json:"events"`
+	Pressure *PSI         `json:"pressure,omitempty"`
+}
+
+// PidsStats is a snapshot of the pids controller of a cgroup. A Limit of zero
+// means the cgroup has no pids limit. Denied is the number of forks denied by
+// the limit, the max counter of pids.events.
+type PidsStats struct {
+	Version Version `json:"version"`
+	Current uint64  `json:"current"`
+	Limit   uint64  `json:"limit"`
+	Denied  uint64  `json:"denied"`
+}
+
+// Detect returns the cgroup version mounted at root.
+func Detect(root string) (Version, error) {
+	if exists(filepath.Join(root, "memory.events")) {
+		return V2, nil
+	}
+	if exists(filepath.Join(root, "memory", "memory.oom_control")) {
+		return V1, nil
+	}
+	return "", ErrNotFound
+}
+
+// ReadMemory reads the memory controller files of the cgroup mounted at root.
+func ReadMemory(root string) (*MemoryStats, error) {
+	v, err := Detect(root)
+	if err != nil {
+		return nil, err
+	}
+
+	if v == V1 {
+		return readMemoryV1(filepath.Join(root, "memory"))
+	}
+	return readMemoryV2(root)
+}
+
+func readMemoryV2(dir string) (*MemoryStats, error) {
+	stats := &MemoryStats{Version: V2}
+
+	var err error
+	if stats.Current, err = readUint(filepath.Join(dir, "memory.current")); err != nil {
+		return nil, err
+	}
+	if stats.Limit, err = readUint(filepath.Join(dir, "memory.max")); err != nil {
+		return nil, err
+	}
+
+	events, err := readKeyValues(filepath.Join(dir, "memory.events"))
+	if err != nil {
+		return nil, err
+	}
+	stats.Events = MemoryEvents{
+		Low:     events["low"],
+		High:    events["high"],
+		Max:     events["max"],
+		OOM:     events["oom"],
+		OOMKill: events["oom_kill"],
+	}
+
+	// memory.pressure is missing when the kernel is built without PSI
+	path := filepath.Join(dir, "memory.pressure")
+	if exists(path) {
+		if stats.Pressure, err = readPressure(path); err != nil {
+			return nil, err
+		}
+	}
+
+	return stats, nil
+}
+
+func readMemoryV1(dir string) (*MemoryStats, error) {
+	stats := &MemoryStats{Version: V1}
+
+	var err error
+	if stats.Current, err = readUint(filepath.Join(dir, "memory.usage_in_bytes")); err != nil {
+		return nil, err
+	}
+	if stats.Limit, err = readUint(filepath.Join(dir, "memory.limit_in_bytes")); err != nil {
+		return nil, err
+	}
+	if stats.Events.Max, err = readUint(filepath.Join(dir, "memory.failcnt")); err != nil {
+		return nil, err
+	}
+
+	oomControl, err := readKeyValues(filepath.Join(dir, "memory.oom_control"))
+	if err != nil {
+		return nil, err
+	}
+	stats.UnderOOM = oomControl["under_oom"] == 1
+	stats.Events.OOMKill = oomControl["oom_kill"]
+
+	// cgroup v1 reports an unlimited cgroup as a huge page aligned value
+	if stats.Limit >= 1<<62 {
+		stats.Limit = 0
+	}
+
+	return stats, nil
+}
+
+// ReadPids reads the pids controller files of the cgroup mounted at root.
+func ReadPids(root string) (*PidsStats, error) {
+	stats := &PidsStats{Version: V2}
+	dir := root
+	if !exists(filepath.Join(dir, "pids.max")) {
+		stats.Version = V1
+		dir = filepath.Join(root, "pids")
+		if !exists(filepath.Join(dir, "pids.max")) {
+			return nil, ErrPidsNotFound
+		}
+	}
+
+	var err error
+	if stats.Current, err = readUint(filepath.Join(dir, "pids.current")); err != nil {
+		return nil, err
+	}
+	if stats.Limit, err = readUint(filepath.Join(dir, "pids.max")); err != nil {
+		return nil, err
+	}
+
+	// pids.events is missing on older kernels
+	path := filepath.Join(dir, "pids.events")
+	if exists(path) {
+		events, err := readKeyValues(path)
+		if err != nil {
+			return nil, err
+		}
+		stats.Denied = events["max"]
+	}
+
+	return stats, nil
+}
+
+// readUint reads a file holding a single integer, "max" is read as zero.
+func readUint(path string) (uint64, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to read %s", path)
+	}
+
+	s := strings.TrimSpace(string(b))
+	if s == "max" {
+		return 0, nil
+	}
+
+	v, err := strconv.ParseUint(s, 10, 64)
+	if err != nil {
+		return 0, errors.Wrapf(err, "unable to parse %s", path)
+	}
+	return v, nil
+}
+
+// readKeyValues reads a flat keyed file made of "key value" lines.
+func readKeyValues(path string) (map[string]uint64, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %s", path)
+	}
+	defer f.Close()
+
+	values := make(map[string]uint64)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) != 2 {
+			continue
+		}
+		v, err := strconv.ParseUint(parts[1], 10, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "unable to parse %s", path)
+		}
+		values[parts[0]] = v
+	}
+
+	return values, scanner.Err()
+}
+
+// readPressure reads a PSI file made of "some" and "full" lines such as
+// "some avg10=0.00 avg60=0.00 avg300=0.00 total=0".
+func readPressure(path string) (*PSI, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to read %s", path)
+	}
+	defer f.Close()
+
+	psi := &PSI{}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		parts := strings.Fields(scanner.Text())
+		if len(parts) == 0 {
+			continue
+		}
+
+		var p *Pressure
+		switch parts[0] {
+		case "some":
+			p = &psi.Some
+		case "full":
+			p = &psi.Full
+		default:
+			continue
+		}
+
+		for _, kv := range parts[1:] {
+			k, v, ok := strings.Cut(kv, "=")
+			if !ok {
+				continue
+			}
+
+			var err error
+			switch k {
+			case "avg10":
+				p.Avg10, err = strconv.ParseFloat(v, 64)
+			case "avg60":
+				p.Avg60, err = strconv.ParseFloat(v, 64)
+			case "avg300":
+				p.Avg300, err = strconv.ParseFloat(v, 64)
+			case "total":
+				p.Total, err = strconv.ParseUint(v, 10, 64)
+			}
+			if err != nil {
+				return nil, errors.Wrapf(err, "unable to parse %s", path)
+			}
+		}
+	}
+
+	return psi, scanner.Err()
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
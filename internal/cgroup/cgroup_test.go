@@ -0,0 +1,178 @@
+package cgroup
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o644))
+	}
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected Version
+		err      error
+	}{
+		{
+			name:     "cgroup v2",
+			files:    map[string]string{"memory.events": ""},
+			expected: V2,
+		},
+		{
+			name:     "cgroup v1",
+			files:    map[string]string{"memory/memory.oom_control": ""},
+			expected: V1,
+		},
+		{
+			name:  "no memory controller",
+			files: map[string]string{},
+			err:   ErrNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+
+			v, err := Detect(root)
+			require.Equal(t, tt.err, err)
+			require.Equal(t, tt.expected, v)
+		})
+	}
+}
+
+func TestReadMemory_V2(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"memory.current": "1048576\n",
+		"memory.max":     "31457280\n",
+		"memory.events":  "low 0\nhigh 3\nmax 12\noom 1\noom_kill 1\noom_group_kill 0\n",
+		"memory.pressure": "some avg10=1.50 avg60=0.25 avg300=0.05 total=123456\n" +
+			"full avg10=0.50 avg60=0.10 avg300=0.00 total=6543\n",
+	})
+
+	stats, err := ReadMemory(root)
+	require.NoError(t, err)
+
+	require.Equal(t, V2, stats.Version)
+	require.Equal(t, uint64(1048576), stats.Current)
+	require.Equal(t, uint64(31457280), stats.Limit)
+	require.Equal(t, MemoryEvents{High: 3, Max: 12, OOM: 1, OOMKill: 1}, stats.Events)
+	require.NotNil(t, stats.Pressure)
+	require.Equal(t, Pressure{Avg10: 1.5, Avg60: 0.25, Avg300: 0.05, Total: 123456}, stats.Pressure.Some)
+	require.Equal(t, Pressure{Avg10: 0.5, Avg60: 0.1, Total: 6543}, stats.Pressure.Full)
+}
+
+func TestReadMemory_V2_Unlimited(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"memory.current": "4096",
+		"memory.max":     "max",
+		"memory.events":  "low 0\nhigh 0\nmax 0\noom 0\noom_kill 0\n",
+	})
+
+	stats, err := ReadMemory(root)
+	require.NoError(t, err)
+
+	require.Equal(t, uint64(0), stats.Limit)
+	require.Nil(t, stats.Pressure)
+}
+
+func TestReadMemory_V1(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"memory/memory.usage_in_bytes": "2097152\n",
+		"memory/memory.limit_in_bytes": "9223372036854771712\n",
+		"memory/memory.failcnt":        "7\n",
+		"memory/memory.oom_control":    "oom_kill_disable 0\nunder_oom 1\noom_kill 2\n",
+	})
+
+	stats, err := ReadMemory(root)
+	require.NoError(t, err)
+
+	require.Equal(t, V1, stats.Version)
+	require.Equal(t, uint64(2097152), stats.Current)
+	require.Equal(t, uint64(0), stats.Limit)
+	require.True(t, stats.UnderOOM)
+	require.Equal(t, MemoryEvents{Max: 7, OOMKill: 2}, stats.Events)
+}
+
+func TestReadMemory_InvalidContent(t *testing.T) {
+	root := t.TempDir()
+	writeFiles(t, root, map[string]string{
+		"memory.current": "not-a-number",
+		"memory.max":     "max",
+		"memory.events":  "",
+	})
+
+	_, err := ReadMemory(root)
+	require.Error(t, err)
+}
+
+func TestReadMemory_NotFound(t *testing.T) {
+	_, err := ReadMemory(t.TempDir())
+	require.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestReadPids(t *testing.T) {
+	tests := []struct {
+		name     string
+		files    map[string]string
+		expected PidsStats
+	}{
+		{
+			name: "cgroup v2",
+			files: map[string]string{
+				"pids.current": "12\n",
+				"pids.max":     "1024\n",
+				"pids.events":  "max 3\n",
+			},
+			expected: PidsStats{Version: V2, Current: 12, Limit: 1024, Denied: 3},
+		},
+		{
+			name: "cgroup v2 unlimited",
+			files: map[string]string{
+				"pids.current": "12\n",
+				"pids.max":     "max\n",
+			},
+			expected: PidsStats{Version: V2, Current: 12},
+		},
+		{
+			name: "cgroup v1",
+			files: map[string]string{
+				"pids/pids.current": "7\n",
+				"pids/pids.max":     "100\n",
+				"pids/pids.events":  "max 0\n",
+			},
+			expected: PidsStats{Version: V1, Current: 7, Limit: 100},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			writeFiles(t, root, tt.files)
+
+			stats, err := ReadPids(root)
+			require.NoError(t, err)
+			require.Equal(t, tt.expected, *stats)
+		})
+	}
+}
+
+func TestReadPids_NotFound(t *testing.T) {
+	_, err := ReadPids(t.TempDir())
+	require.ErrorIs(t, err, ErrPidsNotFound)
+}
@@ -0,0 +1,199 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// Client talks to the control API of a running crashlooper.
+type Client struct {
+	baseURL    *url.URL
+	httpClient *http.Client
+	token      string
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithHTTPClient sets the HTTP client used to send the requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = httpClient
+	}
+}
+
+// WithToken sets the bearer token authenticating the requests.
+func WithToken(token string) Option {
+	return func(c *Client) {
+		c.token = token
+	}
+}
+
+// New returns a client of the crashlooper listening on server, such as
+// "http://localhost:3000". The scheme defaults to http.
+func New(server string, opts ...Option) (*Client, error) {
+	if !strings.Contains(server, "://") {
+		server = "http://" + server
+	}
+
+	baseURL, err := url.Parse(server)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid server")
+	}
+	if baseURL.Scheme != "http" && baseURL.Scheme != "https" || baseURL.Host == "" {
+		return nil, errors.Errorf("invalid server %q, expected an http(s) URL", server)
+	}
+
+	c := &Client{
+		baseURL:    baseURL,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	return c, nil
+}
+
+// Status returns the state of the controls.
+func (c *Client) Status(ctx context.Context) (*handlers.ControlStatus, error) {
+	var status handlers.ControlStatus
+	if err := c.do(ctx, http.MethodGet, "/api/status", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Crash schedules a crash, replacing the pending one if any.
+func (c *Client) Crash(ctx context.Context, req handlers.CrashRequest) (*crash.Schedule, error) {
+	var schedule crash.Schedule
+	if err := c.do(ctx, http.MethodPut, "/api/crash", req, &schedule); err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+// CancelCrash cancels the pending crash.
+func (c *Client) CancelCrash(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/api/crash", nil, nil)
+}
+
+// SetMemory replaces the memory usage target.
+func (c *Client) SetMemory(ctx context.Context, settings memory.Settings) (*memory.Status, error) {
+	var status memory.Status
+	if err := c.do(ctx, http.MethodPut, "/api/memory", settings, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// SetPids replaces the threads or processes target.
+func (c *Client) SetPids(ctx context.Context, settings pids.Settings) (*pids.Status, error) {
+	var status pids.Status
+	if err := c.do(ctx, http.MethodPut, "/api/pids", settings, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// TLS returns the certificate served over TLS.
+func (c *Client) TLS(ctx context.Context) (*certs.Status, error) {
+	var status certs.Status
+	if err := c.do(ctx, http.MethodGet, "/api/tls", nil, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// SetTLS replaces the certificate served over TLS.
+func (c *Client) SetTLS(ctx context.Context, settings certs.Settings) (*certs.Status, error) {
+	var status certs.Status
+	if err := c.do(ctx, http.MethodPut, "/api/tls", settings, &status); err != nil {
+		return nil, err
+	}
+	return &status, nil
+}
+
+// Faults returns the active faults.
+func (c *Client) Faults(ctx context.Context) ([]chaos.Fault, error) {
+	var faults []chaos.Fault
+	if err := c.do(ctx, http.MethodGet, "/api/faults", nil, &faults); err != nil {
+		return nil, err
+	}
+	return faults, nil
+}
+
+// SetFault sets the fault of f.Kind, replacing the active one if any.
+func (c *Client) SetFault(ctx context.Context, f chaos.Fault) (*chaos.Fault, error) {
+	var fault chaos.Fault
+	if err := c.do(ctx, http.MethodPut, "/api/faults/"+url.PathEscape(string(f.Kind)), f, &fault); err != nil {
+		return nil, err
+	}
+	return &fault, nil
+}
+
+// RemoveFault removes the active fault of kind k.
+func (c *Client) RemoveFault(ctx context.Context, k chaos.Kind) error {
+	return c.do(ctx, http.MethodDelete, "/api/faults/"+url.PathEscape(string(k)), nil, nil)
+}
+
+// do sends a request with in encoded as JSON body, if not nil,
+// and decodes the JSON response into out, if not nil.
+func (c *Client) do(ctx context.Context, method string, path string, in interface{}, out interface{}) error {
+	var body io.Reader
+	if in != nil {
+		b, err := json.Marshal(in)
+		if err != nil {
+			return errors.Wrap(err, "unable to encode request")
+		}
+		body = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, c.baseURL.JoinPath(path).String(), body)
+	if err != nil {
+		return errors.Wrap(err, "unable to create request")
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	req.Header.Set(events.HeaderTrigger, string(events.TriggerFrom(ctx)))
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return errors.Wrapf(err, "%s %s failed", method, path)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return errors.Errorf("%s %s failed: %s: %s", method, path, resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return errors.Wrap(err, "unable to decode response")
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
+	"github.com/pixelfactoryio/crashlooper/internal/auth"
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/internal/services/pids"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// startServer starts a crashlooper control API and returns a client of it.
+func startServer(t *testing.T) *Client {
+	t.Helper()
+
+	logger := log.New(log.WithLevel("info"))
+	router := api.NewRouter(
+		logger,
+		api.WithCrash(crash.New(logger, 0)),
+		api.WithMemory(memory.New(logger, 0, 0, 0)),
+		api.WithPids(pids.New(logger, pids.Settings{}, pids.WithCgroupRoot(t.TempDir()))),
+		api.WithFaults(chaos.NewController()),
+	)
+
+	srv := httptest.NewServer(router)
+	t.Cleanup(srv.Close)
+
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+	return c
+}
+
+func TestNew(t *testing.T) {
+	c, err := New("localhost:3000")
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:3000", c.baseURL.String())
+
+	httpClient := &http.Client{}
+	c, err = New("https://crashlooper.example.com", WithHTTPClient(httpClient))
+	require.NoError(t, err)
+	require.Equal(t, "https", c.baseURL.Scheme)
+	require.Same(t, httpClient, c.httpClient)
+
+	for _, server := range []string{"ftp://localhost", "http://", "http://[::1"} {
+		_, err := New(server)
+		require.Error(t, err, server)
+	}
+}
+
+func TestClient_Status(t *testing.T) {
+	c := startServer(t)
+
+	status, err := c.Status(context.Background())
+	require.NoError(t, err)
+	require.NotNil(t, status.Crash)
+	require.False(t, status.Crash.Scheduled)
+	require.NotNil(t, status.Memory)
+	require.Empty(t, status.Faults)
+	require.Nil(t, status.TCPFaults)
+}
+
+func TestClient_Crash(t *testing.T) {
+	c := startServer(t)
+	ctx := context.Background()
+
+	exitCode := 137
+	schedule, err := c.Crash(ctx, handlers.CrashRequest{After: chaos.Duration(time.Hour), ExitCode: &exitCode})
+	require.NoError(t, err)
+	require.True(t, schedule.Scheduled)
+	require.Equal(t, 137, schedule.ExitCode)
+
+	require.NoError(t, c.CancelCrash(ctx))
+	require.Error(t, c.CancelCrash(ctx))
+
+	_, err = c.Crash(ctx, handlers.CrashRequest{After: chaos.Duration(-time.Second)})
+	require.Error(t, err)
+}
+
+func TestClient_SetMemory(t *testing.T) {
+	c := startServer(t)
+
+	status, err := c.SetMemory(context.Background(), memory.Settings{
+		Target:    4 * units.KiB,
+		Increment: units.KiB,
+		Interval:  chaos.Duration(time.Millisecond),
+	})
+	require.NoError(t, err)
+	require.Equal(t, 4*units.KiB, status.Target)
+
+	_, err = c.SetMemory(context.Background(), memory.Settings{Target: -1})
+	require.Error(t, err)
+}
+
+func TestClient_SetPids(t *testing.T) {
+	c := startServer(t)
+
+	status, err := c.SetPids(context.Background(), pids.Settings{
+		Target:    2,
+		Increment: 1,
+		Interval:  chaos.Duration(time.Millisecond),
+	})
+	require.NoError(t, err)
+	require.Equal(t, pids.ModeThreads, status.Mode)
+	require.Equal(t, 2, status.Target)
+
+	_, err = c.SetPids(context.Background(), pids.Settings{})
+	require.NoError(t, err)
+
+	_, err = c.SetPids(context.Background(), pids.Settings{Mode: "forks"})
+	require.Error(t, err)
+}
+
+func TestClient_Faults(t *testing.T) {
+	c := startServer(t)
+	ctx := context.Background()
+
+	fault, err := c.SetFault(ctx, chaos.Fault{Kind: chaos.KindError, Probability: 0.5, StatusCode: 503})
+	require.NoError(t, err)
+	require.Equal(t, chaos.KindError, fault.Kind)
+
+	faults, err := c.Faults(ctx)
+	require.NoError(t, err)
+	require.Len(t, faults, 1)
+
+	require.NoError(t, c.RemoveFault(ctx, chaos.KindError))
+
+	faults, err = c.Faults(ctx)
+	require.NoError(t, err)
+	require.Empty(t, faults)
+
+	err = c.RemoveFault(ctx, chaos.KindError)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "404")
+
+	_, err = c.SetFault(ctx, chaos.Fault{Kind: chaos.KindError, Probability: 2})
+	require.Error(t, err)
+}
+
+func TestClient_Unreachable(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	srv.Close()
+
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+
+	_, err = c.Status(context.Background())
+	require.Error(t, err)
+}
+
+func TestClient_WithToken(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	router := api.NewRouter(
+		logger,
+		api.WithCrash(crash.New(logger, 0)),
+		api.WithAuth(auth.New(auth.WithTokens("secret"))),
+	)
+	srv := httptest.NewServer(router)
+	defer srv.Close()
+
+	ctx := context.Background()
+	req := handlers.CrashRequest{After: chaos.Duration(time.Hour)}
+
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+	_, err = c.Crash(ctx, req)
+	require.ErrorContains(t, err, "401")
+
+	c, err = New(srv.URL, WithToken("secret"))
+	require.NoError(t, err)
+	schedule, err := c.Crash(ctx, req)
+	require.NoError(t, err)
+	require.True(t, schedule.Scheduled)
+	require.NoError(t, c.CancelCrash(ctx))
+}
+
+func TestClient_TLS(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	manager, err := certs.New(logger, []string{"localhost"})
+	require.NoError(t, err)
+
+	srv := httptest.NewServer(api.NewRouter(logger, api.WithTLS(manager)))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+	ctx := context.Background()
+
+	status, err := c.TLS(ctx)
+	require.NoError(t, err)
+	require.Equal(t, certs.ModeValid, status.Mode)
+
+	s, err := LoadScenario(strings.NewReader(`{"steps": [{"at": "0s", "tls": {"mode": "wrong-host", "rotate_interval": "1h"}}]}`))
+	require.NoError(t, err)
+	require.Equal(t, "serve a wrong-host certificate", s.Steps[0].String())
+	require.NoError(t, c.RunScenario(ctx, s, nil))
+
+	status, err = c.TLS(ctx)
+	require.NoError(t, err)
+	require.Equal(t, certs.ModeWrongHost, status.Mode)
+	require.Equal(t, []string{certs.WrongHost}, status.Hosts)
+
+	_, err = c.SetTLS(ctx, certs.Settings{Mode: "revoked"})
+	require.ErrorContains(t, err, "400")
+}
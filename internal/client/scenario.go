@@ -0,0 +1,152 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/services/certs"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// Scenario is a list of control actions applied at given offsets from the
+// start of the run.
+type Scenario struct {
+	Name  string `json:"name"`
+	Steps []Step `json:"steps"`
+}
+
+// Step is a single control action of a scenario, exactly one of the action
+// fields must be set.
+type Step struct {
+	At chaos.Duration `json:"at"`
+
+	Fault       *chaos.Fault           `json:"fault,omitempty"`
+	RemoveFault chaos.Kind             `json:"remove_fault,omitempty"`
+	Memory      *memory.Settings       `json:"memory,omitempty"`
+	TLS         *certs.Settings        `json:"tls,omitempty"`
+	Crash       *handlers.CrashRequest `json:"crash,omitempty"`
+	CancelCrash bool                   `json:"cancel_crash,omitempty"`
+}
+
+// Validate returns an error if the step is not usable.
+func (s Step) Validate() error {
+	if s.At < 0 {
+		return fmt.Errorf("offset must not be negative")
+	}
+
+	actions := 0
+	for _, set := range []bool{s.Fault != nil, s.RemoveFault != "", s.Memory != nil, s.TLS != nil, s.Crash != nil, s.CancelCrash} {
+		if set {
+			actions++
+		}
+	}
+	if actions != 1 {
+		return fmt.Errorf("exactly one action must be set, got %d", actions)
+	}
+
+	if s.Fault != nil {
+		return s.Fault.Validate()
+	}
+	if s.TLS != nil {
+		return s.TLS.Validate()
+	}
+	return nil
+}
+
+// String describes the action of the step.
+func (s Step) String() string {
+	switch {
+	case s.Fault != nil:
+		return fmt.Sprintf("set %s fault", s.Fault.Kind)
+	case s.RemoveFault != "":
+		return fmt.Sprintf("remove %s fault", s.RemoveFault)
+	case s.Memory != nil:
+		return fmt.Sprintf("set memory target to %s", s.Memory.Target)
+	case s.TLS != nil:
+		return fmt.Sprintf("serve a %s certificate", s.TLS.Mode)
+	case s.Crash != nil:
+		return fmt.Sprintf("crash after %s", time.Duration(s.Crash.After))
+	case s.CancelCrash:
+		return "cancel crash"
+	default:
+		return "no action"
+	}
+}
+
+// LoadScenario decodes and validates a JSON scenario. The steps are sorted by offset.
+func LoadScenario(r io.Reader) (*Scenario, error) {
+	var s Scenario
+	dec := json.NewDecoder(r)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&s); err != nil {
+		return nil, errors.Wrap(err, "unable to decode scenario")
+	}
+
+	if len(s.Steps) == 0 {
+		return nil, errors.New("scenario has no steps")
+	}
+	for i, step := range s.Steps {
+		if err := step.Validate(); err != nil {
+			return nil, errors.Wrapf(err, "invalid step %d", i)
+		}
+	}
+
+	sort.SliceStable(s.Steps, func(i, j int) bool {
+		return s.Steps[i].At < s.Steps[j].At
+	})
+
+	return &s, nil
+}
+
+// RunScenario applies the steps of s at their offset from now and calls
+// progress once each step is applied. It stops at the first step failing.
+// The faults applied are reported with the scenario trigger.
+func (c *Client) RunScenario(ctx context.Context, s *Scenario, progress func(Step)) error {
+	ctx = events.WithTrigger(ctx, events.TriggerScenario)
+	start := time.Now()
+	for _, step := range s.Steps {
+		timer := time.NewTimer(time.Until(start.Add(time.Duration(step.At))))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+
+		if err := c.apply(ctx, step); err != nil {
+			return errors.Wrapf(err, "unable to %s", step)
+		}
+		if progress != nil {
+			progress(step)
+		}
+	}
+	return nil
+}
+
+func (c *Client) apply(ctx context.Context, step Step) error {
+	var err error
+	switch {
+	case step.Fault != nil:
+		_, err = c.SetFault(ctx, *step.Fault)
+	case step.RemoveFault != "":
+		err = c.RemoveFault(ctx, step.RemoveFault)
+	case step.Memory != nil:
+		_, err = c.SetMemory(ctx, *step.Memory)
+	case step.TLS != nil:
+		_, err = c.SetTLS(ctx, *step.TLS)
+	case step.Crash != nil:
+		_, err = c.Crash(ctx, *step.Crash)
+	case step.CancelCrash:
+		err = c.CancelCrash(ctx)
+	}
+	return err
+}
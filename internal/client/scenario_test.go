@@ -0,0 +1,126 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+const testScenario = `{
+	"name": "degrade",
+	"steps": [
+		{"at": "20ms", "remove_fault": "latency"},
+		{"at": "0s", "fault": {"kind": "latency", "probability": 1, "delay": "1ms"}},
+		{"at": "10ms", "memory": {"target": "4KiB", "increment": "1KiB", "interval": "1ms"}},
+		{"at": "30ms", "crash": {"after": "1h"}},
+		{"at": "40ms", "cancel_crash": true}
+	]
+}`
+
+func TestLoadScenario(t *testing.T) {
+	s, err := LoadScenario(strings.NewReader(testScenario))
+	require.NoError(t, err)
+	require.Equal(t, "degrade", s.Name)
+	require.Len(t, s.Steps, 5)
+
+	// sorted by offset
+	require.Equal(t, "set latency fault", s.Steps[0].String())
+	require.Equal(t, "set memory target to 4KiB", s.Steps[1].String())
+	require.Equal(t, "remove latency fault", s.Steps[2].String())
+	require.Equal(t, "crash after 1h0m0s", s.Steps[3].String())
+	require.Equal(t, "cancel crash", s.Steps[4].String())
+}
+
+func TestLoadScenario_Invalid(t *testing.T) {
+	tests := []struct {
+		name     string
+		scenario string
+	}{
+		{"invalid json", `{`},
+		{"unknown field", `{"steps": [{"at": "1s", "cancel_crash": true, "foo": 1}]}`},
+		{"no steps", `{"name": "empty"}`},
+		{"no action", `{"steps": [{"at": "1s"}]}`},
+		{"several actions", `{"steps": [{"at": "1s", "cancel_crash": true, "remove_fault": "error"}]}`},
+		{"negative offset", `{"steps": [{"at": "-1s", "cancel_crash": true}]}`},
+		{"invalid fault", `{"steps": [{"fault": {"kind": "error", "probability": 2}}]}`},
+		{"invalid tls", `{"steps": [{"tls": {"mode": "revoked"}}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := LoadScenario(strings.NewReader(tt.scenario))
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestClient_RunScenario(t *testing.T) {
+	c := startServer(t)
+
+	s, err := LoadScenario(strings.NewReader(testScenario))
+	require.NoError(t, err)
+
+	var applied []string
+	start := time.Now()
+	require.NoError(t, c.RunScenario(context.Background(), s, func(step Step) {
+		applied = append(applied, step.String())
+	}))
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+	require.Len(t, applied, 5)
+
+	status, err := c.Status(context.Background())
+	require.NoError(t, err)
+	require.Empty(t, status.Faults)
+	require.False(t, status.Crash.Scheduled)
+	require.Equal(t, "4KiB", status.Memory.Target.String())
+}
+
+func TestClient_RunScenario_Trigger(t *testing.T) {
+	var triggers []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		triggers = append(triggers, r.Header.Get(events.HeaderTrigger))
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	require.NoError(t, err)
+
+	require.NoError(t, c.CancelCrash(context.Background()))
+	require.NoError(t, c.RunScenario(context.Background(), &Scenario{Steps: []Step{{CancelCrash: true}}}, nil))
+	require.Equal(t, []string{"api", "scenario"}, triggers)
+}
+
+func TestClient_RunScenario_StopsOnError(t *testing.T) {
+	c := startServer(t)
+
+	s := &Scenario{Steps: []Step{
+		{RemoveFault: chaos.KindError},
+		{CancelCrash: true},
+	}}
+
+	var applied int
+	err := c.RunScenario(context.Background(), s, func(Step) { applied++ })
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "remove error fault")
+	require.Equal(t, 0, applied)
+}
+
+func TestClient_RunScenario_Cancel(t *testing.T) {
+	c := startServer(t)
+
+	s := &Scenario{Steps: []Step{{At: chaos.Duration(time.Hour), CancelCrash: true}}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	require.ErrorIs(t, c.RunScenario(ctx, s, nil), context.DeadlineExceeded)
+}
@@ -0,0 +1,26 @@
+// Package coordination lets the replicas of crashlooper agree on which one
+// may crash, so that at most one replica crashes at a time and the crashes
+// are staggered.
+//
+// A replica takes the crash slot for a hold duration before crashing, and
+// the slot is free again once the hold expires, leaving the crashed replica
+// the time to restart and become ready before another one crashes.
+package coordination
+
+import (
+	"context"
+	"time"
+)
+
+// Coordinator grants the crash slot.
+type Coordinator interface {
+	// TryAcquire takes the crash slot if it is free, or already held by this
+	// replica, and returns false if another replica holds it.
+	TryAcquire(ctx context.Context) (bool, error)
+}
+
+// held returns true if a slot acquired or renewed at renewed for hold is
+// still held at now.
+func held(renewed time.Time, hold time.Duration, now time.Time) bool {
+	return now.Before(renewed.Add(hold))
+}
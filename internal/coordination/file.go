@@ -0,0 +1,115 @@
+package coordination
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// staleLock is the age past which the lock of a replica which died while
+// updating the slot file is removed.
+const staleLock = 10 * time.Second
+
+// slot is the content of the slot file.
+type slot struct {
+	Holder  string    `json:"holder"`
+	Renewed time.Time `json:"renewed"`
+	Hold    string    `json:"hold"`
+}
+
+// File is a Coordinator holding the crash slot in a file shared by replicas
+// running on the same host, such as local runs.
+type File struct {
+	path     string
+	identity string
+	hold     time.Duration
+	now      func() time.Time
+}
+
+// NewFile returns a Coordinator holding the slot of the file at path for hold
+// on behalf of the replica identity.
+func NewFile(path, identity string, hold time.Duration) (*File, error) {
+	if path == "" || identity == "" {
+		return nil, errors.New("the slot file path and identity are required")
+	}
+	if hold <= 0 {
+		return nil, errors.New("the crash hold must be positive")
+	}
+
+	return &File{path: path, identity: identity, hold: hold, now: time.Now}, nil
+}
+
+// TryAcquire implements Coordinator. The slot file is updated under a lock
+// file, and a replica finding the lock taken reports the slot as held.
+func (f *File) TryAcquire(context.Context) (bool, error) {
+	unlock, ok, err := f.lock()
+	if err != nil || !ok {
+		return false, err
+	}
+	defer unlock()
+
+	now := f.now()
+	var current slot
+	b, err := os.ReadFile(f.path)
+	switch {
+	case os.IsNotExist(err):
+	case err != nil:
+		return false, errors.Wrap(err, "unable to read slot file")
+	default:
+		if err := json.Unmarshal(b, &current); err != nil {
+			return false, errors.Wrap(err, "invalid slot file")
+		}
+	}
+
+	if current.Holder != "" && current.Holder != f.identity {
+		hold, err := time.ParseDuration(current.Hold)
+		if err == nil && held(current.Renewed, hold, now) {
+			return false, nil
+		}
+	}
+
+	b, err = json.Marshal(slot{Holder: f.identity, Renewed: now, Hold: f.hold.String()})
+	if err != nil {
+		return false, err
+	}
+	// replace the file atomically so that readers never see it half written
+	tmp := f.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o644); err != nil {
+		return false, errors.Wrap(err, "unable to write slot file")
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		return false, errors.Wrap(err, "unable to write slot file")
+	}
+	return true, nil
+}
+
+// lock creates the lock file of the slot file, and returns false if another
+// replica holds it.
+func (f *File) lock() (func(), bool, error) {
+	path := f.path + ".lock"
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, false, errors.Wrap(err, "unable to create slot file directory")
+	}
+
+	for attempt := 0; attempt < 2; attempt++ {
+		lock, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+		if err == nil {
+			lock.Close()
+			return func() { _ = os.Remove(path) }, true, nil
+		}
+		if !os.IsExist(err) {
+			return nil, false, errors.Wrap(err, "unable to create lock file")
+		}
+
+		info, err := os.Stat(path)
+		if err != nil || f.now().Sub(info.ModTime()) < staleLock {
+			return nil, false, nil
+		}
+		_ = os.Remove(path)
+	}
+	return nil, false, nil
+}
@@ -0,0 +1,67 @@
+package coordination
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewFile_Invalid(t *testing.T) {
+	_, err := NewFile("", "a", time.Minute)
+	require.Error(t, err)
+	_, err = NewFile(filepath.Join(t.TempDir(), "slot"), "a", 0)
+	require.Error(t, err)
+}
+
+func TestFile_TryAcquire(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "crash", "slot")
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+
+	a, err := NewFile(path, "crashlooper-a", 30*time.Second)
+	require.NoError(t, err)
+	b, err := NewFile(path, "crashlooper-b", 30*time.Second)
+	require.NoError(t, err)
+	a.now = func() time.Time { return now }
+	b.now = a.now
+
+	ok, err := a.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = b.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	now = now.Add(31 * time.Second)
+	ok, err = b.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+	ok, err = a.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestFile_TryAcquire_Locked(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "slot")
+	f, err := NewFile(path, "crashlooper-a", time.Minute)
+	require.NoError(t, err)
+
+	// another replica is updating the slot
+	require.NoError(t, os.WriteFile(path+".lock", nil, 0o644))
+	ok, err := f.TryAcquire(context.Background())
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	// the replica died while updating the slot
+	old := time.Now().Add(-time.Minute)
+	require.NoError(t, os.Chtimes(path+".lock", old, old))
+	ok, err = f.TryAcquire(context.Background())
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.NoFileExists(t, path+".lock")
+}
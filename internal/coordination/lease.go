@@ -0,0 +1,104 @@
+package coordination
+
+import (
+	"context"
+	"time"
+
+	"github.com/pkg/errors"
+	coordinationv1 "k8s.io/api/coordination/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// Lease is a Coordinator holding the crash slot in a Kubernetes Lease,
+// shared by the replicas of a namespace.
+type Lease struct {
+	client    kubernetes.Interface
+	namespace string
+	name      string
+	identity  string
+	hold      time.Duration
+	now       func() time.Time
+}
+
+// NewLease returns a Coordinator holding the Lease name of namespace for
+// hold on behalf of the replica identity.
+func NewLease(client kubernetes.Interface, namespace, name, identity string, hold time.Duration) (*Lease, error) {
+	if namespace == "" || name == "" || identity == "" {
+		return nil, errors.New("the lease namespace, name and identity are required")
+	}
+	if hold < time.Second {
+		return nil, errors.New("the crash hold must be at least 1s")
+	}
+
+	return &Lease{
+		client:    client,
+		namespace: namespace,
+		name:      name,
+		identity:  identity,
+		hold:      hold,
+		now:       time.Now,
+	}, nil
+}
+
+// TryAcquire implements Coordinator. Conflicting updates of the Lease by
+// other replicas are reported as the slot being held.
+func (l *Lease) TryAcquire(ctx context.Context) (bool, error) {
+	leases := l.client.CoordinationV1().Leases(l.namespace)
+	now := metav1.NewMicroTime(l.now())
+	seconds := int32(l.hold / time.Second)
+
+	lease, err := leases.Get(ctx, l.name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		transitions := int32(0)
+		_, err = leases.Create(ctx, &coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{Name: l.name, Namespace: l.namespace},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       &l.identity,
+				LeaseDurationSeconds: &seconds,
+				AcquireTime:          &now,
+				RenewTime:            &now,
+				LeaseTransitions:     &transitions,
+			},
+		}, metav1.CreateOptions{})
+		if apierrors.IsAlreadyExists(err) {
+			return false, nil
+		}
+		if err != nil {
+			return false, errors.Wrap(err, "unable to create lease")
+		}
+		return true, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "unable to get lease")
+	}
+
+	spec := lease.Spec
+	if spec.HolderIdentity != nil && *spec.HolderIdentity != l.identity &&
+		spec.RenewTime != nil && spec.LeaseDurationSeconds != nil &&
+		held(spec.RenewTime.Time, time.Duration(*spec.LeaseDurationSeconds)*time.Second, now.Time) {
+		return false, nil
+	}
+
+	if spec.HolderIdentity == nil || *spec.HolderIdentity != l.identity {
+		lease.Spec.AcquireTime = &now
+		transitions := int32(0)
+		if spec.LeaseTransitions != nil {
+			transitions = *spec.LeaseTransitions + 1
+		}
+		lease.Spec.LeaseTransitions = &transitions
+	}
+	lease.Spec.HolderIdentity = &l.identity
+	lease.Spec.LeaseDurationSeconds = &seconds
+	lease.Spec.RenewTime = &now
+
+	_, err = leases.Update(ctx, lease, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, errors.Wrap(err, "unable to update lease")
+	}
+	return true, nil
+}
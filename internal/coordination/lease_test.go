@@ -0,0 +1,66 @@
+package coordination
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func newTestLeases(t *testing.T, now *time.Time, identities ...string) (*fake.Clientset, []*Lease) {
+	t.Helper()
+
+	client := fake.NewSimpleClientset()
+	var leases []*Lease
+	for _, identity := range identities {
+		l, err := NewLease(client, "crashlooper", "crashlooper-crash", identity, 30*time.Second)
+		require.NoError(t, err)
+		l.now = func() time.Time { return *now }
+		leases = append(leases, l)
+	}
+	return client, leases
+}
+
+func TestNewLease_Invalid(t *testing.T) {
+	client := fake.NewSimpleClientset()
+
+	_, err := NewLease(client, "", "crashlooper-crash", "a", time.Minute)
+	require.Error(t, err)
+	_, err = NewLease(client, "crashlooper", "crashlooper-crash", "a", time.Millisecond)
+	require.Error(t, err)
+}
+
+func TestLease_TryAcquire(t *testing.T) {
+	ctx := context.Background()
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	client, leases := newTestLeases(t, &now, "crashlooper-a", "crashlooper-b")
+	a, b := leases[0], leases[1]
+
+	ok, err := a.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	// held by a until the hold expires
+	ok, err = b.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.False(t, ok)
+
+	ok, err = a.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok, "the holder renews the slot")
+
+	now = now.Add(31 * time.Second)
+	ok, err = b.TryAcquire(ctx)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	lease, err := client.CoordinationV1().Leases("crashlooper").Get(ctx, "crashlooper-crash", metav1.GetOptions{})
+	require.NoError(t, err)
+	require.Equal(t, "crashlooper-b", *lease.Spec.HolderIdentity)
+	require.Equal(t, int32(30), *lease.Spec.LeaseDurationSeconds)
+	require.Equal(t, int32(1), *lease.Spec.LeaseTransitions)
+	require.Equal(t, now, lease.Spec.AcquireTime.UTC())
+}
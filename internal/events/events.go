@@ -0,0 +1,147 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Option configures a Bus.
+type Option func(*Bus)
+
+// WithHook calls fn with every event published, once numbered, before the
+// subscribers receive it. fn is called synchronously so that the events
+// published right before the process exits are not lost.
+func WithHook(fn func(Event)) Option {
+	return func(b *Bus) {
+		b.hooks = append(b.hooks, fn)
+	}
+}
+
+// Type is the lifecycle stage of a fault an event reports.
+type Type string
+
+const (
+	// TypeScheduled reports a fault planned for later.
+	TypeScheduled Type = "scheduled"
+	// TypeStarted reports a fault starting to be applied.
+	TypeStarted Type = "started"
+	// TypeProgress reports the progress of a fault being applied.
+	TypeProgress Type = "progress"
+	// TypeTriggered reports a fault fully applied.
+	TypeTriggered Type = "triggered"
+	// TypeCancelled reports a fault cancelled before being fully applied.
+	TypeCancelled Type = "cancelled"
+)
+
+// ParseType returns the event type named s, or false if s names none.
+func ParseType(s string) (Type, bool) {
+	switch t := Type(s); t {
+	case TypeScheduled, TypeStarted, TypeProgress, TypeTriggered, TypeCancelled:
+		return t, true
+	}
+	return "", false
+}
+
+const (
+	historySize    = 100
+	subscriberSize = historySize + 64
+)
+
+// Event is a fault lifecycle event. The events of the lifecycle of a fault
+// share the same FaultID.
+type Event struct {
+	ID      uint64                 `json:"id"`
+	Time    time.Time              `json:"time"`
+	Type    Type                   `json:"type"`
+	Source  string                 `json:"source"`
+	FaultID string                 `json:"fault_id,omitempty"`
+	Trigger Trigger                `json:"trigger,omitempty"`
+	Message string                 `json:"message,omitempty"`
+	Data    map[string]interface{} `json:"data,omitempty"`
+}
+
+// Exiting returns true if e reports a crash, of the crash service or of a
+// fault, about to exit the process.
+func (e Event) Exiting() bool {
+	return e.Type == TypeTriggered && (e.Source == "crash" || e.Source == SourceFaults)
+}
+
+// Publisher publishes events.
+type Publisher interface {
+	Publish(Event)
+}
+
+// Bus dispatches the published events to every subscriber and keeps the
+// last ones so that subscribers can catch up after reconnecting.
+type Bus struct {
+	mu          sync.Mutex
+	lastID      uint64
+	history     []Event
+	subscribers map[chan Event]struct{}
+	hooks       []func(Event)
+}
+
+// NewBus returns an empty Bus.
+func NewBus(opts ...Option) *Bus {
+	b := &Bus{
+		subscribers: make(map[chan Event]struct{}),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Publish numbers and timestamps e and sends it to the subscribers.
+// Events are dropped for the subscribers which are too slow to keep up.
+func (b *Bus) Publish(e Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.lastID++
+	e.ID = b.lastID
+	if e.Time.IsZero() {
+		e.Time = time.Now()
+	}
+
+	for _, hook := range b.hooks {
+		hook(e)
+	}
+
+	b.history = append(b.history, e)
+	if len(b.history) > historySize {
+		b.history = b.history[len(b.history)-historySize:]
+	}
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel receiving the events published after lastID,
+// starting with the ones still in history, and a function to unsubscribe.
+func (b *Bus) Subscribe(lastID uint64) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan Event, subscriberSize)
+	for _, e := range b.history {
+		if e.ID > lastID {
+			ch <- e
+		}
+	}
+	b.subscribers[ch] = struct{}{}
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			b.mu.Lock()
+			defer b.mu.Unlock()
+			delete(b.subscribers, ch)
+			close(ch)
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package events
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func receive(t *testing.T, ch <-chan Event) Event {
+	t.Helper()
+
+	select {
+	case e := <-ch:
+		return e
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestParseType(t *testing.T) {
+	for _, s := range []string{"scheduled", "started", "progress", "triggered", "cancelled"} {
+		got, ok := ParseType(s)
+		require.True(t, ok, s)
+		require.Equal(t, Type(s), got)
+	}
+
+	_, ok := ParseType("crashed")
+	require.False(t, ok)
+}
+
+func TestBus_Publish(t *testing.T) {
+	bus := NewBus()
+
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	bus.Publish(Event{Type: TypeScheduled, Source: "crash"})
+	bus.Publish(Event{Type: TypeCancelled, Source: "crash"})
+
+	e := receive(t, ch)
+	require.Equal(t, uint64(1), e.ID)
+	require.Equal(t, TypeScheduled, e.Type)
+	require.Equal(t, "crash", e.Source)
+	require.WithinDuration(t, time.Now(), e.Time, time.Second)
+
+	e = receive(t, ch)
+	require.Equal(t, uint64(2), e.ID)
+	require.Equal(t, TypeCancelled, e.Type)
+}
+
+func TestBus_Publish_KeepsTime(t *testing.T) {
+	bus := NewBus()
+	at := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	bus.Publish(Event{Type: TypeStarted, Time: at})
+
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+	require.Equal(t, at, receive(t, ch).Time)
+}
+
+func TestBus_Subscribe_History(t *testing.T) {
+	bus := NewBus()
+	for i := 0; i < historySize+10; i++ {
+		bus.Publish(Event{Type: TypeProgress})
+	}
+
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+	require.Len(t, ch, historySize)
+	require.Equal(t, uint64(11), receive(t, ch).ID)
+
+	ch, unsubscribe = bus.Subscribe(105)
+	defer unsubscribe()
+	require.Len(t, ch, 5)
+	require.Equal(t, uint64(106), receive(t, ch).ID)
+}
+
+func TestBus_Unsubscribe(t *testing.T) {
+	bus := NewBus()
+
+	ch, unsubscribe := bus.Subscribe(0)
+	unsubscribe()
+	unsubscribe()
+
+	bus.Publish(Event{Type: TypeTriggered})
+
+	_, ok := <-ch
+	require.False(t, ok)
+	require.Empty(t, bus.subscribers)
+}
+
+func TestBus_SlowSubscriber(t *testing.T) {
+	bus := NewBus()
+
+	slow, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	// publishing never blocks, the events overflowing the subscriber are dropped
+	for i := 0; i < subscriberSize+10; i++ {
+		bus.Publish(Event{Type: TypeProgress})
+	}
+	require.Len(t, slow, subscriberSize)
+}
+
+func TestBus_WithHook(t *testing.T) {
+	var hooked []Event
+	bus := NewBus(WithHook(func(e Event) { hooked = append(hooked, e) }))
+
+	bus.Publish(Event{Type: TypeScheduled, Source: "crash", FaultID: "f1", Trigger: TriggerFlag})
+
+	require.Len(t, hooked, 1)
+	require.Equal(t, uint64(1), hooked[0].ID)
+	require.Equal(t, "f1", hooked[0].FaultID)
+	require.Equal(t, TriggerFlag, hooked[0].Trigger)
+	require.False(t, hooked[0].Time.IsZero())
+}
+
+func TestEvent_Exiting(t *testing.T) {
+	require.True(t, Event{Type: TypeTriggered, Source: "crash"}.Exiting())
+	require.True(t, Event{Type: TypeTriggered, Source: SourceFaults}.Exiting())
+	require.False(t, Event{Type: TypeScheduled, Source: "crash"}.Exiting())
+	require.False(t, Event{Type: TypeTriggered, Source: "memory"}.Exiting())
+}
@@ -0,0 +1,62 @@
+package events
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// SourceFaults is the source of the events of the faults of a chaos.Controller.
+const SourceFaults = "faults"
+
+// FaultNotifier returns a function, meant for chaos.WithNotify, publishing the
+// changes of the faults to p. Setting a fault starts it, removing it cancels
+// it and a crash fault exiting the process triggers it. The events of a fault
+// share its fault id until it is replaced or removed.
+func FaultNotifier(p Publisher) func(ctx context.Context, ch chaos.Change) {
+	var (
+		mu  sync.Mutex
+		ids = map[chaos.Kind]string{}
+	)
+
+	return func(ctx context.Context, ch chaos.Change) {
+		mu.Lock()
+		id := ids[ch.Fault.Kind]
+		switch ch.Action {
+		case chaos.ActionSet:
+			id = NewFaultID()
+			ids[ch.Fault.Kind] = id
+		case chaos.ActionRemoved:
+			delete(ids, ch.Fault.Kind)
+		}
+		mu.Unlock()
+
+		e := Event{
+			Source:  SourceFaults,
+			FaultID: id,
+			Trigger: TriggerFrom(ctx),
+			Data:    faultData(ch.Fault),
+		}
+		switch ch.Action {
+		case chaos.ActionSet:
+			e.Type, e.Message = TypeStarted, "Fault set: "+string(ch.Fault.Kind)
+		case chaos.ActionRemoved:
+			e.Type, e.Message = TypeCancelled, "Fault removed: "+string(ch.Fault.Kind)
+		case chaos.ActionCrash:
+			e.Type, e.Message = TypeTriggered, "Crashing"
+		}
+		p.Publish(e)
+	}
+}
+
+// faultData returns the fields of f as encoded by the faults API.
+func faultData(f chaos.Fault) map[string]interface{} {
+	data := map[string]interface{}{}
+	b, err := json.Marshal(f)
+	if err == nil {
+		_ = json.Unmarshal(b, &data)
+	}
+	return data
+}
@@ -0,0 +1,52 @@
+package events
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+func TestFaultNotifier(t *testing.T) {
+	bus := NewBus()
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	c := chaos.NewController(
+		chaos.WithExitFunc(func(int) {}),
+		chaos.WithNotify(FaultNotifier(bus)),
+	)
+
+	ctx := WithTrigger(context.Background(), TriggerScenario)
+	latency := chaos.Fault{Kind: chaos.KindLatency, Probability: 1, Delay: chaos.Duration(time.Second)}
+	require.NoError(t, c.SetContext(ctx, latency))
+	require.True(t, c.Remove(chaos.KindLatency))
+
+	crash := chaos.Fault{Kind: chaos.KindCrash, Probability: 0.5, ExitCode: 3}
+	require.NoError(t, c.Set(crash))
+	c.Crash(context.Background(), crash)
+
+	set := receive(t, ch)
+	require.Equal(t, TypeStarted, set.Type)
+	require.Equal(t, SourceFaults, set.Source)
+	require.Equal(t, TriggerScenario, set.Trigger)
+	require.Equal(t, "latency", set.Data["kind"])
+	require.Equal(t, "1s", set.Data["delay"])
+	require.NotEmpty(t, set.FaultID)
+
+	removed := receive(t, ch)
+	require.Equal(t, TypeCancelled, removed.Type)
+	require.Equal(t, TriggerAPI, removed.Trigger)
+	require.Equal(t, set.FaultID, removed.FaultID)
+
+	crashSet := receive(t, ch)
+	require.NotEqual(t, set.FaultID, crashSet.FaultID)
+
+	crashed := receive(t, ch)
+	require.Equal(t, TypeTriggered, crashed.Type)
+	require.Equal(t, crashSet.FaultID, crashed.FaultID)
+	require.Equal(t, float64(3), crashed.Data["exit_code"])
+}
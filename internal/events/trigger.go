@@ -0,0 +1,61 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// Trigger is what applied a fault.
+type Trigger string
+
+const (
+	// TriggerFlag reports a fault configured by the command line flags.
+	TriggerFlag Trigger = "flag"
+	// TriggerAPI reports a fault applied through the control API.
+	TriggerAPI Trigger = "api"
+	// TriggerScenario reports a fault applied by a step of a scenario.
+	TriggerScenario Trigger = "scenario"
+	// TriggerRota reports a crash applied by the turn of the replica in the
+	// crash rota.
+	TriggerRota Trigger = "rota"
+)
+
+// HeaderTrigger is the request header telling the control API what applies
+// the faults of the request, api when missing.
+const HeaderTrigger = "X-Crashlooper-Trigger"
+
+// ParseTrigger returns the trigger named s, or false if s names none.
+func ParseTrigger(s string) (Trigger, bool) {
+	switch t := Trigger(s); t {
+	case TriggerFlag, TriggerAPI, TriggerScenario, TriggerRota:
+		return t, true
+	}
+	return "", false
+}
+
+type triggerKey struct{}
+
+// WithTrigger returns a copy of ctx carrying the trigger of the faults applied with it.
+func WithTrigger(ctx context.Context, t Trigger) context.Context {
+	return context.WithValue(ctx, triggerKey{}, t)
+}
+
+// TriggerFrom returns the trigger carried by ctx, TriggerAPI if none.
+func TriggerFrom(ctx context.Context) Trigger {
+	if t, ok := ctx.Value(triggerKey{}).(Trigger); ok {
+		return t
+	}
+	return TriggerAPI
+}
+
+// NewFaultID returns a new random fault identifier.
+func NewFaultID() string {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return strconv.FormatInt(time.Now().UnixNano(), 16)
+	}
+	return hex.EncodeToString(b)
+}
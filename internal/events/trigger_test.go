@@ -0,0 +1,32 @@
+package events
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerFrom(t *testing.T) {
+	require.Equal(t, TriggerAPI, TriggerFrom(context.Background()))
+
+	ctx := WithTrigger(context.Background(), TriggerScenario)
+	require.Equal(t, TriggerScenario, TriggerFrom(ctx))
+}
+
+func TestParseTrigger(t *testing.T) {
+	for _, s := range []string{"flag", "api", "scenario", "rota"} {
+		got, ok := ParseTrigger(s)
+		require.True(t, ok, s)
+		require.Equal(t, Trigger(s), got)
+	}
+
+	_, ok := ParseTrigger("cron")
+	require.False(t, ok)
+}
+
+func TestNewFaultID(t *testing.T) {
+	id := NewFaultID()
+	require.Len(t, id, 16)
+	require.NotEqual(t, id, NewFaultID())
+}
@@ -0,0 +1,181 @@
+// Package kubeevents records the fault events as Kubernetes Events of the
+// pod running crashlooper, so that `kubectl get events` shows them next to
+// the events of the kubelet.
+package kubeevents
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+// Reasons of the Kubernetes Events.
+const (
+	// ReasonCrash reports a crash scheduled or exiting the process.
+	ReasonCrash = "CrashlooperCrash"
+	// ReasonFault reports a fault started or fully applied.
+	ReasonFault = "CrashlooperFault"
+)
+
+const (
+	component = "crashlooper"
+	queueSize = 64
+)
+
+// signals names the signals conventionally reported by the exit codes above 128.
+var signals = map[int]string{
+	129: "SIGHUP",
+	130: "SIGINT",
+	131: "SIGQUIT",
+	134: "SIGABRT",
+	137: "SIGKILL",
+	139: "SIGSEGV",
+	143: "SIGTERM",
+}
+
+// Recorder creates the Kubernetes Events of the fault events.
+type Recorder struct {
+	logger  *log.DefaultLogger
+	client  kubernetes.Interface
+	pod     *podinfo.Info
+	timeout time.Duration
+	now     func() time.Time
+	queue   chan events.Event
+}
+
+// New returns a Recorder creating the Events of pod through client. timeout
+// bounds each request, and the time a crash waits for its Event before
+// exiting the process.
+func New(logger *log.DefaultLogger, client kubernetes.Interface, pod *podinfo.Info, timeout time.Duration) (*Recorder, error) {
+	if pod == nil || pod.Name == "" || pod.Namespace == "" {
+		return nil, errors.New("the pod name and namespace are required to record Kubernetes events")
+	}
+	if timeout <= 0 {
+		return nil, errors.New("the Kubernetes events timeout must be positive")
+	}
+
+	logger.Info("Creating Kubernetes events recorder", fields.String("pod", pod.String()))
+	return &Recorder{
+		logger:  logger,
+		client:  client,
+		pod:     pod,
+		timeout: timeout,
+		now:     time.Now,
+		queue:   make(chan events.Event, queueSize),
+	}, nil
+}
+
+// Record creates the Event of e, if e reports a fault starting or applied or
+// a crash. It is meant to be used as an events.WithHook function: the Event
+// of a crash exiting the process is created before Record returns, the
+// others are queued and created by Start.
+func (r *Recorder) Record(e events.Event) {
+	switch e.Type {
+	case events.TypeScheduled, events.TypeStarted, events.TypeTriggered:
+	default:
+		return
+	}
+
+	if e.Exiting() {
+		r.create(e)
+		return
+	}
+	select {
+	case r.queue <- e:
+	default:
+		r.logger.Warn("Kubernetes events queue full, dropping event", fields.Any("event_id", e.ID))
+	}
+}
+
+// Start creates the queued Events.
+func (r *Recorder) Start() {
+	for e := range r.queue {
+		r.create(e)
+	}
+}
+
+func (r *Recorder) create(e events.Event) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+
+	ev := r.event(e)
+	if _, err := r.client.CoreV1().Events(r.pod.Namespace).Create(ctx, ev, metav1.CreateOptions{}); err != nil {
+		r.logger.Error("Unable to create Kubernetes event", fields.String("reason", ev.Reason), fields.Error(err))
+	}
+}
+
+// event returns the Kubernetes Event of e.
+func (r *Recorder) event(e events.Event) *corev1.Event {
+	now := metav1.NewTime(r.now())
+	eventType, reason, message := corev1.EventTypeNormal, ReasonFault, e.Message
+
+	switch {
+	case e.Exiting():
+		eventType, reason = corev1.EventTypeWarning, ReasonCrash
+		message = "exiting with " + exitStatus(e.Data["exit_code"])
+	case e.Source == "crash" && e.Type == events.TypeScheduled:
+		eventType, reason = corev1.EventTypeWarning, ReasonCrash
+		message = "exiting with " + exitStatus(e.Data["exit_code"])
+		if at, ok := e.Data["at"].(time.Time); ok {
+			message += " in " + at.Sub(r.now()).Round(time.Second).String()
+		}
+	}
+	if e.Trigger != "" {
+		message += fmt.Sprintf(" (%s, triggered by %s)", e.Source, e.Trigger)
+	}
+
+	return &corev1.Event{
+		ObjectMeta: metav1.ObjectMeta{
+			// the name is unique as the one of the Events of client-go's recorder
+			Name:      fmt.Sprintf("%s.%x", r.pod.Name, now.UnixNano()),
+			Namespace: r.pod.Namespace,
+			Labels:    map[string]string{"crashlooper.pixelfactory.io/fault-id": e.FaultID},
+		},
+		InvolvedObject: corev1.ObjectReference{
+			APIVersion: "v1",
+			Kind:       "Pod",
+			Name:       r.pod.Name,
+			Namespace:  r.pod.Namespace,
+			UID:        types.UID(r.pod.UID),
+		},
+		Reason:              reason,
+		Message:             message,
+		Type:                eventType,
+		Source:              corev1.EventSource{Component: component, Host: r.pod.Node},
+		FirstTimestamp:      now,
+		LastTimestamp:       now,
+		Count:               1,
+		ReportingController: component,
+		ReportingInstance:   r.pod.Name,
+	}
+}
+
+// exitStatus describes an exit code, such as "code 137 (SIGKILL)".
+func exitStatus(v interface{}) string {
+	var code int
+	switch c := v.(type) {
+	case int:
+		code = c
+	case float64:
+		// exit codes decoded from JSON
+		code = int(c)
+	default:
+		return "an unknown code"
+	}
+
+	if signal, ok := signals[code]; ok {
+		return fmt.Sprintf("code %d (%s)", code, signal)
+	}
+	return fmt.Sprintf("code %d", code)
+}
@@ -0,0 +1,110 @@
+package kubeevents
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+var testPod = &podinfo.Info{Name: "crashlooper-7d9f", Namespace: "crashlooper", UID: "0f1e2d3c", Node: "node-1"}
+
+func newTestRecorder(t *testing.T) (*Recorder, *fake.Clientset) {
+	t.Helper()
+
+	client := fake.NewSimpleClientset()
+	r, err := New(log.New(log.WithLevel("info")), client, testPod, time.Second)
+	require.NoError(t, err)
+	return r, client
+}
+
+func listEvents(t *testing.T, client *fake.Clientset) []corev1.Event {
+	t.Helper()
+
+	list, err := client.CoreV1().Events("crashlooper").List(context.Background(), metav1.ListOptions{})
+	require.NoError(t, err)
+	return list.Items
+}
+
+func TestNew_Invalid(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	client := fake.NewSimpleClientset()
+
+	_, err := New(logger, client, &podinfo.Info{Hostname: "localhost"}, time.Second)
+	require.Error(t, err)
+	_, err = New(logger, client, testPod, 0)
+	require.Error(t, err)
+}
+
+func TestRecorder_Record_Crash(t *testing.T) {
+	r, client := newTestRecorder(t)
+	now := time.Date(2022, 6, 1, 10, 0, 0, 0, time.UTC)
+	r.now = func() time.Time { return now }
+
+	// created synchronously as the process exits right after
+	r.Record(events.Event{
+		Type:    events.TypeTriggered,
+		Source:  "crash",
+		FaultID: "f1",
+		Trigger: events.TriggerAPI,
+		Data:    map[string]interface{}{"exit_code": 137},
+	})
+
+	items := listEvents(t, client)
+	require.Len(t, items, 1)
+	ev := items[0]
+	require.Equal(t, ReasonCrash, ev.Reason)
+	require.Equal(t, corev1.EventTypeWarning, ev.Type)
+	require.Equal(t, "exiting with code 137 (SIGKILL) (crash, triggered by api)", ev.Message)
+	require.Equal(t, "f1", ev.Labels["crashlooper.pixelfactory.io/fault-id"])
+	require.Equal(t, corev1.ObjectReference{
+		APIVersion: "v1",
+		Kind:       "Pod",
+		Name:       "crashlooper-7d9f",
+		Namespace:  "crashlooper",
+		UID:        "0f1e2d3c",
+	}, ev.InvolvedObject)
+	require.Equal(t, corev1.EventSource{Component: "crashlooper", Host: "node-1"}, ev.Source)
+	require.Equal(t, now, ev.FirstTimestamp.UTC())
+}
+
+func TestRecorder_Record_Queued(t *testing.T) {
+	r, client := newTestRecorder(t)
+	go r.Start()
+
+	at := time.Now().Add(5 * time.Second)
+	r.Record(events.Event{Type: events.TypeProgress, Source: "memory", Message: "Memory incremented"})
+	r.Record(events.Event{Type: events.TypeCancelled, Source: "crash", Message: "Crash cancelled"})
+	r.Record(events.Event{
+		Type:    events.TypeScheduled,
+		Source:  "crash",
+		Trigger: events.TriggerFlag,
+		Data:    map[string]interface{}{"at": at, "exit_code": 1},
+	})
+	r.Record(events.Event{Type: events.TypeStarted, Source: events.SourceFaults, Trigger: events.TriggerScenario, Message: "Fault set: latency"})
+
+	require.Eventually(t, func() bool { return len(listEvents(t, client)) == 2 }, time.Second, 10*time.Millisecond)
+
+	messages := map[string]string{}
+	for _, ev := range listEvents(t, client) {
+		messages[ev.Reason] = ev.Message
+	}
+	require.Equal(t, map[string]string{
+		ReasonCrash: "exiting with code 1 in 5s (crash, triggered by flag)",
+		ReasonFault: "Fault set: latency (faults, triggered by scenario)",
+	}, messages)
+}
+
+func TestExitStatus(t *testing.T) {
+	require.Equal(t, "code 1", exitStatus(1))
+	require.Equal(t, "code 143 (SIGTERM)", exitStatus(float64(143)))
+	require.Equal(t, "an unknown code", exitStatus(nil))
+}
@@ -0,0 +1,183 @@
+package operator
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"strconv"
+
+	"github.com/pkg/errors"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+
+	"github.com/pixelfactoryio/crashlooper/internal/operator/v1alpha1"
+)
+
+const (
+	// DefaultImage is the image of crashlooper when the spec sets none.
+	DefaultImage = "pixelfactory/crashlooper:beta"
+	// DefaultPort is the port of crashlooper when the spec sets none.
+	DefaultPort = 3000
+
+	containerName = "crashlooper"
+	scenarioKey   = "scenario.json"
+	scenarioDir   = "/etc/crashlooper"
+
+	// annotationScenario is the hash of the scenario on the pod template,
+	// rolling the replicas out to run a changed scenario from the start.
+	annotationScenario = "crashlooper.pixelfactory.io/scenario-hash"
+)
+
+// labels returns the labels selecting the replicas of cs.
+func labels(cs *v1alpha1.ChaosScenario) map[string]string {
+	return map[string]string{
+		"app.kubernetes.io/name":       "crashlooper",
+		"app.kubernetes.io/instance":   cs.Name,
+		"app.kubernetes.io/managed-by": "crashlooper-operator",
+	}
+}
+
+// port returns the port of the replicas of cs.
+func port(cs *v1alpha1.ChaosScenario) int32 {
+	if cs.Spec.Port != 0 {
+		return cs.Spec.Port
+	}
+	return DefaultPort
+}
+
+// scenarioName returns the name of the ConfigMap of the scenario of cs.
+func scenarioName(cs *v1alpha1.ChaosScenario) string {
+	return cs.Name + "-scenario"
+}
+
+// scenarioData returns the content of the scenario ConfigMap of cs and its hash.
+func scenarioData(cs *v1alpha1.ChaosScenario) (map[string]string, string, error) {
+	b, err := json.Marshal(cs.Spec.Scenario)
+	if err != nil {
+		return nil, "", errors.Wrap(err, "unable to encode scenario")
+	}
+	sum := sha256.Sum256(b)
+	return map[string]string{scenarioKey: string(b)}, hex.EncodeToString(sum[:8]), nil
+}
+
+// args returns the command line of the replicas of cs.
+func args(cs *v1alpha1.ChaosScenario) []string {
+	a := []string{"--port", strconv.Itoa(int(port(cs)))}
+	if cs.Spec.CrashAfter != nil {
+		a = append(a, "--crash-after", cs.Spec.CrashAfter.Duration.String())
+	}
+	if cs.Spec.Scenario != nil {
+		a = append(a, "--scenario", scenarioDir+"/"+scenarioKey)
+	}
+	return append(a, cs.Spec.Args...)
+}
+
+// mutateDeployment sets the desired state of the Deployment of cs on d,
+// keeping the fields set by the API server. scenarioHash is empty when cs
+// has no scenario.
+func mutateDeployment(cs *v1alpha1.ChaosScenario, d *appsv1.Deployment, scenarioHash string) {
+	l := labels(cs)
+	d.Labels = l
+	// the selector of a Deployment is immutable
+	if d.CreationTimestamp.IsZero() {
+		d.Spec.Selector = &metav1.LabelSelector{MatchLabels: l}
+	}
+	replicas := int32(1)
+	if cs.Spec.Replicas != nil {
+		replicas = *cs.Spec.Replicas
+	}
+	d.Spec.Replicas = &replicas
+
+	template := &d.Spec.Template
+	template.Labels = l
+	if scenarioHash != "" {
+		if template.Annotations == nil {
+			template.Annotations = map[string]string{}
+		}
+		template.Annotations[annotationScenario] = scenarioHash
+	} else {
+		delete(template.Annotations, annotationScenario)
+	}
+
+	var c *corev1.Container
+	for i := range template.Spec.Containers {
+		if template.Spec.Containers[i].Name == containerName {
+			c = &template.Spec.Containers[i]
+		}
+	}
+	if c == nil {
+		template.Spec.Containers = append(template.Spec.Containers, corev1.Container{Name: containerName})
+		c = &template.Spec.Containers[len(template.Spec.Containers)-1]
+	}
+
+	c.Image = cs.Spec.Image
+	if c.Image == "" {
+		c.Image = DefaultImage
+	}
+	c.Args = args(cs)
+	c.Env = env(cs)
+	c.Ports = []corev1.ContainerPort{{Name: "http", ContainerPort: port(cs), Protocol: corev1.ProtocolTCP}}
+	c.ReadinessProbe = &corev1.Probe{
+		ProbeHandler: corev1.ProbeHandler{
+			HTTPGet: &corev1.HTTPGetAction{Path: "/checks/health", Port: intstr.FromString("http"), Scheme: corev1.URISchemeHTTP},
+		},
+		TimeoutSeconds:   1,
+		PeriodSeconds:    10,
+		SuccessThreshold: 1,
+		FailureThreshold: 3,
+	}
+
+	c.VolumeMounts = nil
+	template.Spec.Volumes = nil
+	if scenarioHash != "" {
+		c.VolumeMounts = []corev1.VolumeMount{{Name: "scenario", MountPath: scenarioDir, ReadOnly: true}}
+		mode := int32(corev1.ConfigMapVolumeSourceDefaultMode)
+		template.Spec.Volumes = []corev1.Volume{{
+			Name: "scenario",
+			VolumeSource: corev1.VolumeSource{
+				ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: scenarioName(cs)},
+					DefaultMode:          &mode,
+				},
+			},
+		}}
+	}
+}
+
+// env returns the environment of the replicas of cs, identifying their pod
+// and authenticating the control API.
+func env(cs *v1alpha1.ChaosScenario) []corev1.EnvVar {
+	var e []corev1.EnvVar
+	for _, v := range []struct {
+		name  string
+		field string
+	}{
+		{"POD_NAME", "metadata.name"},
+		{"POD_NAMESPACE", "metadata.namespace"},
+		{"POD_UID", "metadata.uid"},
+		{"NODE_NAME", "spec.nodeName"},
+		{"POD_IP", "status.podIP"},
+	} {
+		e = append(e, corev1.EnvVar{
+			Name: v.name,
+			ValueFrom: &corev1.EnvVarSource{
+				FieldRef: &corev1.ObjectFieldSelector{APIVersion: "v1", FieldPath: v.field},
+			},
+		})
+	}
+
+	if cs.Spec.AuthSecretName != "" {
+		e = append(e, corev1.EnvVar{
+			Name: "CRASHLOOPER_AUTH_TOKEN",
+			ValueFrom: &corev1.EnvVarSource{
+				SecretKeyRef: &corev1.SecretKeySelector{
+					LocalObjectReference: corev1.LocalObjectReference{Name: cs.Spec.AuthSecretName},
+					Key:                  secretTokenKey,
+				},
+			},
+		})
+	}
+	return e
+}
@@ -0,0 +1,77 @@
+package operator
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pixelfactoryio/crashlooper/internal/client"
+	"github.com/pixelfactoryio/crashlooper/internal/operator/v1alpha1"
+)
+
+func TestMutateDeployment(t *testing.T) {
+	cs := &v1alpha1.ChaosScenario{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "chaos"},
+		Spec: v1alpha1.ChaosScenarioSpec{
+			Image:          "pixelfactory/crashlooper:v1",
+			Port:           8080,
+			Args:           []string{"--log-level", "debug"},
+			AuthSecretName: "crashlooper-token",
+			Scenario:       &client.Scenario{Name: "crash", Steps: []client.Step{{CancelCrash: true}}},
+		},
+	}
+	_, hash, err := scenarioData(cs)
+	require.NoError(t, err)
+
+	d := &appsv1.Deployment{}
+	mutateDeployment(cs, d, hash)
+
+	require.Equal(t, int32(1), *d.Spec.Replicas)
+	require.Equal(t, hash, d.Spec.Template.Annotations[annotationScenario])
+	c := d.Spec.Template.Spec.Containers[0]
+	require.Equal(t, "pixelfactory/crashlooper:v1", c.Image)
+	require.Equal(t, []string{"--port", "8080", "--scenario", "/etc/crashlooper/scenario.json", "--log-level", "debug"}, c.Args)
+	require.Equal(t, int32(8080), c.Ports[0].ContainerPort)
+	require.Equal(t, "/etc/crashlooper", c.VolumeMounts[0].MountPath)
+	require.Equal(t, "checkout-scenario", d.Spec.Template.Spec.Volumes[0].ConfigMap.Name)
+
+	token := c.Env[len(c.Env)-1]
+	require.Equal(t, "CRASHLOOPER_AUTH_TOKEN", token.Name)
+	require.Equal(t, "crashlooper-token", token.ValueFrom.SecretKeyRef.Name)
+
+	// the selector of an existing Deployment is kept, the containers added
+	// by others are left as is
+	d.CreationTimestamp = metav1.Now()
+	d.Spec.Selector.MatchLabels = map[string]string{"app": "checkout"}
+	d.Spec.Template.Spec.Containers = append(d.Spec.Template.Spec.Containers, d.Spec.Template.Spec.Containers[0])
+	d.Spec.Template.Spec.Containers[1].Name = "sidecar"
+	cs.Spec.Scenario = nil
+	cs.Spec.CrashAfter = &metav1.Duration{Duration: time.Minute}
+	mutateDeployment(cs, d, "")
+
+	require.Equal(t, map[string]string{"app": "checkout"}, d.Spec.Selector.MatchLabels)
+	require.NotContains(t, d.Spec.Template.Annotations, annotationScenario)
+	require.Len(t, d.Spec.Template.Spec.Containers, 2)
+	c = d.Spec.Template.Spec.Containers[0]
+	require.Equal(t, []string{"--port", "8080", "--crash-after", "1m0s", "--log-level", "debug"}, c.Args)
+	require.Empty(t, c.VolumeMounts)
+	require.Empty(t, d.Spec.Template.Spec.Volumes)
+}
+
+func TestScenarioData(t *testing.T) {
+	cs := &v1alpha1.ChaosScenario{Spec: v1alpha1.ChaosScenarioSpec{
+		Scenario: &client.Scenario{Name: "crash", Steps: []client.Step{{CancelCrash: true}}},
+	}}
+	data, hash, err := scenarioData(cs)
+	require.NoError(t, err)
+	require.JSONEq(t, `{"name": "crash", "steps": [{"at": "0s", "cancel_crash": true}]}`, data["scenario.json"])
+	require.Len(t, hash, 16)
+
+	cs.Spec.Scenario.Name = "other"
+	_, other, err := scenarioData(cs)
+	require.NoError(t, err)
+	require.NotEqual(t, hash, other)
+}
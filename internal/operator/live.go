@@ -0,0 +1,63 @@
+package operator
+
+import (
+	"context"
+
+	"github.com/pixelfactoryio/crashlooper/internal/client"
+	"github.com/pixelfactoryio/crashlooper/internal/operator/v1alpha1"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// syncReplica pushes the faults and the memory target of the spec of cs to
+// the replica whose control API is served at server, and removes the faults
+// applied for a previous spec. The settings already applied are left as is,
+// so that a resync does not report them again in the events of the replica.
+func (r *Reconciler) syncReplica(ctx context.Context, server, token string, cs *v1alpha1.ChaosScenario) error {
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	c, err := client.New(server, client.WithToken(token), client.WithHTTPClient(r.httpClient))
+	if err != nil {
+		return err
+	}
+
+	faults, err := c.Faults(ctx)
+	if err != nil {
+		return err
+	}
+	applied := make(map[chaos.Kind]chaos.Fault, len(faults))
+	for _, f := range faults {
+		applied[f.Kind] = f
+	}
+
+	desired := make(map[chaos.Kind]bool, len(cs.Spec.Faults))
+	for _, f := range cs.Spec.Faults {
+		desired[f.Kind] = true
+		if current, ok := applied[f.Kind]; ok && current == f {
+			continue
+		}
+		if _, err := c.SetFault(ctx, f); err != nil {
+			return err
+		}
+	}
+	for _, k := range cs.Status.Faults {
+		if _, ok := applied[k]; ok && !desired[k] {
+			if err := c.RemoveFault(ctx, k); err != nil {
+				return err
+			}
+		}
+	}
+
+	if cs.Spec.Memory == nil {
+		return nil
+	}
+	status, err := c.Status(ctx)
+	if err != nil {
+		return err
+	}
+	if status.Memory != nil && status.Memory.Settings == *cs.Spec.Memory {
+		return nil
+	}
+	_, err = c.SetMemory(ctx, *cs.Spec.Memory)
+	return err
+}
@@ -0,0 +1,52 @@
+package operator
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/auth"
+	"github.com/pixelfactoryio/crashlooper/internal/operator/v1alpha1"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+func TestReconciler_SyncReplica(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	router := api.NewRouter(
+		logger,
+		api.WithMemory(memory.New(logger, 0, 0, 0)),
+		api.WithFaults(chaos.NewController()),
+		api.WithAuth(auth.New(auth.WithTokens("secret"))),
+	)
+	var writes atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			writes.Add(1)
+		}
+		router.ServeHTTP(w, r)
+	}))
+	defer srv.Close()
+
+	cs := &v1alpha1.ChaosScenario{Spec: v1alpha1.ChaosScenarioSpec{
+		Faults: []chaos.Fault{{Kind: chaos.KindLatency, Probability: 1, Delay: chaos.Duration(10 * time.Millisecond)}},
+		Memory: &memory.Settings{Target: units.MiB, Increment: units.MiB, Interval: chaos.Duration(time.Hour)},
+	}}
+	r := NewReconciler(logger, nil, nil)
+
+	require.Error(t, r.syncReplica(context.Background(), srv.URL, "wrong", cs))
+	require.NoError(t, r.syncReplica(context.Background(), srv.URL, "secret", cs))
+	require.Equal(t, int32(3), writes.Load())
+
+	// the settings already applied are not pushed again
+	require.NoError(t, r.syncReplica(context.Background(), srv.URL, "secret", cs))
+	require.Equal(t, int32(3), writes.Load())
+}
@@ -0,0 +1,261 @@
+// Package operator reconciles the ChaosScenario resources into crashlooper
+// Deployments and scenario ConfigMaps, and pushes the live faults of their
+// spec to the running replicas through the crashlooper control API.
+package operator
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	"github.com/pixelfactoryio/crashlooper/internal/operator/v1alpha1"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// secretTokenKey is the key of the token in the Secret named by the spec.
+const secretTokenKey = "token"
+
+// Reconciler reconciles the ChaosScenario resources.
+type Reconciler struct {
+	logger     *log.DefaultLogger
+	client     ctrlclient.Client
+	scheme     *runtime.Scheme
+	httpClient *http.Client
+	// resync is the interval the live faults are pushed again, the
+	// replicas losing them when they crash.
+	resync time.Duration
+	// timeout bounds the requests to the control API of each replica.
+	timeout time.Duration
+}
+
+// Option configures a Reconciler.
+type Option func(*Reconciler)
+
+// WithHTTPClient sets the HTTP client of the requests to the control API.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(r *Reconciler) {
+		r.httpClient = httpClient
+	}
+}
+
+// WithResync sets the interval the live faults are pushed again, 30s by default.
+func WithResync(d time.Duration) Option {
+	return func(r *Reconciler) {
+		r.resync = d
+	}
+}
+
+// NewReconciler returns a Reconciler managing the resources with c. The
+// scheme registers the ChaosScenario resources.
+func NewReconciler(logger *log.DefaultLogger, c ctrlclient.Client, scheme *runtime.Scheme, opts ...Option) *Reconciler {
+	r := &Reconciler{
+		logger:     logger,
+		client:     c,
+		scheme:     scheme,
+		httpClient: &http.Client{},
+		resync:     30 * time.Second,
+		timeout:    5 * time.Second,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// SetupWithManager reconciles the ChaosScenario resources of mgr and the
+// resources they own.
+func (r *Reconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&v1alpha1.ChaosScenario{}).
+		Owns(&appsv1.Deployment{}).
+		Owns(&corev1.ConfigMap{}).
+		Complete(r)
+}
+
+// Reconcile creates or updates the Deployment and the scenario ConfigMap of
+// the ChaosScenario, pushes its live faults to the ready replicas and
+// reports the outcome in its status.
+func (r *Reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	cs := &v1alpha1.ChaosScenario{}
+	if err := r.client.Get(ctx, req.NamespacedName, cs); err != nil {
+		return ctrl.Result{}, ctrlclient.IgnoreNotFound(err)
+	}
+	logger := r.logger.With(fields.String("namespace", cs.Namespace), fields.String("name", cs.Name))
+
+	if err := cs.Spec.Validate(); err != nil {
+		logger.Warn("Invalid chaos scenario", fields.Error(err))
+		r.setCondition(cs, metav1.ConditionFalse, "Invalid", err.Error())
+		// the resource is reconciled again once its spec changes
+		return ctrl.Result{}, r.client.Status().Update(ctx, cs)
+	}
+
+	scenarioHash, err := r.reconcileScenario(ctx, cs)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	d := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Name: cs.Name, Namespace: cs.Namespace}}
+	op, err := controllerutil.CreateOrUpdate(ctx, r.client, d, func() error {
+		mutateDeployment(cs, d, scenarioHash)
+		return controllerutil.SetControllerReference(cs, d, r.scheme)
+	})
+	if err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "unable to reconcile deployment")
+	}
+	if op != controllerutil.OperationResultNone {
+		logger.Info("Deployment reconciled", fields.String("operation", string(op)))
+	}
+
+	synced, syncErr := r.syncReplicas(ctx, cs)
+	if syncErr != nil {
+		logger.Warn("Unable to push the live faults", fields.Error(syncErr))
+	}
+
+	cs.Status.ObservedGeneration = cs.Generation
+	cs.Status.ReadyReplicas = d.Status.ReadyReplicas
+	cs.Status.SyncedReplicas = synced
+	cs.Status.Faults = appliedKinds(cs, syncErr != nil)
+	if syncErr != nil {
+		r.setCondition(cs, metav1.ConditionFalse, "SyncFailed", syncErr.Error())
+	} else {
+		r.setCondition(cs, metav1.ConditionTrue, "Synced", "The ready replicas apply the live faults")
+	}
+	if err := r.client.Status().Update(ctx, cs); err != nil {
+		return ctrl.Result{}, errors.Wrap(err, "unable to update status")
+	}
+
+	return ctrl.Result{RequeueAfter: r.resync}, nil
+}
+
+// reconcileScenario creates or updates the scenario ConfigMap of cs, or
+// deletes it when cs has no scenario, and returns the hash of the scenario.
+func (r *Reconciler) reconcileScenario(ctx context.Context, cs *v1alpha1.ChaosScenario) (string, error) {
+	cm := &corev1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: scenarioName(cs), Namespace: cs.Namespace}}
+	if cs.Spec.Scenario == nil {
+		if err := r.client.Delete(ctx, cm); err != nil && !apierrors.IsNotFound(err) {
+			return "", errors.Wrap(err, "unable to delete scenario configmap")
+		}
+		return "", nil
+	}
+
+	data, hash, err := scenarioData(cs)
+	if err != nil {
+		return "", err
+	}
+	_, err = controllerutil.CreateOrUpdate(ctx, r.client, cm, func() error {
+		cm.Labels = labels(cs)
+		cm.Data = data
+		return controllerutil.SetControllerReference(cs, cm, r.scheme)
+	})
+	if err != nil {
+		return "", errors.Wrap(err, "unable to reconcile scenario configmap")
+	}
+	return hash, nil
+}
+
+// syncReplicas pushes the live faults of cs to its ready replicas and
+// returns the number of replicas synced, and the first error met.
+func (r *Reconciler) syncReplicas(ctx context.Context, cs *v1alpha1.ChaosScenario) (int32, error) {
+	token, err := r.token(ctx, cs)
+	if err != nil {
+		return 0, err
+	}
+
+	pods := &corev1.PodList{}
+	if err := r.client.List(ctx, pods, ctrlclient.InNamespace(cs.Namespace), ctrlclient.MatchingLabels(labels(cs))); err != nil {
+		return 0, errors.Wrap(err, "unable to list replicas")
+	}
+
+	var synced int32
+	var firstErr error
+	for _, pod := range pods.Items {
+		if !ready(&pod) {
+			continue
+		}
+		server := "http://" + net.JoinHostPort(pod.Status.PodIP, strconv.Itoa(int(port(cs))))
+		if err := r.syncReplica(ctx, server, token, cs); err != nil {
+			if firstErr == nil {
+				firstErr = errors.Wrapf(err, "replica %s", pod.Name)
+			}
+			continue
+		}
+		synced++
+	}
+	return synced, firstErr
+}
+
+// token returns the token authenticating the requests to the control API of
+// the replicas of cs, empty when the control API is not authenticated.
+func (r *Reconciler) token(ctx context.Context, cs *v1alpha1.ChaosScenario) (string, error) {
+	if cs.Spec.AuthSecretName == "" {
+		return "", nil
+	}
+	secret := &corev1.Secret{}
+	key := ctrlclient.ObjectKey{Namespace: cs.Namespace, Name: cs.Spec.AuthSecretName}
+	if err := r.client.Get(ctx, key, secret); err != nil {
+		return "", errors.Wrap(err, "unable to read auth secret")
+	}
+	token, ok := secret.Data[secretTokenKey]
+	if !ok {
+		return "", errors.Errorf("auth secret %s has no %s key", secret.Name, secretTokenKey)
+	}
+	return string(token), nil
+}
+
+func (r *Reconciler) setCondition(cs *v1alpha1.ChaosScenario, status metav1.ConditionStatus, reason, message string) {
+	meta.SetStatusCondition(&cs.Status.Conditions, metav1.Condition{
+		Type:               v1alpha1.ConditionSynced,
+		Status:             status,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: cs.Generation,
+	})
+}
+
+// ready returns true if pod is ready to serve its control API.
+func ready(pod *corev1.Pod) bool {
+	if pod.Status.PodIP == "" || pod.DeletionTimestamp != nil {
+		return false
+	}
+	for _, c := range pod.Status.Conditions {
+		if c.Type == corev1.PodReady {
+			return c.Status == corev1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// appliedKinds returns the kinds of the faults applied to the replicas of
+// cs. The kinds removed from the spec are kept while a replica may still
+// apply them, so that they are removed on the next sync.
+func appliedKinds(cs *v1alpha1.ChaosScenario, failed bool) []chaos.Kind {
+	var kinds []chaos.Kind
+	seen := make(map[chaos.Kind]bool)
+	for _, f := range cs.Spec.Faults {
+		kinds = append(kinds, f.Kind)
+		seen[f.Kind] = true
+	}
+	if failed {
+		for _, k := range cs.Status.Faults {
+			if !seen[k] {
+				kinds = append(kinds, k)
+			}
+		}
+	}
+	return kinds
+}
@@ -0,0 +1,253 @@
+package operator
+
+import (
+	"context"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	ctrlclient "sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api"
+	"github.com/pixelfactoryio/crashlooper/internal/client"
+	"github.com/pixelfactoryio/crashlooper/internal/operator/v1alpha1"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+func newScheme(t *testing.T) *runtime.Scheme {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	require.NoError(t, clientgoscheme.AddToScheme(scheme))
+	require.NoError(t, v1alpha1.AddToScheme(scheme))
+	return scheme
+}
+
+func newReconciler(t *testing.T, objs ...ctrlclient.Object) (*Reconciler, ctrlclient.Client) {
+	t.Helper()
+	scheme := newScheme(t)
+	c := fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(&v1alpha1.ChaosScenario{}).
+		Build()
+	return NewReconciler(log.New(log.WithLevel("info")), c, scheme), c
+}
+
+func reconcile(t *testing.T, r *Reconciler, c ctrlclient.Client, cs *v1alpha1.ChaosScenario) {
+	t.Helper()
+	key := types.NamespacedName{Namespace: cs.Namespace, Name: cs.Name}
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.Equal(t, 30*time.Second, result.RequeueAfter)
+	require.NoError(t, c.Get(context.Background(), key, cs))
+}
+
+// startReplica serves the control API of a crashlooper replica on localhost
+// and returns a ready pod of cs answering on it.
+func startReplica(t *testing.T, cs *v1alpha1.ChaosScenario, name string) (*corev1.Pod, *client.Client) {
+	t.Helper()
+	logger := log.New(log.WithLevel("info"))
+	srv := httptest.NewServer(api.NewRouter(
+		logger,
+		api.WithCrash(crash.New(logger, 0)),
+		api.WithMemory(memory.New(logger, 0, 0, 0)),
+		api.WithFaults(chaos.NewController()),
+	))
+	t.Cleanup(srv.Close)
+
+	host, p, err := net.SplitHostPort(srv.Listener.Addr().String())
+	require.NoError(t, err)
+	port, err := strconv.Atoi(p)
+	require.NoError(t, err)
+	cs.Spec.Port = int32(port)
+
+	c, err := client.New(srv.URL)
+	require.NoError(t, err)
+
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: cs.Namespace, Labels: labels(cs)},
+		Status: corev1.PodStatus{
+			PodIP:      host,
+			Conditions: []corev1.PodCondition{{Type: corev1.PodReady, Status: corev1.ConditionTrue}},
+		},
+	}, c
+}
+
+func TestReconcile(t *testing.T) {
+	replicas := int32(3)
+	cs := &v1alpha1.ChaosScenario{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "chaos", Generation: 1},
+		Spec: v1alpha1.ChaosScenarioSpec{
+			Replicas:   &replicas,
+			CrashAfter: &metav1.Duration{Duration: 5 * time.Minute},
+			Scenario: &client.Scenario{
+				Name:  "latency",
+				Steps: []client.Step{{At: chaos.Duration(time.Minute), CancelCrash: true}},
+			},
+		},
+	}
+	r, c := newReconciler(t, cs)
+	reconcile(t, r, c, cs)
+
+	d := &appsv1.Deployment{}
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "chaos", Name: "checkout"}, d))
+	require.Equal(t, int32(3), *d.Spec.Replicas)
+	require.Equal(t, labels(cs), d.Spec.Selector.MatchLabels)
+	require.Equal(t, "checkout", d.OwnerReferences[0].Name)
+	require.Equal(t, []string{"--port", "3000", "--crash-after", "5m0s", "--scenario", "/etc/crashlooper/scenario.json"}, d.Spec.Template.Spec.Containers[0].Args)
+
+	cm := &corev1.ConfigMap{}
+	require.NoError(t, c.Get(context.Background(), types.NamespacedName{Namespace: "chaos", Name: "checkout-scenario"}, cm))
+	require.Equal(t, "checkout", cm.OwnerReferences[0].Name)
+	require.JSONEq(t, `{"name": "latency", "steps": [{"at": "1m0s", "cancel_crash": true}]}`, cm.Data["scenario.json"])
+
+	require.Equal(t, int64(1), cs.Status.ObservedGeneration)
+	require.True(t, meta.IsStatusConditionTrue(cs.Status.Conditions, v1alpha1.ConditionSynced))
+
+	// the ConfigMap is deleted once the scenario is removed
+	cs.Spec.Scenario = nil
+	require.NoError(t, c.Update(context.Background(), cs))
+	reconcile(t, r, c, cs)
+	err := c.Get(context.Background(), types.NamespacedName{Namespace: "chaos", Name: "checkout-scenario"}, cm)
+	require.True(t, apierrors.IsNotFound(err))
+}
+
+func TestReconcile_LiveFaults(t *testing.T) {
+	cs := &v1alpha1.ChaosScenario{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "chaos"},
+		Spec: v1alpha1.ChaosScenarioSpec{
+			Faults: []chaos.Fault{{Kind: chaos.KindLatency, Probability: 1, Delay: chaos.Duration(10 * time.Millisecond)}},
+			Memory: &memory.Settings{Target: units.MiB, Increment: units.MiB, Interval: chaos.Duration(time.Hour)},
+		},
+	}
+	pod, replica := startReplica(t, cs, "checkout-7d9f")
+	notReady := pod.DeepCopy()
+	notReady.Name = "checkout-x2k1"
+	notReady.Status.Conditions = nil
+
+	r, c := newReconciler(t, cs, pod, notReady)
+	reconcile(t, r, c, cs)
+
+	faults, err := replica.Faults(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, cs.Spec.Faults, faults)
+	status, err := replica.Status(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, units.MiB, status.Memory.Target)
+
+	require.Equal(t, int32(1), cs.Status.SyncedReplicas)
+	require.Equal(t, []chaos.Kind{chaos.KindLatency}, cs.Status.Faults)
+	require.True(t, meta.IsStatusConditionTrue(cs.Status.Conditions, v1alpha1.ConditionSynced))
+
+	// the faults removed from the spec are removed from the replicas, the
+	// faults applied by others are left as is
+	_, err = replica.SetFault(context.Background(), chaos.Fault{Kind: chaos.KindDisconnect, Probability: 0.1})
+	require.NoError(t, err)
+	cs.Spec.Faults = []chaos.Fault{{Kind: chaos.KindError, Probability: 1, StatusCode: 503}}
+	require.NoError(t, c.Update(context.Background(), cs))
+	reconcile(t, r, c, cs)
+
+	faults, err = replica.Faults(context.Background())
+	require.NoError(t, err)
+	kinds := []chaos.Kind{}
+	for _, f := range faults {
+		kinds = append(kinds, f.Kind)
+	}
+	require.ElementsMatch(t, []chaos.Kind{chaos.KindDisconnect, chaos.KindError}, kinds)
+	require.Equal(t, []chaos.Kind{chaos.KindError}, cs.Status.Faults)
+}
+
+func TestReconcile_SyncFailed(t *testing.T) {
+	cs := &v1alpha1.ChaosScenario{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "chaos"},
+		Spec: v1alpha1.ChaosScenarioSpec{
+			Faults: []chaos.Fault{{Kind: chaos.KindLatency, Probability: 1, Delay: chaos.Duration(10 * time.Millisecond)}},
+		},
+		Status: v1alpha1.ChaosScenarioStatus{Faults: []chaos.Kind{chaos.KindError}},
+	}
+	pod, _ := startReplica(t, cs, "checkout-7d9f")
+	// nothing listens on the port of the replica anymore
+	cs.Spec.Port = 1
+
+	r, c := newReconciler(t, cs, pod)
+	reconcile(t, r, c, cs)
+
+	require.Equal(t, int32(0), cs.Status.SyncedReplicas)
+	cond := meta.FindStatusCondition(cs.Status.Conditions, v1alpha1.ConditionSynced)
+	require.NotNil(t, cond)
+	require.Equal(t, metav1.ConditionFalse, cond.Status)
+	require.Equal(t, "SyncFailed", cond.Reason)
+	// the removed kinds are kept until every replica is synced
+	require.Equal(t, []chaos.Kind{chaos.KindLatency, chaos.KindError}, cs.Status.Faults)
+}
+
+func TestReconcile_Invalid(t *testing.T) {
+	cs := &v1alpha1.ChaosScenario{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "chaos"},
+		Spec: v1alpha1.ChaosScenarioSpec{
+			Faults: []chaos.Fault{{Kind: chaos.KindLatency, Probability: 2}},
+		},
+	}
+	r, c := newReconciler(t, cs)
+
+	key := types.NamespacedName{Namespace: "chaos", Name: "checkout"}
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: key})
+	require.NoError(t, err)
+	require.Zero(t, result.RequeueAfter)
+
+	require.NoError(t, c.Get(context.Background(), key, cs))
+	cond := meta.FindStatusCondition(cs.Status.Conditions, v1alpha1.ConditionSynced)
+	require.NotNil(t, cond)
+	require.Equal(t, "Invalid", cond.Reason)
+
+	err = c.Get(context.Background(), key, &appsv1.Deployment{})
+	require.True(t, apierrors.IsNotFound(err))
+}
+
+func TestReconcile_NotFound(t *testing.T) {
+	r, _ := newReconciler(t)
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "chaos", Name: "missing"}})
+	require.NoError(t, err)
+	require.Zero(t, result)
+}
+
+func TestReconciler_Token(t *testing.T) {
+	cs := &v1alpha1.ChaosScenario{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Namespace: "chaos"},
+		Spec:       v1alpha1.ChaosScenarioSpec{AuthSecretName: "crashlooper-token"},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: "crashlooper-token", Namespace: "chaos"},
+		Data:       map[string][]byte{"token": []byte("secret")},
+	}
+
+	r, _ := newReconciler(t, secret)
+	token, err := r.token(context.Background(), cs)
+	require.NoError(t, err)
+	require.Equal(t, "secret", token)
+
+	cs.Spec.AuthSecretName = "missing"
+	_, err = r.token(context.Background(), cs)
+	require.Error(t, err)
+
+	token, err = r.token(context.Background(), &v1alpha1.ChaosScenario{})
+	require.NoError(t, err)
+	require.Empty(t, token)
+}
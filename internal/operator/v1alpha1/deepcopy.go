@@ -0,0 +1,135 @@
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
+	"github.com/pixelfactoryio/crashlooper/internal/client"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// DeepCopyInto copies s into out.
+func (s *ChaosScenario) DeepCopyInto(out *ChaosScenario) {
+	*out = *s
+	out.TypeMeta = s.TypeMeta
+	s.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	s.Spec.DeepCopyInto(&out.Spec)
+	s.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy returns a copy of s.
+func (s *ChaosScenario) DeepCopy() *ChaosScenario {
+	if s == nil {
+		return nil
+	}
+	out := new(ChaosScenario)
+	s.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (s *ChaosScenario) DeepCopyObject() runtime.Object {
+	return s.DeepCopy()
+}
+
+// DeepCopyInto copies l into out.
+func (l *ChaosScenarioList) DeepCopyInto(out *ChaosScenarioList) {
+	*out = *l
+	out.TypeMeta = l.TypeMeta
+	l.ListMeta.DeepCopyInto(&out.ListMeta)
+	if l.Items != nil {
+		out.Items = make([]ChaosScenario, len(l.Items))
+		for i := range l.Items {
+			l.Items[i].DeepCopyInto(&out.Items[i])
+		}
+	}
+}
+
+// DeepCopy returns a copy of l.
+func (l *ChaosScenarioList) DeepCopy() *ChaosScenarioList {
+	if l == nil {
+		return nil
+	}
+	out := new(ChaosScenarioList)
+	l.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject implements runtime.Object.
+func (l *ChaosScenarioList) DeepCopyObject() runtime.Object {
+	return l.DeepCopy()
+}
+
+// DeepCopyInto copies s into out.
+func (s *ChaosScenarioSpec) DeepCopyInto(out *ChaosScenarioSpec) {
+	*out = *s
+	if s.Replicas != nil {
+		out.Replicas = new(int32)
+		*out.Replicas = *s.Replicas
+	}
+	if s.CrashAfter != nil {
+		out.CrashAfter = s.CrashAfter.DeepCopy()
+	}
+	if s.Args != nil {
+		out.Args = append([]string(nil), s.Args...)
+	}
+	if s.Scenario != nil {
+		out.Scenario = copyScenario(s.Scenario)
+	}
+	if s.Faults != nil {
+		out.Faults = append([]chaos.Fault(nil), s.Faults...)
+	}
+	if s.Memory != nil {
+		out.Memory = new(memory.Settings)
+		*out.Memory = *s.Memory
+	}
+}
+
+// DeepCopyInto copies s into out.
+func (s *ChaosScenarioStatus) DeepCopyInto(out *ChaosScenarioStatus) {
+	*out = *s
+	if s.Faults != nil {
+		out.Faults = append([]chaos.Kind(nil), s.Faults...)
+	}
+	if s.Conditions != nil {
+		out.Conditions = make([]metav1.Condition, len(s.Conditions))
+		for i := range s.Conditions {
+			s.Conditions[i].DeepCopyInto(&out.Conditions[i])
+		}
+	}
+}
+
+// copyScenario returns a copy of s, the actions of its steps included.
+func copyScenario(s *client.Scenario) *client.Scenario {
+	out := &client.Scenario{Name: s.Name}
+	if s.Steps == nil {
+		return out
+	}
+	out.Steps = make([]client.Step, len(s.Steps))
+	for i, step := range s.Steps {
+		out.Steps[i] = step
+		if step.Fault != nil {
+			f := *step.Fault
+			out.Steps[i].Fault = &f
+		}
+		if step.Memory != nil {
+			m := *step.Memory
+			out.Steps[i].Memory = &m
+		}
+		if step.TLS != nil {
+			t := *step.TLS
+			out.Steps[i].TLS = &t
+		}
+		if step.Crash != nil {
+			c := handlers.CrashRequest{After: step.Crash.After}
+			if step.Crash.ExitCode != nil {
+				code := *step.Crash.ExitCode
+				c.ExitCode = &code
+			}
+			out.Steps[i].Crash = &c
+		}
+	}
+	return out
+}
@@ -0,0 +1,25 @@
+// Package v1alpha1 defines the ChaosScenario custom resource reconciled by
+// the crashlooper operator.
+package v1alpha1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// GroupVersion is the API group and version of the resources.
+var GroupVersion = schema.GroupVersion{Group: "crashlooper.pixelfactory.io", Version: "v1alpha1"}
+
+var (
+	// SchemeBuilder registers the resources to a scheme.
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	// AddToScheme adds the resources to a scheme.
+	AddToScheme = SchemeBuilder.AddToScheme
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(GroupVersion, &ChaosScenario{}, &ChaosScenarioList{})
+	metav1.AddToGroupVersion(scheme, GroupVersion)
+	return nil
+}
@@ -0,0 +1,120 @@
+package v1alpha1
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pixelfactoryio/crashlooper/internal/client"
+	"github.com/pixelfactoryio/crashlooper/internal/services/memory"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// ConditionSynced reports whether the running replicas apply the live
+// faults and memory target of the spec.
+const ConditionSynced = "Synced"
+
+// ChaosScenario runs a crashlooper Deployment, its scenario and its faults.
+type ChaosScenario struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ChaosScenarioSpec   `json:"spec,omitempty"`
+	Status ChaosScenarioStatus `json:"status,omitempty"`
+}
+
+// ChaosScenarioSpec is the desired crashlooper Deployment. The faults and the
+// memory target are pushed live through the control API of the replicas,
+// the other fields roll the Deployment out.
+type ChaosScenarioSpec struct {
+	// Replicas defaults to 1.
+	Replicas *int32 `json:"replicas,omitempty"`
+	// Image defaults to pixelfactory/crashlooper:beta.
+	Image string `json:"image,omitempty"`
+	// Port serves the application routes and the control API, 3000 by default.
+	Port int32 `json:"port,omitempty"`
+	// CrashAfter crashes every replica after the given delay from its start.
+	CrashAfter *metav1.Duration `json:"crashAfter,omitempty"`
+	// Args are added to the command line of crashlooper. They must not move
+	// the control API or the health checks away from Port.
+	Args []string `json:"args,omitempty"`
+	// AuthSecretName names the Secret whose token key authenticates the
+	// requests to the control API.
+	AuthSecretName string `json:"authSecretName,omitempty"`
+
+	// Scenario is run by every replica from its start, it is stored in a
+	// ConfigMap mounted in the replicas.
+	Scenario *client.Scenario `json:"scenario,omitempty"`
+	// Faults are applied to the running replicas.
+	Faults []chaos.Fault `json:"faults,omitempty"`
+	// Memory is the memory usage target of the running replicas.
+	Memory *memory.Settings `json:"memory,omitempty"`
+}
+
+// portArgs are the flags moving the routes the operator reaches on Port, the
+// control API it syncs and the health checks of the readiness probe.
+var portArgs = []string{"--port", "--admin-port", "--probe-port"}
+
+// Validate returns an error if the spec is not usable.
+func (s ChaosScenarioSpec) Validate() error {
+	if s.Replicas != nil && *s.Replicas < 0 {
+		return fmt.Errorf("replicas must not be negative")
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		return fmt.Errorf("invalid port %d", s.Port)
+	}
+	if s.CrashAfter != nil && s.CrashAfter.Duration < 0 {
+		return fmt.Errorf("crashAfter must not be negative")
+	}
+	for _, arg := range s.Args {
+		for _, flag := range portArgs {
+			if arg == flag || strings.HasPrefix(arg, flag+"=") {
+				return fmt.Errorf("args must not set %s, the operator reaches the replicas on port", flag)
+			}
+		}
+	}
+
+	if s.Scenario != nil {
+		if len(s.Scenario.Steps) == 0 {
+			return fmt.Errorf("scenario has no steps")
+		}
+		for i, step := range s.Scenario.Steps {
+			if err := step.Validate(); err != nil {
+				return fmt.Errorf("invalid scenario step %d: %w", i, err)
+			}
+		}
+	}
+
+	kinds := make(map[chaos.Kind]bool)
+	for _, f := range s.Faults {
+		if err := f.Validate(); err != nil {
+			return fmt.Errorf("invalid %s fault: %w", f.Kind, err)
+		}
+		if kinds[f.Kind] {
+			return fmt.Errorf("duplicate %s fault", f.Kind)
+		}
+		kinds[f.Kind] = true
+	}
+	return nil
+}
+
+// ChaosScenarioStatus is the observed state of a ChaosScenario.
+type ChaosScenarioStatus struct {
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+	ReadyReplicas      int32 `json:"readyReplicas"`
+	// SyncedReplicas are the running replicas applying the live faults and
+	// memory target of the spec.
+	SyncedReplicas int32 `json:"syncedReplicas"`
+	// Faults are the kinds of the faults applied to the replicas, the
+	// faults removed from the spec are removed from the replicas.
+	Faults     []chaos.Kind       `json:"faults,omitempty"`
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// ChaosScenarioList is a list of ChaosScenario.
+type ChaosScenarioList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ChaosScenario `json:"items"`
+}
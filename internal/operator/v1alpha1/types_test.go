@@ -0,0 +1,88 @@
+package v1alpha1
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	"github.com/pixelfactoryio/crashlooper/internal/api/handlers"
+	"github.com/pixelfactoryio/crashlooper/internal/client"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+func TestChaosScenarioSpec_Validate(t *testing.T) {
+	latency := chaos.Fault{Kind: chaos.KindLatency, Probability: 1, Delay: chaos.Duration(time.Second)}
+	negative := int32(-1)
+
+	tests := []struct {
+		name    string
+		spec    ChaosScenarioSpec
+		wantErr bool
+	}{
+		{"empty", ChaosScenarioSpec{}, false},
+		{"faults", ChaosScenarioSpec{Faults: []chaos.Fault{latency}}, false},
+		{"negative replicas", ChaosScenarioSpec{Replicas: &negative}, true},
+		{"invalid port", ChaosScenarioSpec{Port: 70000}, true},
+		{"negative crash", ChaosScenarioSpec{CrashAfter: &metav1.Duration{Duration: -time.Second}}, true},
+		{"args", ChaosScenarioSpec{Args: []string{"--tls-port", "3443", "--log-level=debug"}}, false},
+		{"port args", ChaosScenarioSpec{Args: []string{"--port", "8080"}}, true},
+		{"admin port args", ChaosScenarioSpec{Args: []string{"--admin-port=8080"}}, true},
+		{"probe port args", ChaosScenarioSpec{Args: []string{"--probe-port", "8081"}}, true},
+		{"invalid fault", ChaosScenarioSpec{Faults: []chaos.Fault{{Kind: chaos.KindLatency, Probability: 1}}}, true},
+		{"duplicate fault", ChaosScenarioSpec{Faults: []chaos.Fault{latency, latency}}, true},
+		{"empty scenario", ChaosScenarioSpec{Scenario: &client.Scenario{Name: "empty"}}, true},
+		{"invalid step", ChaosScenarioSpec{Scenario: &client.Scenario{Steps: []client.Step{{}}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestChaosScenario_DeepCopy(t *testing.T) {
+	replicas := int32(2)
+	exitCode := 137
+	cs := &ChaosScenario{
+		ObjectMeta: metav1.ObjectMeta{Name: "checkout", Labels: map[string]string{"team": "payments"}},
+		Spec: ChaosScenarioSpec{
+			Replicas: &replicas,
+			Args:     []string{"--log-level", "debug"},
+			Faults:   []chaos.Fault{{Kind: chaos.KindError, Probability: 1, StatusCode: 503}},
+			Scenario: &client.Scenario{Name: "crash", Steps: []client.Step{
+				{Crash: &handlers.CrashRequest{ExitCode: &exitCode}},
+			}},
+		},
+		Status: ChaosScenarioStatus{
+			Faults:     []chaos.Kind{chaos.KindError},
+			Conditions: []metav1.Condition{{Type: ConditionSynced, Status: metav1.ConditionTrue}},
+		},
+	}
+
+	out := cs.DeepCopyObject().(*ChaosScenario)
+	require.Equal(t, cs, out)
+
+	*out.Spec.Replicas = 3
+	out.Labels["team"] = "search"
+	out.Spec.Args[0] = "--port"
+	out.Spec.Faults[0].StatusCode = 500
+	*out.Spec.Scenario.Steps[0].Crash.ExitCode = 1
+	out.Status.Conditions[0].Status = metav1.ConditionFalse
+	require.Equal(t, int32(2), *cs.Spec.Replicas)
+	require.Equal(t, "payments", cs.Labels["team"])
+	require.Equal(t, "--log-level", cs.Spec.Args[0])
+	require.Equal(t, 503, cs.Spec.Faults[0].StatusCode)
+	require.Equal(t, 137, *cs.Spec.Scenario.Steps[0].Crash.ExitCode)
+	require.Equal(t, metav1.ConditionTrue, cs.Status.Conditions[0].Status)
+
+	list := &ChaosScenarioList{Items: []ChaosScenario{*cs}}
+	require.Equal(t, list, list.DeepCopyObject())
+}
@@ -0,0 +1,125 @@
+// Package podinfo reads the identity of the pod running crashlooper from the
+// Kubernetes downward API, exposed as environment variables or volume files.
+package podinfo
+
+import (
+	"bufio"
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultDir is the conventional mount point of the downward API volume.
+const DefaultDir = "/etc/podinfo"
+
+// Info is the identity of the pod, every field may be empty outside of Kubernetes.
+type Info struct {
+	Name      string            `json:"name,omitempty"`
+	Namespace string            `json:"namespace,omitempty"`
+	UID       string            `json:"uid,omitempty"`
+	Node      string            `json:"node,omitempty"`
+	IP        string            `json:"ip,omitempty"`
+	Hostname  string            `json:"hostname,omitempty"`
+	Labels    map[string]string `json:"labels,omitempty"`
+}
+
+// sources are the environment variable and the volume file of each field.
+var sources = []struct {
+	env  string
+	file string
+	get  func(*Info) *string
+}{
+	{"POD_NAME", "name", func(i *Info) *string { return &i.Name }},
+	{"POD_NAMESPACE", "namespace", func(i *Info) *string { return &i.Namespace }},
+	{"POD_UID", "uid", func(i *Info) *string { return &i.UID }},
+	{"NODE_NAME", "node", func(i *Info) *string { return &i.Node }},
+	{"POD_IP", "ip", func(i *Info) *string { return &i.IP }},
+}
+
+// Load returns the identity of the pod. The POD_NAME, POD_NAMESPACE, POD_UID,
+// NODE_NAME and POD_IP environment variables take precedence over the name,
+// namespace, uid, node and ip files of dir, and the labels are read from its labels
+// file. Missing files are ignored.
+func Load(dir string) (*Info, error) {
+	info := &Info{}
+	info.Hostname, _ = os.Hostname()
+
+	for _, s := range sources {
+		v := os.Getenv(s.env)
+		if v == "" {
+			b, err := readFile(dir, s.file)
+			if err != nil {
+				return nil, err
+			}
+			v = strings.TrimSpace(string(b))
+		}
+		*s.get(info) = v
+	}
+
+	b, err := readFile(dir, "labels")
+	if err != nil {
+		return nil, err
+	}
+	if info.Labels, err = parseLabels(b); err != nil {
+		return nil, errors.Wrap(err, "invalid labels file")
+	}
+
+	return info, nil
+}
+
+// String returns namespace/name, or the hostname outside of Kubernetes.
+func (i *Info) String() string {
+	switch {
+	case i.Name != "" && i.Namespace != "":
+		return i.Namespace + "/" + i.Name
+	case i.Name != "":
+		return i.Name
+	default:
+		return i.Hostname
+	}
+}
+
+func readFile(dir, name string) ([]byte, error) {
+	if dir == "" {
+		return nil, nil
+	}
+
+	b, err := os.ReadFile(filepath.Join(dir, name))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrapf(err, "unable to read %s", name)
+	}
+	return b, nil
+}
+
+// parseLabels parses the key="value" lines of a downward API labels file.
+func parseLabels(b []byte) (map[string]string, error) {
+	var labels map[string]string
+
+	scanner := bufio.NewScanner(bytes.NewReader(b))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+
+		key, quoted, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, errors.Errorf("missing value in %q", line)
+		}
+		value, err := strconv.Unquote(quoted)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid value of %s", key)
+		}
+
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[key] = value
+	}
+
+	return labels, scanner.Err()
+}
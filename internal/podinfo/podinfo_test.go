@@ -0,0 +1,80 @@
+package podinfo
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func writeFiles(t *testing.T, files map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600))
+	}
+	return dir
+}
+
+func TestLoad_Env(t *testing.T) {
+	t.Setenv("POD_NAME", "crashlooper-7d9f")
+	t.Setenv("POD_NAMESPACE", "crashlooper")
+	t.Setenv("POD_UID", "0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0")
+	t.Setenv("NODE_NAME", "node-1")
+	t.Setenv("POD_IP", "10.0.0.12")
+
+	info, err := Load("")
+	require.NoError(t, err)
+	require.Equal(t, "crashlooper-7d9f", info.Name)
+	require.Equal(t, "crashlooper", info.Namespace)
+	require.Equal(t, "0f1e2d3c-4b5a-6978-8796-a5b4c3d2e1f0", info.UID)
+	require.Equal(t, "node-1", info.Node)
+	require.Equal(t, "10.0.0.12", info.IP)
+	require.NotEmpty(t, info.Hostname)
+	require.Nil(t, info.Labels)
+	require.Equal(t, "crashlooper/crashlooper-7d9f", info.String())
+}
+
+func TestLoad_Files(t *testing.T) {
+	t.Setenv("POD_NAME", "from-env")
+	t.Setenv("POD_NAMESPACE", "")
+	t.Setenv("POD_UID", "")
+	t.Setenv("NODE_NAME", "")
+	t.Setenv("POD_IP", "")
+
+	dir := writeFiles(t, map[string]string{
+		"name":      "from-file",
+		"namespace": "crashlooper\n",
+		"uid":       "0f1e2d3c",
+		"node":      "node-2",
+		"labels":    "app=\"crashlooper\"\npod-template-hash=\"7d9f\"\n",
+	})
+
+	info, err := Load(dir)
+	require.NoError(t, err)
+	require.Equal(t, "from-env", info.Name)
+	require.Equal(t, "crashlooper", info.Namespace)
+	require.Equal(t, "0f1e2d3c", info.UID)
+	require.Equal(t, "node-2", info.Node)
+	require.Empty(t, info.IP)
+	require.Equal(t, map[string]string{"app": "crashlooper", "pod-template-hash": "7d9f"}, info.Labels)
+}
+
+func TestLoad_InvalidLabels(t *testing.T) {
+	dir := writeFiles(t, map[string]string{"labels": "app=crashlooper\n"})
+
+	_, err := Load(dir)
+	require.Error(t, err)
+
+	dir = writeFiles(t, map[string]string{"labels": "app\n"})
+	_, err = Load(dir)
+	require.Error(t, err)
+}
+
+func TestInfo_String(t *testing.T) {
+	require.Equal(t, "host", (&Info{Hostname: "host"}).String())
+	require.Equal(t, "pod", (&Info{Name: "pod", Hostname: "host"}).String())
+	require.Equal(t, "ns/pod", (&Info{Name: "pod", Namespace: "ns", Hostname: "host"}).String())
+}
@@ -0,0 +1,49 @@
+package rota
+
+import (
+	"context"
+	"net"
+
+	"github.com/pkg/errors"
+)
+
+// Discovery returns the addresses of the replicas taking part in the rota.
+type Discovery interface {
+	Peers(ctx context.Context) ([]string, error)
+}
+
+type static []string
+
+// Static discovers the given host:port addresses, for replicas whose
+// addresses are known in advance, such as local processes.
+func Static(addrs ...string) Discovery {
+	return static(addrs)
+}
+
+func (s static) Peers(context.Context) ([]string, error) {
+	return s, nil
+}
+
+type dns struct {
+	name     string
+	port     string
+	resolver *net.Resolver
+}
+
+// DNS discovers the replicas by resolving name, such as a headless Service
+// resolving to the addresses of its pods, all listening on port.
+func DNS(name, port string) Discovery {
+	return &dns{name: name, port: port, resolver: net.DefaultResolver}
+}
+
+func (d *dns) Peers(ctx context.Context) ([]string, error) {
+	hosts, err := d.resolver.LookupHost(ctx, d.name)
+	if err != nil {
+		return nil, errors.Wrapf(err, "unable to resolve %s", d.name)
+	}
+	addrs := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		addrs = append(addrs, net.JoinHostPort(host, d.port))
+	}
+	return addrs, nil
+}
@@ -0,0 +1,23 @@
+package rota
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStatic(t *testing.T) {
+	peers, err := Static("127.0.0.1:3000", "127.0.0.1:3001").Peers(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []string{"127.0.0.1:3000", "127.0.0.1:3001"}, peers)
+}
+
+func TestDNS(t *testing.T) {
+	peers, err := DNS("localhost", "3000").Peers(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, peers, "127.0.0.1:3000")
+
+	_, err = DNS("crashlooper.invalid", "3000").Peers(context.Background())
+	require.Error(t, err)
+}
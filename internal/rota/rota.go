@@ -0,0 +1,284 @@
+// Package rota crashes the replicas of a deployment in turn without the
+// Kubernetes API. The replicas discover each other through DNS or a static
+// list of addresses, learn the identity of each other from their rota API,
+// and crash one at a time, one every interval, in the order of their
+// identities.
+//
+// The replicas agree on the turns without exchanging them: the turn starting
+// at the n-th multiple of the interval since the Unix epoch goes to the n-th
+// member, modulo the number of members. Their clocks are expected to be
+// synchronized.
+package rota
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// Path is the path of the rota API the replicas query each other on.
+const Path = "/api/rota"
+
+// exitCode is the exit code of the crashes of the rota.
+const exitCode = 1
+
+// Turn is a turn of the rota.
+type Turn struct {
+	At time.Time `json:"at"`
+	ID string    `json:"id"`
+}
+
+// View is the rota as seen by a replica.
+type View struct {
+	// ID identifies the replica.
+	ID       string         `json:"id"`
+	Interval chaos.Duration `json:"interval"`
+	// Members are the identities of the replicas taking part in the rota,
+	// in the order of their turns.
+	Members []string `json:"members"`
+	Next    Turn     `json:"next"`
+}
+
+// Crasher crashes the process.
+type Crasher interface {
+	Schedule(ctx context.Context, after time.Duration, exitCode int) (crash.Schedule, error)
+}
+
+// Rota crashes its replica on its turns.
+type Rota struct {
+	logger     *log.DefaultLogger
+	id         string
+	interval   time.Duration
+	discovery  Discovery
+	crasher    Crasher
+	httpClient *http.Client
+	// refresh is the interval between two discoveries of the members.
+	refresh time.Duration
+	// ttl is the time a member remains in the rota after it was last seen,
+	// so that a replica restarting after its turn keeps its place.
+	ttl time.Duration
+
+	mu   sync.Mutex
+	seen map[string]time.Time
+
+	done     chan struct{}
+	stopOnce sync.Once
+}
+
+// Option configures a Rota.
+type Option func(*Rota)
+
+// WithHTTPClient sets the HTTP client used to query the other replicas.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(r *Rota) {
+		r.httpClient = httpClient
+	}
+}
+
+// New returns a Rota crashing the replica identified by id with crasher,
+// in turn with the replicas found by discovery, one every interval.
+func New(logger *log.DefaultLogger, id string, interval time.Duration, discovery Discovery, crasher Crasher, opts ...Option) (*Rota, error) {
+	if id == "" {
+		return nil, errors.New("the replica identity is required")
+	}
+	if interval <= 0 {
+		return nil, errors.New("the rota interval must be positive")
+	}
+
+	logger.Info(
+		"Creating crash rota",
+		fields.String("id", id),
+		fields.Duration("interval", interval),
+	)
+
+	refresh := interval / 4
+	r := &Rota{
+		logger:     logger,
+		id:         id,
+		interval:   interval,
+		discovery:  discovery,
+		crasher:    crasher,
+		httpClient: &http.Client{Timeout: refresh},
+		refresh:    refresh,
+		ttl:        2 * interval,
+		seen:       make(map[string]time.Time),
+		done:       make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r, nil
+}
+
+// Start discovers the members and crashes the replica on its turns, until
+// Stop is called.
+func (r *Rota) Start() {
+	r.discover()
+	go func() {
+		ticker := time.NewTicker(r.refresh)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-r.done:
+				return
+			case <-ticker.C:
+				r.discover()
+			}
+		}
+	}()
+
+	for {
+		at := r.nextTurn(time.Now())
+		timer := time.NewTimer(time.Until(at))
+		select {
+		case <-r.done:
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		r.turn(at)
+	}
+}
+
+// Stop stops the rota.
+func (r *Rota) Stop() {
+	r.stopOnce.Do(func() {
+		close(r.done)
+	})
+}
+
+// View returns the rota as seen by the replica.
+func (r *Rota) View() View {
+	now := time.Now()
+	members := r.members(now)
+	at := r.nextTurn(now)
+	return View{
+		ID:       r.id,
+		Interval: chaos.Duration(r.interval),
+		Members:  members,
+		Next:     Turn{At: at, ID: r.owner(at, members)},
+	}
+}
+
+// turn crashes the replica if the turn starting at at is its own.
+func (r *Rota) turn(at time.Time) {
+	members := r.members(time.Now())
+	owner := r.owner(at, members)
+	if owner != r.id {
+		r.logger.Debug("Rota turn of another replica", fields.String("id", owner))
+		return
+	}
+
+	r.logger.Info("Rota turn", fields.Int("members", len(members)))
+	ctx := events.WithTrigger(context.Background(), events.TriggerRota)
+	if _, err := r.crasher.Schedule(ctx, 0, exitCode); err != nil {
+		r.logger.Error("Unable to crash for the rota turn", fields.Error(err))
+	}
+}
+
+// nextTurn returns the start of the first turn after now.
+func (r *Rota) nextTurn(now time.Time) time.Time {
+	slot := now.UnixNano() / int64(r.interval)
+	return time.Unix(0, (slot+1)*int64(r.interval))
+}
+
+// owner returns the member whose turn starts at at.
+func (r *Rota) owner(at time.Time, members []string) string {
+	slot := at.UnixNano() / int64(r.interval)
+	return members[slot%int64(len(members))]
+}
+
+// members returns the replica and the members seen within the TTL, sorted.
+func (r *Rota) members(now time.Time) []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	members := []string{r.id}
+	for id, seen := range r.seen {
+		if now.Sub(seen) > r.ttl {
+			delete(r.seen, id)
+			continue
+		}
+		if id != r.id {
+			members = append(members, id)
+		}
+	}
+	sort.Strings(members)
+	return members
+}
+
+// discover queries the rota API of the discovered replicas and records the
+// members taking part in the same rota.
+func (r *Rota) discover() {
+	ctx, cancel := context.WithTimeout(context.Background(), r.refresh)
+	defer cancel()
+
+	addrs, err := r.discovery.Peers(ctx)
+	if err != nil {
+		r.logger.Warn("Unable to discover the rota members", fields.Error(err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, addr := range addrs {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			view, err := r.query(ctx, addr)
+			if err != nil {
+				// replicas are expected to be unreachable while restarting
+				r.logger.Debug("Unable to query rota member", fields.String("addr", addr), fields.Error(err))
+				return
+			}
+			if time.Duration(view.Interval) != r.interval {
+				r.logger.Warn(
+					"Ignoring replica of another rota",
+					fields.String("addr", addr),
+					fields.Duration("interval", time.Duration(view.Interval)),
+				)
+				return
+			}
+
+			r.mu.Lock()
+			r.seen[view.ID] = time.Now()
+			r.mu.Unlock()
+		}(addr)
+	}
+	wg.Wait()
+}
+
+// query returns the view of the replica listening on addr.
+func (r *Rota) query(ctx context.Context, addr string) (View, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "http://"+addr+Path, nil)
+	if err != nil {
+		return View{}, err
+	}
+	resp, err := r.httpClient.Do(req)
+	if err != nil {
+		return View{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return View{}, errors.Errorf("unexpected status %s", resp.Status)
+	}
+	var view View
+	if err := json.NewDecoder(resp.Body).Decode(&view); err != nil {
+		return View{}, errors.Wrap(err, "invalid rota view")
+	}
+	if view.ID == "" {
+		return View{}, errors.New("rota view without identity")
+	}
+	return view, nil
+}
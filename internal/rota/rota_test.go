@@ -0,0 +1,164 @@
+package rota
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/services/crash"
+)
+
+// crashes records the crashes of the replicas of a test.
+type crashes struct {
+	mu   sync.Mutex
+	list []turnCrash
+}
+
+type turnCrash struct {
+	id      string
+	at      time.Time
+	trigger events.Trigger
+}
+
+func (c *crashes) get() []turnCrash {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]turnCrash(nil), c.list...)
+}
+
+type fakeCrasher struct {
+	id      string
+	crashes *crashes
+}
+
+func (f *fakeCrasher) Schedule(ctx context.Context, after time.Duration, exitCode int) (crash.Schedule, error) {
+	f.crashes.mu.Lock()
+	defer f.crashes.mu.Unlock()
+	f.crashes.list = append(f.crashes.list, turnCrash{f.id, time.Now(), events.TriggerFrom(ctx)})
+	return crash.Schedule{Scheduled: true, At: time.Now().Add(after), ExitCode: exitCode}, nil
+}
+
+// serve serves the rota API of r.
+func serve(r **Rota) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path != Path {
+			http.NotFound(w, req)
+			return
+		}
+		_ = json.NewEncoder(w).Encode((*r).View())
+	}))
+}
+
+func TestNew(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+
+	_, err := New(logger, "", time.Minute, Static(), &fakeCrasher{})
+	require.Error(t, err)
+	_, err = New(logger, "a", 0, Static(), &fakeCrasher{})
+	require.Error(t, err)
+}
+
+func TestRota(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	interval := 300 * time.Millisecond
+	ids := []string{"c", "a", "b"}
+	got := &crashes{}
+
+	rotas := make([]*Rota, len(ids))
+	var addrs []string
+	for i := range ids {
+		srv := serve(&rotas[i])
+		defer srv.Close()
+		addrs = append(addrs, strings.TrimPrefix(srv.URL, "http://"))
+	}
+	for i, id := range ids {
+		r, err := New(logger, id, interval, Static(addrs...), &fakeCrasher{id, got})
+		require.NoError(t, err)
+		rotas[i] = r
+	}
+	for _, r := range rotas {
+		go r.Start()
+		defer r.Stop()
+	}
+
+	require.Eventually(t, func() bool {
+		return len(got.get()) >= 4
+	}, 4*time.Second, 10*time.Millisecond)
+
+	members := []string{"a", "b", "c"}
+	require.Equal(t, members, rotas[0].View().Members)
+
+	// a single replica crashes on each turn, in the order of the identities
+	slots := make(map[int64]string)
+	for _, c := range got.get() {
+		slot := c.at.UnixNano() / int64(interval)
+		require.NotContains(t, slots, slot)
+		slots[slot] = c.id
+		require.Equal(t, members[slot%3], c.id)
+		require.Equal(t, events.TriggerRota, c.trigger)
+	}
+}
+
+func TestRota_Members(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	r, err := New(logger, "b", time.Minute, Static(), &fakeCrasher{})
+	require.NoError(t, err)
+
+	now := time.Now()
+	r.seen["a"] = now.Add(-time.Minute)
+	r.seen["b"] = now
+	r.seen["c"] = now.Add(-3 * time.Minute)
+	require.Equal(t, []string{"a", "b"}, r.members(now))
+
+	// the members lost after the TTL leave the rota
+	require.Equal(t, []string{"b"}, r.members(now.Add(2*time.Minute)))
+}
+
+func TestRota_View(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	r, err := New(logger, "a", time.Minute, Static(), &fakeCrasher{})
+	require.NoError(t, err)
+
+	view := r.View()
+	require.Equal(t, "a", view.ID)
+	require.Equal(t, []string{"a"}, view.Members)
+	require.Equal(t, "a", view.Next.ID)
+	require.True(t, view.Next.At.After(time.Now()))
+	require.Zero(t, view.Next.At.UnixNano()%int64(time.Minute))
+}
+
+func TestRota_Discover(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+
+	var other *Rota
+	srv := serve(&other)
+	defer srv.Close()
+	other, err := New(logger, "other", 2*time.Minute, Static(), &fakeCrasher{})
+	require.NoError(t, err)
+
+	var same *Rota
+	sameSrv := serve(&same)
+	defer sameSrv.Close()
+	same, err = New(logger, "same", time.Minute, Static(), &fakeCrasher{})
+	require.NoError(t, err)
+
+	r, err := New(logger, "a", time.Minute, Static(
+		strings.TrimPrefix(srv.URL, "http://"),
+		strings.TrimPrefix(sameSrv.URL, "http://"),
+		"127.0.0.1:1",
+	), &fakeCrasher{})
+	require.NoError(t, err)
+	r.discover()
+
+	// the replicas of another rota and the unreachable ones are left out
+	require.Equal(t, []string{"a", "same"}, r.View().Members)
+}
@@ -0,0 +1,451 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// Mode is the kind of certificate served.
+type Mode string
+
+const (
+	// ModeValid serves the provided certificate, or one signed by the crashlooper CA.
+	ModeValid Mode = "valid"
+	// ModeExpired serves a certificate which expired yesterday.
+	ModeExpired Mode = "expired"
+	// ModeNotYetValid serves a certificate valid from tomorrow.
+	ModeNotYetValid Mode = "not-yet-valid"
+	// ModeWrongHost serves a certificate for a host other than the served ones.
+	ModeWrongHost Mode = "wrong-host"
+	// ModeSelfSigned serves a certificate not signed by the crashlooper CA.
+	ModeSelfSigned Mode = "self-signed"
+)
+
+// Modes lists the supported modes.
+var Modes = []Mode{ModeValid, ModeExpired, ModeNotYetValid, ModeWrongHost, ModeSelfSigned}
+
+// WrongHost is the only name of the certificates served in ModeWrongHost.
+const WrongHost = "wrong-host.crashlooper.invalid"
+
+const (
+	caValidity   = 10 * 365 * 24 * time.Hour
+	leafValidity = 365 * 24 * time.Hour
+)
+
+// Settings describes the certificate served.
+type Settings struct {
+	Mode Mode `json:"mode"`
+	// RotateInterval replaces the certificate on a schedule, 0 means never.
+	RotateInterval chaos.Duration `json:"rotate_interval,omitempty"`
+}
+
+// Validate returns an error if the settings are not usable.
+func (s Settings) Validate() error {
+	if s.RotateInterval < 0 {
+		return fmt.Errorf("rotate interval must not be negative")
+	}
+	for _, m := range Modes {
+		if s.Mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown mode %q", s.Mode)
+}
+
+// Status describes the certificate served.
+type Status struct {
+	Settings
+	Source    string    `json:"source"`
+	Serial    string    `json:"serial"`
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	Hosts     []string  `json:"hosts"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	RotatedAt time.Time `json:"rotated_at"`
+}
+
+type service struct {
+	logger   *log.DefaultLogger
+	events   events.Publisher
+	hosts    []string
+	certFile string
+	keyFile  string
+
+	caCert *x509.Certificate
+	caKey  *ecdsa.PrivateKey
+
+	mu        sync.Mutex
+	settings  Settings
+	cert      *tls.Certificate
+	source    string
+	modTime   time.Time
+	rotatedAt time.Time
+	reset     chan struct{}
+	fault     fault
+}
+
+// fault identifies the current settings in the events.
+type fault struct {
+	id      string
+	trigger events.Trigger
+}
+
+// Option configures the service.
+type Option func(*service)
+
+// WithFiles serves the PEM certificate and key of certFile and keyFile in
+// ModeValid. The files are reloaded when they change.
+func WithFiles(certFile, keyFile string) Option {
+	return func(s *service) {
+		s.certFile = certFile
+		s.keyFile = keyFile
+	}
+}
+
+// WithSettings sets the initial settings, ModeValid without rotation by default.
+func WithSettings(settings Settings) Option {
+	return func(s *service) {
+		s.settings = settings
+	}
+}
+
+// WithEvents publishes the certificate lifecycle events to p.
+func WithEvents(p events.Publisher) Option {
+	return func(s *service) {
+		s.events = p
+	}
+}
+
+// New returns a certificate manager serving hosts, which may be names or IP addresses.
+func New(logger *log.DefaultLogger, hosts []string, opts ...Option) (*service, error) {
+	logger.Info("Creating certificate manager", fields.Any("hosts", hosts))
+
+	s := &service{
+		logger:   logger,
+		hosts:    hosts,
+		settings: Settings{Mode: ModeValid},
+		reset:    make(chan struct{}, 1),
+		fault:    fault{id: events.NewFaultID(), trigger: events.TriggerFlag},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	if len(s.hosts) == 0 {
+		return nil, errors.New("at least one host is required")
+	}
+	if err := s.settings.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.createCA(); err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.renew(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Start rotates the certificate every rotate interval.
+func (s *service) Start() {
+	for {
+		s.mu.Lock()
+		interval := time.Duration(s.settings.RotateInterval)
+		s.mu.Unlock()
+
+		var (
+			timer *time.Timer
+			tick  <-chan time.Time
+		)
+		if interval > 0 {
+			timer = time.NewTimer(interval)
+			tick = timer.C
+		}
+
+		select {
+		case <-tick:
+			if err := s.Rotate(); err != nil {
+				s.logger.Error("Unable to rotate certificate", fields.Error(err))
+			}
+		case <-s.reset:
+			if timer != nil {
+				timer.Stop()
+			}
+		}
+	}
+}
+
+// GetCertificate returns the certificate to serve, it is meant to be used
+// as tls.Config.GetCertificate.
+func (s *service) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.settings.Mode == ModeValid && s.certFile != "" {
+		if info, err := os.Stat(s.certFile); err == nil && !info.ModTime().Equal(s.modTime) {
+			s.logger.Info("Reloading certificate", fields.String("file", s.certFile))
+			if err := s.renew(); err != nil {
+				// keep serving the previous certificate until the files are fixed
+				s.logger.Error("Unable to reload certificate", fields.Error(err))
+			}
+		}
+	}
+
+	return s.cert, nil
+}
+
+// Set replaces the settings and the certificate served. The events of the
+// new settings report the trigger of ctx.
+func (s *service) Set(ctx context.Context, settings Settings) error {
+	if err := settings.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	previous := s.settings
+	s.settings = settings
+	if err := s.renew(); err != nil {
+		s.settings = previous
+		return err
+	}
+	s.fault = fault{id: events.NewFaultID(), trigger: events.TriggerFrom(ctx)}
+
+	select {
+	case s.reset <- struct{}{}:
+	default:
+	}
+
+	s.logger.Info(
+		"Serving certificate",
+		fields.String("mode", string(settings.Mode)),
+		fields.Duration("rotate_interval", time.Duration(settings.RotateInterval)),
+	)
+	if settings.Mode == ModeValid {
+		if previous.Mode != ModeValid {
+			s.publish(events.TypeCancelled, "Serving a valid certificate")
+		}
+	} else {
+		s.publish(events.TypeTriggered, fmt.Sprintf("Serving a %s certificate", settings.Mode))
+	}
+	return nil
+}
+
+// Rotate replaces the certificate served by a new one of the same mode.
+func (s *service) Rotate() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.renew(); err != nil {
+		return err
+	}
+
+	s.logger.Info("Rotated certificate", fields.String("serial", s.cert.Leaf.SerialNumber.Text(16)))
+	s.publish(events.TypeProgress, "Certificate rotated")
+	return nil
+}
+
+// Status returns the settings and the certificate served.
+func (s *service) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	leaf := s.cert.Leaf
+	hosts := append([]string{}, leaf.DNSNames...)
+	for _, ip := range leaf.IPAddresses {
+		hosts = append(hosts, ip.String())
+	}
+
+	return Status{
+		Settings:  s.settings,
+		Source:    s.source,
+		Serial:    leaf.SerialNumber.Text(16),
+		Subject:   leaf.Subject.CommonName,
+		Issuer:    leaf.Issuer.CommonName,
+		Hosts:     hosts,
+		NotBefore: leaf.NotBefore,
+		NotAfter:  leaf.NotAfter,
+		RotatedAt: s.rotatedAt,
+	}
+}
+
+// CA returns the PEM certificate of the crashlooper CA, which signs the
+// generated certificates except in ModeSelfSigned.
+func (s *service) CA() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.caCert.Raw})
+}
+
+// renew replaces the certificate according to the settings, s.mu must be held.
+func (s *service) renew() error {
+	var (
+		cert *tls.Certificate
+		err  error
+	)
+	if s.settings.Mode == ModeValid && s.certFile != "" {
+		cert, err = s.load()
+		s.source = "file"
+	} else {
+		cert, err = s.generate(s.settings.Mode)
+		s.source = "generated"
+	}
+	if err != nil {
+		return err
+	}
+
+	s.cert = cert
+	s.rotatedAt = time.Now()
+	return nil
+}
+
+func (s *service) load() (*tls.Certificate, error) {
+	info, err := os.Stat(s.certFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to read certificate")
+	}
+
+	cert, err := tls.LoadX509KeyPair(s.certFile, s.keyFile)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to load certificate")
+	}
+	if cert.Leaf == nil {
+		if cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0]); err != nil {
+			return nil, errors.Wrap(err, "unable to parse certificate")
+		}
+	}
+
+	s.modTime = info.ModTime()
+	return &cert, nil
+}
+
+func (s *service) createCA() error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return errors.Wrap(err, "unable to generate CA key")
+	}
+
+	tmpl := &x509.Certificate{
+		SerialNumber:          newSerial(),
+		Subject:               pkix.Name{CommonName: "crashlooper CA", Organization: []string{"crashlooper"}},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(caValidity),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return errors.Wrap(err, "unable to create CA certificate")
+	}
+
+	s.caCert, err = x509.ParseCertificate(der)
+	s.caKey = key
+	return err
+}
+
+func (s *service) generate(mode Mode) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to generate key")
+	}
+
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: newSerial(),
+		Subject:      pkix.Name{CommonName: s.hosts[0], Organization: []string{"crashlooper"}},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+
+	hosts := s.hosts
+	switch mode {
+	case ModeExpired:
+		tmpl.NotBefore = now.Add(-48 * time.Hour)
+		tmpl.NotAfter = now.Add(-24 * time.Hour)
+	case ModeNotYetValid:
+		tmpl.NotBefore = now.Add(24 * time.Hour)
+		tmpl.NotAfter = now.Add(leafValidity)
+	case ModeWrongHost:
+		hosts = []string{WrongHost}
+		tmpl.Subject.CommonName = WrongHost
+	}
+	for _, h := range hosts {
+		if ip := net.ParseIP(h); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, h)
+		}
+	}
+
+	parent, signer := s.caCert, s.caKey
+	if mode == ModeSelfSigned {
+		parent, signer = tmpl, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, parent, &key.PublicKey, signer)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create certificate")
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to parse certificate")
+	}
+
+	chain := [][]byte{der}
+	if mode != ModeSelfSigned {
+		chain = append(chain, s.caCert.Raw)
+	}
+	return &tls.Certificate{Certificate: chain, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// publish publishes an event of the current settings. It must be called with mu held.
+func (s *service) publish(t events.Type, msg string) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(events.Event{
+		Type:    t,
+		Source:  "tls",
+		FaultID: s.fault.id,
+		Trigger: s.fault.trigger,
+		Message: msg,
+		Data: map[string]interface{}{
+			"mode":   s.settings.Mode,
+			"serial": s.cert.Leaf.SerialNumber.Text(16),
+		},
+	})
+}
+
+func newSerial() *big.Int {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	if err != nil {
+		return big.NewInt(time.Now().UnixNano())
+	}
+	return serial
+}
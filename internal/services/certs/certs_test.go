@@ -0,0 +1,182 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+func newTestService(t *testing.T, opts ...Option) *service {
+	t.Helper()
+
+	s, err := New(log.New(log.WithLevel("info")), []string{"localhost", "127.0.0.1"}, opts...)
+	require.NoError(t, err)
+	return s
+}
+
+// verify verifies the served certificate for host against the crashlooper CA.
+func verify(t *testing.T, s *service, host string) error {
+	t.Helper()
+
+	cert, err := s.GetCertificate(&tls.ClientHelloInfo{ServerName: host})
+	require.NoError(t, err)
+
+	roots := x509.NewCertPool()
+	require.True(t, roots.AppendCertsFromPEM(s.CA()))
+
+	_, err = cert.Leaf.Verify(x509.VerifyOptions{DNSName: host, Roots: roots})
+	return err
+}
+
+// writeKeyPair writes a certificate for host signed by itself and its key.
+func writeKeyPair(t *testing.T, dir string, host string) (string, string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "tls.crt"), filepath.Join(dir, "tls.key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestNew(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+
+	_, err := New(logger, nil)
+	require.Error(t, err)
+
+	_, err = New(logger, []string{"localhost"}, WithSettings(Settings{Mode: "broken"}))
+	require.Error(t, err)
+
+	_, err = New(logger, []string{"localhost"}, WithFiles("/nonexistent/tls.crt", "/nonexistent/tls.key"))
+	require.Error(t, err)
+}
+
+func TestService_Modes(t *testing.T) {
+	s := newTestService(t)
+
+	require.NoError(t, verify(t, s, "localhost"))
+	status := s.Status()
+	require.Equal(t, ModeValid, status.Mode)
+	require.Equal(t, "generated", status.Source)
+	require.Equal(t, "crashlooper CA", status.Issuer)
+	require.ElementsMatch(t, []string{"localhost", "127.0.0.1"}, status.Hosts)
+
+	tests := []struct {
+		mode Mode
+		err  interface{}
+	}{
+		{ModeExpired, x509.CertificateInvalidError{}},
+		{ModeNotYetValid, x509.CertificateInvalidError{}},
+		{ModeWrongHost, x509.HostnameError{}},
+		{ModeSelfSigned, x509.UnknownAuthorityError{}},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.mode), func(t *testing.T) {
+			require.NoError(t, s.Set(context.Background(), Settings{Mode: tt.mode}))
+			require.Equal(t, tt.mode, s.Status().Mode)
+			require.IsType(t, tt.err, verify(t, s, "localhost"))
+		})
+	}
+
+	require.NoError(t, s.Set(context.Background(), Settings{Mode: ModeValid}))
+	require.NoError(t, verify(t, s, "localhost"))
+
+	require.Error(t, s.Set(context.Background(), Settings{Mode: "broken"}))
+	require.Error(t, s.Set(context.Background(), Settings{Mode: ModeValid, RotateInterval: -1}))
+}
+
+func TestService_Rotate(t *testing.T) {
+	bus := events.NewBus()
+	s := newTestService(t, WithEvents(bus), WithSettings(Settings{
+		Mode:           ModeValid,
+		RotateInterval: chaos.Duration(20 * time.Millisecond),
+	}))
+	serial := s.Status().Serial
+
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	go s.Start()
+
+	var rotated events.Event
+	select {
+	case rotated = <-ch:
+		require.Equal(t, events.TypeProgress, rotated.Type)
+		require.Equal(t, "tls", rotated.Source)
+		require.Equal(t, events.TriggerFlag, rotated.Trigger)
+	case <-time.After(time.Second):
+		t.Fatal("certificate not rotated")
+	}
+	require.NotEqual(t, serial, s.Status().Serial)
+	require.NoError(t, verify(t, s, "localhost"))
+
+	// stopping the rotation
+	ctx := events.WithTrigger(context.Background(), events.TriggerScenario)
+	require.NoError(t, s.Set(ctx, Settings{Mode: ModeExpired}))
+	for e := range ch {
+		if e.Type == events.TypeTriggered {
+			require.Equal(t, events.TriggerScenario, e.Trigger)
+			require.NotEqual(t, rotated.FaultID, e.FaultID)
+			break
+		}
+	}
+	serial = s.Status().Serial
+	time.Sleep(60 * time.Millisecond)
+	require.Equal(t, serial, s.Status().Serial)
+}
+
+func TestService_Files(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeKeyPair(t, dir, "first.example.com")
+
+	s := newTestService(t, WithFiles(certFile, keyFile))
+	status := s.Status()
+	require.Equal(t, "file", status.Source)
+	require.Equal(t, []string{"first.example.com"}, status.Hosts)
+
+	// renewed files are served on the next handshake
+	time.Sleep(10 * time.Millisecond)
+	writeKeyPair(t, dir, "second.example.com")
+	require.NoError(t, os.Chtimes(certFile, time.Now().Add(time.Minute), time.Now().Add(time.Minute)))
+
+	cert, err := s.GetCertificate(&tls.ClientHelloInfo{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"second.example.com"}, cert.Leaf.DNSNames)
+
+	// fault modes serve generated certificates
+	require.NoError(t, s.Set(context.Background(), Settings{Mode: ModeWrongHost}))
+	require.Equal(t, "generated", s.Status().Source)
+	require.Equal(t, []string{WrongHost}, s.Status().Hosts)
+}
@@ -1,32 +1,246 @@
 package crash
 
 import (
+	"context"
+	"fmt"
 	"os"
+	"sync"
 	"time"
 
 	"go.pixelfactory.io/pkg/observability/log"
 	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/coordination"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
 )
 
+// Schedule describes the pending crash, if any.
+type Schedule struct {
+	Scheduled bool      `json:"scheduled"`
+	At        time.Time `json:"at,omitzero"`
+	ExitCode  int       `json:"exit_code,omitempty"`
+	// Waiting is true once the delay elapsed while another replica holds
+	// the crash slot.
+	Waiting bool `json:"waiting,omitempty"`
+}
+
 type service struct {
 	logger *log.DefaultLogger
 	after  time.Duration
+	exit   func(code int)
+	events events.Publisher
+
+	coordinator coordination.Coordinator
+	retry       time.Duration
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	stop     context.CancelFunc
+	schedule Schedule
+	fault    fault
+	// acquiring is true from the time the delay elapsed until the crash slot
+	// is granted, while the crash can still be cancelled.
+	acquiring bool
+}
+
+// fault identifies the pending crash in the events.
+type fault struct {
+	id      string
+	trigger events.Trigger
+}
+
+// Option configures the service.
+type Option func(*service)
+
+// WithEvents publishes the crash lifecycle events to p.
+func WithEvents(p events.Publisher) Option {
+	return func(s *service) {
+		s.events = p
+	}
 }
 
-func New(logger *log.DefaultLogger, after time.Duration) *service {
+// WithExit sets the function exiting the process, os.Exit by default.
+func WithExit(exit func(code int)) Option {
+	return func(s *service) {
+		s.exit = exit
+	}
+}
+
+// WithCoordinator crashes only once c grants the crash slot, asking for it
+// every retry interval while another replica holds it.
+func WithCoordinator(c coordination.Coordinator, retry time.Duration) Option {
+	return func(s *service) {
+		s.coordinator = c
+		s.retry = retry
+	}
+}
+
+func New(logger *log.DefaultLogger, after time.Duration, opts ...Option) *service {
 	logger.Info(
 		"Creating crash manager",
 		fields.Duration("after", after),
 	)
-	return &service{logger, after}
+	s := &service{
+		logger: logger,
+		after:  after,
+		exit:   os.Exit,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
 }
 
+// Start schedules the crash configured at creation, if any.
 func (s *service) Start() {
-	killTimer := time.AfterFunc(s.after, func() {
-		s.logger.Info("Crashing")
-		os.Exit(1)
+	if s.after <= 0 {
+		return
+	}
+	ctx := events.WithTrigger(context.Background(), events.TriggerFlag)
+	if _, err := s.Schedule(ctx, s.after, 1); err != nil {
+		s.logger.Error("Unable to schedule crash", fields.Error(err))
+	}
+}
+
+// Schedule exits the process with exitCode after the given delay,
+// replacing the pending crash if any. The events of the crash report the
+// trigger of ctx.
+func (s *service) Schedule(ctx context.Context, after time.Duration, exitCode int) (Schedule, error) {
+	if after < 0 {
+		return Schedule{}, fmt.Errorf("delay must not be negative")
+	}
+	if exitCode < 0 || exitCode > 255 {
+		return Schedule{}, fmt.Errorf("invalid exit code %d, must be in [0, 255]", exitCode)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.timer != nil {
+		// the crash replaced is cancelled, unless it is already exiting
+		if s.timer.Stop() || s.acquiring {
+			s.logger.Info("Replacing crash")
+			s.publish(fault{id: s.fault.id, trigger: events.TriggerFrom(ctx)}, events.TypeCancelled, "Crash replaced", nil)
+		}
+		s.stop()
+	}
+
+	s.schedule = Schedule{Scheduled: true, At: time.Now().Add(after), ExitCode: exitCode}
+	s.acquiring = false
+	s.fault = fault{id: events.NewFaultID(), trigger: events.TriggerFrom(ctx)}
+	f := s.fault
+	crashCtx, stop := context.WithCancel(context.Background())
+	s.stop = stop
+	s.timer = time.AfterFunc(after, func() {
+		s.crash(crashCtx, f, exitCode)
+	})
+
+	s.logger.Info("Scheduling crash", fields.Duration("after", after), fields.Int("exit_code", exitCode))
+	s.publish(f, events.TypeScheduled, "Crash scheduled", map[string]interface{}{
+		"at":        s.schedule.At,
+		"exit_code": exitCode,
+	})
+	return s.schedule, nil
+}
+
+// Cancel cancels the pending crash and returns false if there was none.
+// The cancelled event reports the trigger of ctx.
+func (s *service) Cancel(ctx context.Context) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// a crash asking for the crash slot can still be cancelled
+	if s.timer == nil || (!s.timer.Stop() && !s.acquiring) {
+		return false
+	}
+
+	f := fault{id: s.fault.id, trigger: events.TriggerFrom(ctx)}
+	s.stop()
+	s.timer = nil
+	s.schedule = Schedule{}
+	s.fault = fault{}
+	s.acquiring = false
+
+	s.logger.Info("Cancelling crash")
+	s.publish(f, events.TypeCancelled, "Crash cancelled", nil)
+	return true
+}
+
+// crash exits the process with exitCode, once the coordinator, if any, grants
+// the crash slot. It returns without crashing if ctx is cancelled meanwhile.
+func (s *service) crash(ctx context.Context, f fault, exitCode int) {
+	if s.coordinator != nil && !s.acquire(ctx, f) {
+		return
+	}
+
+	s.logger.Info("Crashing", fields.Int("exit_code", exitCode))
+	s.publish(f, events.TypeTriggered, "Crashing", map[string]interface{}{"exit_code": exitCode})
+	s.exit(exitCode)
+}
+
+// acquire asks the coordinator for the crash slot until it is granted, and
+// returns false if ctx is cancelled first.
+func (s *service) acquire(ctx context.Context, f fault) bool {
+	s.mu.Lock()
+	if ctx.Err() != nil {
+		s.mu.Unlock()
+		return false
+	}
+	s.acquiring = true
+	s.mu.Unlock()
+
+	for waiting := false; ; {
+		ok, err := s.coordinator.TryAcquire(ctx)
+		if err != nil {
+			s.logger.Error("Unable to acquire the crash slot", fields.Error(err))
+		}
+
+		s.mu.Lock()
+		if ctx.Err() != nil {
+			s.mu.Unlock()
+			return false
+		}
+		if ok {
+			s.acquiring = false
+			s.schedule.Waiting = false
+			s.mu.Unlock()
+			return true
+		}
+		if !waiting {
+			waiting = true
+			s.schedule.Waiting = true
+			s.logger.Info("Waiting for another replica to crash")
+			s.publish(f, events.TypeProgress, "Waiting for another replica to crash", nil)
+		}
+		s.mu.Unlock()
+
+		timer := time.NewTimer(s.retry)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+	}
+}
+
+// Pending returns the pending crash.
+func (s *service) Pending() Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.schedule
+}
+
+func (s *service) publish(f fault, t events.Type, msg string, data map[string]interface{}) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(events.Event{
+		Type:    t,
+		Source:  "crash",
+		FaultID: f.id,
+		Trigger: f.trigger,
+		Message: msg,
+		Data:    data,
 	})
-	defer killTimer.Stop()
-	c := make(chan struct{})
-	<-c
 }
@@ -1,11 +1,14 @@
 package crash
 
 import (
+	"context"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/require"
 	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
 )
 
 func TestNew(t *testing.T) {
@@ -67,22 +70,86 @@ func TestService_Fields(t *testing.T) {
 	require.Equal(t, after, svc.after)
 }
 
-// Note: We cannot fully test Start() method as it calls os.Exit(1) which would terminate the test
-// In a real-world scenario, you might want to refactor the code to make os.Exit injectable
-// or use an interface to allow mocking the exit behavior for testing purposes.
+// newTestService returns a service recording the exit codes instead of exiting.
+func newTestService(t *testing.T, after time.Duration) (*service, chan int) {
+	t.Helper()
 
-func TestService_Start_TimerCreated(t *testing.T) {
-	// This test verifies the service can be created and would start a timer
-	// We use a very long duration to avoid the actual crash during the test
-	logger := log.New(log.WithLevel("info"))
-	after := 1 * time.Hour
+	exited := make(chan int, 1)
+	svc := New(log.New(log.WithLevel("info")), after, WithExit(func(code int) { exited <- code }))
 
-	svc := New(logger, after)
-	require.NotNil(t, svc)
+	return svc, exited
+}
+
+func TestService_Start(t *testing.T) {
+	svc, exited := newTestService(t, 10*time.Millisecond)
+
+	svc.Start()
+	require.True(t, svc.Pending().Scheduled)
+
+	select {
+	case code := <-exited:
+		require.Equal(t, 1, code)
+	case <-time.After(time.Second):
+		t.Fatal("service did not crash")
+	}
+}
+
+func TestService_Start_Never(t *testing.T) {
+	svc, _ := newTestService(t, 0)
+
+	svc.Start()
+	require.Equal(t, Schedule{}, svc.Pending())
+}
+
+func TestService_Schedule(t *testing.T) {
+	svc, exited := newTestService(t, 0)
+
+	schedule, err := svc.Schedule(context.Background(), time.Hour, 3)
+	require.NoError(t, err)
+	require.True(t, schedule.Scheduled)
+	require.Equal(t, 3, schedule.ExitCode)
+	require.WithinDuration(t, time.Now().Add(time.Hour), schedule.At, time.Second)
+	require.Equal(t, schedule, svc.Pending())
+
+	// replaces the pending crash
+	_, err = svc.Schedule(context.Background(), 10*time.Millisecond, 137)
+	require.NoError(t, err)
+
+	select {
+	case code := <-exited:
+		require.Equal(t, 137, code)
+	case <-time.After(time.Second):
+		t.Fatal("service did not crash")
+	}
+}
 
-	// We can't fully test Start() without it blocking or calling os.Exit
-	// In production code, you might want to refactor to allow dependency injection
-	// of the exit function for testing purposes
+func TestService_Schedule_Invalid(t *testing.T) {
+	svc, _ := newTestService(t, 0)
+
+	_, err := svc.Schedule(context.Background(), -time.Second, 1)
+	require.Error(t, err)
+
+	_, err = svc.Schedule(context.Background(), time.Second, 256)
+	require.Error(t, err)
+
+	require.Equal(t, Schedule{}, svc.Pending())
+}
+
+func TestService_Cancel(t *testing.T) {
+	svc, exited := newTestService(t, 0)
+
+	require.False(t, svc.Cancel(context.Background()))
+
+	_, err := svc.Schedule(context.Background(), 50*time.Millisecond, 1)
+	require.NoError(t, err)
+	require.True(t, svc.Cancel(context.Background()))
+	require.Equal(t, Schedule{}, svc.Pending())
+
+	select {
+	case <-exited:
+		t.Fatal("cancelled crash was triggered")
+	case <-time.After(100 * time.Millisecond):
+	}
 }
 
 // TestService_Integration tests that the service can be created and would work correctly
@@ -117,3 +184,175 @@ func TestService_Integration(t *testing.T) {
 		})
 	}
 }
+
+func TestService_Events(t *testing.T) {
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	svc, exited := newTestService(t, 0)
+	WithEvents(bus)(svc)
+
+	_, err := svc.Schedule(context.Background(), time.Hour, 1)
+	require.NoError(t, err)
+	require.True(t, svc.Cancel(context.Background()))
+
+	_, err = svc.Schedule(context.Background(), time.Millisecond, 2)
+	require.NoError(t, err)
+	<-exited
+
+	var received []events.Event
+	for len(ch) > 0 {
+		e := <-ch
+		require.Equal(t, "crash", e.Source)
+		require.Equal(t, events.TriggerAPI, e.Trigger)
+		received = append(received, e)
+	}
+	require.Len(t, received, 4)
+	require.Equal(t, []events.Type{
+		events.TypeScheduled,
+		events.TypeCancelled,
+		events.TypeScheduled,
+		events.TypeTriggered,
+	}, []events.Type{received[0].Type, received[1].Type, received[2].Type, received[3].Type})
+
+	// the events of a crash share its fault id
+	require.NotEmpty(t, received[0].FaultID)
+	require.Equal(t, received[0].FaultID, received[1].FaultID)
+	require.Equal(t, received[2].FaultID, received[3].FaultID)
+	require.NotEqual(t, received[0].FaultID, received[2].FaultID)
+}
+
+func TestService_Events_Replaced(t *testing.T) {
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	svc, _ := newTestService(t, 0)
+	WithEvents(bus)(svc)
+
+	_, err := svc.Schedule(context.Background(), time.Hour, 1)
+	require.NoError(t, err)
+	scheduled := <-ch
+
+	ctx := events.WithTrigger(context.Background(), events.TriggerScenario)
+	_, err = svc.Schedule(ctx, time.Hour, 2)
+	require.NoError(t, err)
+
+	// the pending crash is cancelled before the new one is scheduled
+	cancelled := <-ch
+	require.Equal(t, events.TypeCancelled, cancelled.Type)
+	require.Equal(t, scheduled.FaultID, cancelled.FaultID)
+	require.Equal(t, events.TriggerScenario, cancelled.Trigger)
+
+	rescheduled := <-ch
+	require.Equal(t, events.TypeScheduled, rescheduled.Type)
+	require.NotEqual(t, scheduled.FaultID, rescheduled.FaultID)
+	require.True(t, svc.Cancel(context.Background()))
+}
+
+func TestService_Events_Trigger(t *testing.T) {
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	svc, _ := newTestService(t, time.Hour)
+	WithEvents(bus)(svc)
+
+	svc.Start()
+	require.Equal(t, events.TriggerFlag, (<-ch).Trigger)
+
+	ctx := events.WithTrigger(context.Background(), events.TriggerScenario)
+	require.True(t, svc.Cancel(ctx))
+	require.Equal(t, events.TriggerScenario, (<-ch).Trigger)
+}
+
+// fakeCoordinator grants the crash slot once free is closed.
+type fakeCoordinator struct {
+	free chan struct{}
+}
+
+func (c *fakeCoordinator) TryAcquire(context.Context) (bool, error) {
+	select {
+	case <-c.free:
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// blockingCoordinator grants the crash slot once free is closed, blocking
+// the calls until then, as a slow Lease round-trip.
+type blockingCoordinator struct {
+	called chan struct{}
+	free   chan struct{}
+}
+
+func (c *blockingCoordinator) TryAcquire(context.Context) (bool, error) {
+	c.called <- struct{}{}
+	<-c.free
+	return true, nil
+}
+
+func TestService_Coordinator(t *testing.T) {
+	coordinator := &fakeCoordinator{free: make(chan struct{})}
+	svc, exited := newTestService(t, 0)
+	WithCoordinator(coordinator, time.Millisecond)(svc)
+
+	_, err := svc.Schedule(context.Background(), time.Millisecond, 3)
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool { return svc.Pending().Waiting }, time.Second, time.Millisecond)
+	select {
+	case <-exited:
+		t.Fatal("crashed while another replica holds the crash slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(coordinator.free)
+	select {
+	case code := <-exited:
+		require.Equal(t, 3, code)
+	case <-time.After(time.Second):
+		t.Fatal("service did not crash once the crash slot was free")
+	}
+}
+
+func TestService_Coordinator_Cancel(t *testing.T) {
+	coordinator := &fakeCoordinator{free: make(chan struct{})}
+	svc, exited := newTestService(t, 0)
+	WithCoordinator(coordinator, time.Millisecond)(svc)
+
+	_, err := svc.Schedule(context.Background(), time.Millisecond, 3)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool { return svc.Pending().Waiting }, time.Second, time.Millisecond)
+
+	require.True(t, svc.Cancel(context.Background()))
+	require.Equal(t, Schedule{}, svc.Pending())
+
+	close(coordinator.free)
+	select {
+	case <-exited:
+		t.Fatal("cancelled crash was triggered")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
+
+func TestService_Coordinator_CancelAcquiring(t *testing.T) {
+	coordinator := &blockingCoordinator{called: make(chan struct{}, 1), free: make(chan struct{})}
+	svc, exited := newTestService(t, 0)
+	WithCoordinator(coordinator, time.Millisecond)(svc)
+
+	_, err := svc.Schedule(context.Background(), time.Millisecond, 3)
+	require.NoError(t, err)
+
+	// cancelled while the first request for the crash slot is in flight
+	<-coordinator.called
+	require.True(t, svc.Cancel(context.Background()))
+	close(coordinator.free)
+	select {
+	case <-exited:
+		t.Fatal("cancelled crash was triggered")
+	case <-time.After(20 * time.Millisecond):
+	}
+}
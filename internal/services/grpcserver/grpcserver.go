@@ -0,0 +1,122 @@
+// Package grpcserver serves the gRPC health service, for the native gRPC probes
+// of Kubernetes, and an echo service on which the chaos faults apply.
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
+
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos/chaosgrpc"
+)
+
+// probeServices are the services the faults never apply to, like the HTTP
+// health checks, so that the probes only fail when crashlooper does.
+var probeServices = []string{
+	"/" + healthpb.Health_ServiceDesc.ServiceName + "/",
+	"/grpc.reflection.",
+}
+
+type service struct {
+	logger *log.DefaultLogger
+	listen string
+	faults *chaos.Controller
+
+	server *grpc.Server
+	health *health.Server
+}
+
+// Option configures the gRPC server.
+type Option func(*service)
+
+// WithFaults applies the latency, error and crash faults of c to the RPCs.
+func WithFaults(c *chaos.Controller) Option {
+	return func(s *service) {
+		s.faults = c
+	}
+}
+
+// New returns a service serving gRPC on listen.
+func New(logger *log.DefaultLogger, listen string, opts ...Option) *service {
+	logger.Info("Creating gRPC server", fields.String("listen", listen))
+
+	s := &service{
+		logger: logger,
+		listen: listen,
+		health: health.NewServer(),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	var serverOpts []grpc.ServerOption
+	if s.faults != nil {
+		serverOpts = append(serverOpts,
+			grpc.ChainUnaryInterceptor(unaryFaults(chaosgrpc.UnaryServerInterceptor(s.faults))),
+			grpc.ChainStreamInterceptor(streamFaults(chaosgrpc.StreamServerInterceptor(s.faults))),
+		)
+	}
+
+	s.server = grpc.NewServer(serverOpts...)
+	healthpb.RegisterHealthServer(s.server, s.health)
+	s.server.RegisterService(&echoServiceDesc, echo{})
+	s.health.SetServingStatus(EchoService, healthpb.HealthCheckResponse_SERVING)
+	reflection.Register(s.server)
+
+	return s
+}
+
+// Start listens on the listen address and serves RPCs until the listener fails.
+func (s *service) Start() error {
+	l, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return errors.Wrap(err, "unable to listen")
+	}
+
+	return s.serve(l)
+}
+
+func (s *service) serve(l net.Listener) error {
+	if err := s.server.Serve(l); err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		return errors.Wrap(err, "unable to serve gRPC")
+	}
+	return nil
+}
+
+// unaryFaults returns interceptor skipping the RPCs of the probe services.
+func unaryFaults(interceptor grpc.UnaryServerInterceptor) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isProbe(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		return interceptor(ctx, req, info, handler)
+	}
+}
+
+// streamFaults returns interceptor skipping the RPCs of the probe services.
+func streamFaults(interceptor grpc.StreamServerInterceptor) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isProbe(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		return interceptor(srv, ss, info, handler)
+	}
+}
+
+func isProbe(method string) bool {
+	for _, prefix := range probeServices {
+		if strings.HasPrefix(method, prefix) {
+			return true
+		}
+	}
+	return false
+}
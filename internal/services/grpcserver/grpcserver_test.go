@@ -0,0 +1,130 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	reflectionpb "google.golang.org/grpc/reflection/grpc_reflection_v1"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// startServer starts a gRPC server applying the faults of c and returns a
+// connection to it.
+func startServer(t *testing.T, c *chaos.Controller) *grpc.ClientConn {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	svc := New(log.New(log.WithLevel("info")), l.Addr().String(), WithFaults(c))
+	go func() {
+		_ = svc.serve(l)
+	}()
+	t.Cleanup(svc.server.Stop)
+
+	conn, err := grpc.NewClient(l.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func echoCall(ctx context.Context, conn *grpc.ClientConn, msg string, opts ...grpc.CallOption) (string, error) {
+	out := new(wrapperspb.StringValue)
+	err := conn.Invoke(ctx, EchoMethod, wrapperspb.String(msg), out, opts...)
+	return out.GetValue(), err
+}
+
+func TestNew(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, ":50051")
+
+	require.NotNil(t, svc)
+	require.Equal(t, ":50051", svc.listen)
+	require.Nil(t, svc.faults)
+}
+
+func TestService_Health(t *testing.T) {
+	conn := startServer(t, chaos.NewController())
+	client := healthpb.NewHealthClient(conn)
+
+	for _, service := range []string{"", EchoService} {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		require.NoError(t, err)
+		require.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+	}
+
+	_, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "unknown"})
+	require.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestService_Echo(t *testing.T) {
+	conn := startServer(t, chaos.NewController())
+
+	msg, err := echoCall(context.Background(), conn, "hello")
+	require.NoError(t, err)
+	require.Equal(t, "hello", msg)
+}
+
+func TestService_Faults(t *testing.T) {
+	c := chaos.NewController()
+	conn := startServer(t, c)
+
+	require.NoError(t, c.Set(chaos.Fault{Kind: chaos.KindError, Probability: 1, StatusCode: http.StatusServiceUnavailable}))
+
+	var header metadata.MD
+	_, err := echoCall(context.Background(), conn, "hello", grpc.Header(&header))
+	require.Equal(t, codes.Unavailable, status.Code(err))
+	require.Equal(t, []string{string(chaos.KindError)}, header.Get(chaos.HeaderFault))
+
+	// the probes are never faulted
+	_, err = healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+
+	require.True(t, c.Remove(chaos.KindError))
+	require.NoError(t, c.Set(chaos.Fault{Kind: chaos.KindLatency, Probability: 1, Delay: chaos.Duration(time.Hour)}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err = echoCall(ctx, conn, "hello")
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestService_Reflection(t *testing.T) {
+	conn := startServer(t, chaos.NewController())
+
+	stream, err := reflectionpb.NewServerReflectionClient(conn).ServerReflectionInfo(context.Background())
+	require.NoError(t, err)
+
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_ListServices{},
+	}))
+	resp, err := stream.Recv()
+	require.NoError(t, err)
+
+	var services []string
+	for _, s := range resp.GetListServicesResponse().GetService() {
+		services = append(services, s.Name)
+	}
+	require.Contains(t, services, EchoService)
+	require.Contains(t, services, healthpb.Health_ServiceDesc.ServiceName)
+
+	require.NoError(t, stream.Send(&reflectionpb.ServerReflectionRequest{
+		MessageRequest: &reflectionpb.ServerReflectionRequest_FileContainingSymbol{FileContainingSymbol: EchoService},
+	}))
+	resp, err = stream.Recv()
+	require.NoError(t, err)
+	require.NotEmpty(t, resp.GetFileDescriptorResponse().GetFileDescriptorProto())
+}
@@ -0,0 +1,193 @@
+// Package job does the work of the job and init modes, then completes with a
+// controlled exit code, so that the handling of Jobs, CronJobs and init
+// containers can be tested.
+package job
+
+import (
+	"context"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+)
+
+// ExitInterrupted is the exit code of a run interrupted by SIGTERM, as if
+// the process had been killed by it.
+const ExitInterrupted = 128 + 15
+
+const pageSize = 4096
+
+// Config configures the work of a run and its completion.
+type Config struct {
+	// Allocate is the memory allocated and held until the exit.
+	Allocate units.Base2Bytes
+	// CPUBurn is the time spent burning CPU with CPUWorkers busy goroutines.
+	CPUBurn    time.Duration
+	CPUWorkers int
+	// Sleep is the time spent idle after the CPU burn.
+	Sleep time.Duration
+
+	// ExitCode is the exit code of a successful attempt.
+	ExitCode int
+	// FailAttempts fails the first attempts with FailExitCode. The attempts
+	// are counted in AttemptFile, which must outlive the container, such as
+	// on an emptyDir or a persistent volume.
+	FailAttempts int
+	FailExitCode int
+	AttemptFile  string
+	// Hang never exits after a successful attempt, until the process is
+	// killed, such as past the activeDeadlineSeconds of a Job.
+	Hang bool
+}
+
+// Validate returns an error if the configuration is not usable.
+func (c Config) Validate() error {
+	if c.Allocate < 0 || c.CPUBurn < 0 || c.Sleep < 0 {
+		return errors.New("the work must not be negative")
+	}
+	if c.CPUBurn > 0 && c.CPUWorkers < 1 {
+		return errors.New("burning CPU requires at least one worker")
+	}
+	for _, code := range []int{c.ExitCode, c.FailExitCode} {
+		if code < 0 || code > 255 {
+			return errors.Errorf("invalid exit code %d, must be in [0, 255]", code)
+		}
+	}
+	if c.FailAttempts < 0 {
+		return errors.New("the failed attempts must not be negative")
+	}
+	if c.FailAttempts > 0 && c.AttemptFile == "" {
+		return errors.New("failing attempts requires an attempt file")
+	}
+	return nil
+}
+
+type service struct {
+	logger *log.DefaultLogger
+	mode   string
+	cfg    Config
+}
+
+// New returns a service running the work of cfg, mode names the job or
+// init mode in the logs.
+func New(logger *log.DefaultLogger, mode string, cfg Config) *service {
+	logger.Info(
+		"Creating "+mode,
+		fields.Any("allocate", cfg.Allocate),
+		fields.Duration("cpu_burn", cfg.CPUBurn),
+		fields.Duration("sleep", cfg.Sleep),
+		fields.Int("exit_code", cfg.ExitCode),
+	)
+	return &service{logger: logger, mode: mode, cfg: cfg}
+}
+
+// Run does the work and returns the exit code of the process. It returns
+// ExitInterrupted once ctx is cancelled, such as by SIGTERM.
+func (s *service) Run(ctx context.Context) (int, error) {
+	attempt := 1
+	if s.cfg.AttemptFile != "" {
+		var err error
+		if attempt, err = nextAttempt(s.cfg.AttemptFile); err != nil {
+			return 0, err
+		}
+	}
+	logger := s.logger.With(fields.String("mode", s.mode), fields.Int("attempt", attempt))
+	logger.Info("Starting " + s.mode)
+
+	// the memory is held until the exit
+	ballast := allocate(s.cfg.Allocate)
+	defer runtime.KeepAlive(ballast)
+
+	burn(ctx, s.cfg.CPUBurn, s.cfg.CPUWorkers)
+
+	timer := time.NewTimer(s.cfg.Sleep)
+	select {
+	case <-ctx.Done():
+		timer.Stop()
+	case <-timer.C:
+	}
+	if ctx.Err() != nil {
+		logger.Info("Interrupted")
+		return ExitInterrupted, nil
+	}
+
+	if attempt <= s.cfg.FailAttempts {
+		logger.Info("Failing attempt", fields.Int("exit_code", s.cfg.FailExitCode))
+		return s.cfg.FailExitCode, nil
+	}
+
+	if s.cfg.Hang {
+		logger.Info("Hanging until killed")
+		<-ctx.Done()
+		logger.Info("Interrupted")
+		return ExitInterrupted, nil
+	}
+
+	logger.Info("Completed", fields.Int("exit_code", s.cfg.ExitCode))
+	return s.cfg.ExitCode, nil
+}
+
+// nextAttempt increments the attempt counter stored in path and returns the
+// current attempt, starting at 1.
+func nextAttempt(path string) (int, error) {
+	attempt := 0
+	b, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if attempt, err = strconv.Atoi(strings.TrimSpace(string(b))); err != nil {
+			return 0, errors.Wrap(err, "invalid attempt file")
+		}
+	case !os.IsNotExist(err):
+		return 0, errors.Wrap(err, "unable to read attempt file")
+	}
+
+	attempt++
+	if err := os.WriteFile(path, []byte(strconv.Itoa(attempt)+"\n"), 0o600); err != nil {
+		return 0, errors.Wrap(err, "unable to write attempt file")
+	}
+	return attempt, nil
+}
+
+// allocate returns size bytes of memory, every page written so that it is
+// resident.
+func allocate(size units.Base2Bytes) []byte {
+	if size <= 0 {
+		return nil
+	}
+	buf := make([]byte, size)
+	for i := 0; i < len(buf); i += pageSize {
+		buf[i] = 1
+	}
+	return buf
+}
+
+// burn keeps workers goroutines busy for d, or until ctx is cancelled.
+func burn(ctx context.Context, d time.Duration, workers int) {
+	if d <= 0 {
+		return
+	}
+	deadline := time.Now().Add(d)
+
+	var wg sync.WaitGroup
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for n := 0; ; n++ {
+				// checking the time and ctx on every iteration would
+				// dominate the work
+				if n%100000 == 0 && (ctx.Err() != nil || time.Now().After(deadline)) {
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+}
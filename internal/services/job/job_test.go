@@ -0,0 +1,116 @@
+package job
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"empty", Config{}, false},
+		{"work", Config{Allocate: units.MiB, CPUBurn: time.Second, CPUWorkers: 2, Sleep: time.Second}, false},
+		{"fail attempts", Config{FailAttempts: 2, FailExitCode: 1, AttemptFile: "/tmp/attempts"}, false},
+		{"negative sleep", Config{Sleep: -time.Second}, true},
+		{"no worker", Config{CPUBurn: time.Second}, true},
+		{"invalid exit code", Config{ExitCode: 256}, true},
+		{"invalid fail exit code", Config{FailExitCode: -1}, true},
+		{"negative fail attempts", Config{FailAttempts: -1}, true},
+		{"no attempt file", Config{FailAttempts: 1, FailExitCode: 1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestService_Run(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	s := New(logger, "job", Config{
+		Allocate:   units.MiB,
+		CPUBurn:    20 * time.Millisecond,
+		CPUWorkers: 2,
+		Sleep:      20 * time.Millisecond,
+		ExitCode:   3,
+	})
+
+	start := time.Now()
+	code, err := s.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 3, code)
+	require.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestService_Run_FailAttempts(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	path := filepath.Join(t.TempDir(), "attempts")
+	s := New(logger, "init", Config{FailAttempts: 2, FailExitCode: 42, AttemptFile: path})
+
+	for _, want := range []int{42, 42, 0, 0} {
+		code, err := s.Run(context.Background())
+		require.NoError(t, err)
+		require.Equal(t, want, code)
+	}
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "4\n", string(b))
+
+	require.NoError(t, os.WriteFile(path, []byte("three"), 0o600))
+	_, err = s.Run(context.Background())
+	require.Error(t, err)
+}
+
+func TestService_Run_Hang(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	path := filepath.Join(t.TempDir(), "attempts")
+	s := New(logger, "job", Config{FailAttempts: 1, FailExitCode: 1, AttemptFile: path, Hang: true})
+
+	// the failed attempts do not hang
+	code, err := s.Run(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, code)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan int)
+	go func() {
+		code, _ := s.Run(ctx)
+		done <- code
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("the successful attempt must hang")
+	case <-time.After(50 * time.Millisecond):
+	}
+	cancel()
+	require.Equal(t, ExitInterrupted, <-done)
+}
+
+func TestService_Run_Interrupted(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	s := New(logger, "job", Config{CPUBurn: time.Minute, CPUWorkers: 1, Sleep: time.Minute})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	code, err := s.Run(ctx)
+	require.NoError(t, err)
+	require.Equal(t, ExitInterrupted, code)
+}
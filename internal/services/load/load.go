@@ -0,0 +1,197 @@
+package load
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+	"go.uber.org/zap/zapcore"
+)
+
+const tick = 10 * time.Millisecond
+
+type service struct {
+	logger         *log.DefaultLogger
+	target         string
+	method         string
+	concurrency    int
+	stages         []Stage
+	reportInterval time.Duration
+	client         *http.Client
+
+	duration *prometheus.HistogramVec
+	requests *prometheus.CounterVec
+}
+
+// New returns a service sending requests to target following the stages
+// schedule, with at most concurrency requests in flight. The metrics are
+// registered on registerer.
+func New(
+	logger *log.DefaultLogger,
+	target string,
+	method string,
+	concurrency int,
+	stages []Stage,
+	timeout time.Duration,
+	reportInterval time.Duration,
+	registerer prometheus.Registerer,
+) *service {
+	logger.Info(
+		"Creating load generator",
+		fields.String("target", target),
+		fields.String("method", method),
+		fields.Int("concurrency", concurrency),
+	)
+
+	s := &service{
+		logger:         logger,
+		target:         target,
+		method:         method,
+		concurrency:    concurrency,
+		stages:         stages,
+		reportInterval: reportInterval,
+		client:         &http.Client{Timeout: timeout},
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "crashlooper_load_request_duration_seconds",
+			Help:    "Duration of the requests sent by the load generator.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"code"}),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "crashlooper_load_requests_total",
+			Help: "Number of requests sent by the load generator.",
+		}, []string{"code"}),
+	}
+
+	registerer.MustRegister(s.duration, s.requests)
+
+	return s
+}
+
+// Run sends requests until the schedule is over or ctx is cancelled,
+// and returns the report of every request sent.
+func (s *service) Run(ctx context.Context) Report {
+	total := newStats()
+	interval := newStats()
+
+	jobs := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < s.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range jobs {
+				d, code, err := s.send(ctx)
+				total.record(d, code, err)
+				interval.record(d, code, err)
+			}
+		}()
+	}
+
+	ticker := time.NewTicker(tick)
+	defer ticker.Stop()
+
+	var reportC <-chan time.Time
+	if s.reportInterval > 0 {
+		reporter := time.NewTicker(s.reportInterval)
+		defer reporter.Stop()
+		reportC = reporter.C
+	}
+
+	start := time.Now()
+	last := start
+	var tokens float64
+
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case <-reportC:
+			s.report("Load report", interval.reset())
+		case now := <-ticker.C:
+			rate, ok := rateAt(s.stages, now.Sub(start))
+			if !ok {
+				break loop
+			}
+
+			tokens += rate * now.Sub(last).Seconds()
+			last = now
+			for ; tokens >= 1; tokens-- {
+				select {
+				case jobs <- struct{}{}:
+				default:
+					total.skip()
+					interval.skip()
+				}
+			}
+		}
+	}
+
+	close(jobs)
+	wg.Wait()
+
+	r := total.snapshot()
+	s.report("Load summary", r)
+	return r
+}
+
+// send sends a single request and returns its duration and status code.
+func (s *service) send(ctx context.Context) (time.Duration, int, error) {
+	req, err := http.NewRequestWithContext(ctx, s.method, s.target, nil)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	start := time.Now()
+	resp, err := s.client.Do(req)
+	if err != nil {
+		d := time.Since(start)
+		s.observe("error", d)
+		s.logger.Debug("Request failed", fields.Error(err))
+		return d, 0, err
+	}
+	_, _ = io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+
+	d := time.Since(start)
+	s.observe(strconv.Itoa(resp.StatusCode), d)
+	return d, resp.StatusCode, nil
+}
+
+func (s *service) observe(code string, d time.Duration) {
+	s.requests.WithLabelValues(code).Inc()
+	s.duration.WithLabelValues(code).Observe(d.Seconds())
+}
+
+func (s *service) report(msg string, r Report) {
+	codes := make([]string, 0, len(r.Codes))
+	for code := range r.Codes {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	logFields := []zapcore.Field{
+		fields.Int("requests", int(r.Requests)),
+		fields.Int("errors", int(r.Errors)),
+		fields.Int("skipped", int(r.Skipped)),
+		fields.Any("rate", r.Rate()),
+		fields.Duration("min", r.Min),
+		fields.Duration("mean", r.Mean),
+		fields.Duration("p50", r.Percentile(0.50)),
+		fields.Duration("p90", r.Percentile(0.90)),
+		fields.Duration("p99", r.Percentile(0.99)),
+		fields.Duration("max", r.Max),
+	}
+	for _, code := range codes {
+		logFields = append(logFields, fields.Int("code_"+code, int(r.Codes[code])))
+	}
+
+	s.logger.Info(msg, logFields...)
+}
@@ -0,0 +1,105 @@
+package load
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+)
+
+func TestNew(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	stages := ConstantStages(10, time.Second)
+	reg := prometheus.NewRegistry()
+
+	svc := New(logger, "http://localhost:3000", http.MethodGet, 2, stages, time.Second, time.Second, reg)
+
+	require.NotNil(t, svc)
+	require.Equal(t, logger, svc.logger)
+	require.Equal(t, "http://localhost:3000", svc.target)
+	require.Equal(t, http.MethodGet, svc.method)
+	require.Equal(t, 2, svc.concurrency)
+	require.Equal(t, stages, svc.stages)
+	require.Equal(t, time.Second, svc.client.Timeout)
+}
+
+func TestService_Run(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1)%2 == 0 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	logger := log.New(log.WithLevel("info"))
+	reg := prometheus.NewRegistry()
+	svc := New(logger, srv.URL, http.MethodGet, 4, ConstantStages(100, 500*time.Millisecond), time.Second, 100*time.Millisecond, reg)
+
+	r := svc.Run(context.Background())
+
+	require.InDelta(t, 50, r.Requests, 15)
+	require.Equal(t, uint64(0), r.Errors)
+	require.Equal(t, uint64(atomic.LoadInt32(&hits)), r.Requests)
+	require.Equal(t, r.Requests, r.Codes["200"]+r.Codes["503"])
+	require.NotZero(t, r.Codes["503"])
+
+	require.Equal(t, float64(r.Codes["200"]), testutil.ToFloat64(svc.requests.WithLabelValues("200")))
+	require.Equal(t, float64(r.Codes["503"]), testutil.ToFloat64(svc.requests.WithLabelValues("503")))
+}
+
+func TestService_Run_Errors(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	target := srv.URL
+	srv.Close()
+
+	logger := log.New(log.WithLevel("info"))
+	reg := prometheus.NewRegistry()
+	svc := New(logger, target, http.MethodGet, 1, ConstantStages(50, 200*time.Millisecond), time.Second, 0, reg)
+
+	r := svc.Run(context.Background())
+
+	require.NotZero(t, r.Requests)
+	require.Equal(t, r.Requests, r.Errors)
+	require.Empty(t, r.Codes)
+	require.Equal(t, float64(r.Errors), testutil.ToFloat64(svc.requests.WithLabelValues("error")))
+}
+
+func TestService_Run_Cancel(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer srv.Close()
+
+	logger := log.New(log.WithLevel("info"))
+	reg := prometheus.NewRegistry()
+	svc := New(logger, srv.URL, http.MethodGet, 1, ConstantStages(10, 0), time.Second, 0, reg)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	svc.Run(ctx)
+	require.Less(t, time.Since(start), time.Second)
+}
+
+func TestService_Run_Skipped(t *testing.T) {
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer srv.Close()
+	defer close(release)
+
+	logger := log.New(log.WithLevel("info"))
+	reg := prometheus.NewRegistry()
+	svc := New(logger, srv.URL, http.MethodGet, 1, ConstantStages(100, 200*time.Millisecond), 300*time.Millisecond, 0, reg)
+
+	r := svc.Run(context.Background())
+	require.NotZero(t, r.Skipped)
+}
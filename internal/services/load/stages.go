@@ -0,0 +1,71 @@
+package load
+
+import (
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Stage is a step of the ramp schedule, the request rate is linearly ramped
+// from the rate of the previous stage, or zero for the first one, to Rate
+// over Duration.
+type Stage struct {
+	Duration time.Duration
+	Rate     float64
+}
+
+// ParseStages parses a ramp schedule made of comma separated "duration:rate"
+// stages, such as "30s:10,2m:10,30s:100". A "0s:rate" stage starts directly at rate.
+func ParseStages(s string) ([]Stage, error) {
+	var stages []Stage
+	for _, part := range strings.Split(s, ",") {
+		d, r, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return nil, errors.Errorf("invalid stage %q, expected duration:rate", part)
+		}
+
+		duration, err := time.ParseDuration(d)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid stage %q", part)
+		}
+
+		rate, err := strconv.ParseFloat(r, 64)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid stage %q", part)
+		}
+
+		if duration < 0 || rate < 0 {
+			return nil, errors.Errorf("invalid stage %q, duration and rate must not be negative", part)
+		}
+
+		stages = append(stages, Stage{Duration: duration, Rate: rate})
+	}
+
+	return stages, nil
+}
+
+// ConstantStages returns a schedule sending rate requests per second for
+// duration, or forever if duration is zero.
+func ConstantStages(rate float64, duration time.Duration) []Stage {
+	if duration == 0 {
+		duration = time.Duration(math.MaxInt64)
+	}
+	return []Stage{{Rate: rate}, {Duration: duration, Rate: rate}}
+}
+
+// rateAt returns the request rate of the schedule after elapsed,
+// and false once the schedule is over.
+func rateAt(stages []Stage, elapsed time.Duration) (float64, bool) {
+	var from float64
+	for _, st := range stages {
+		if elapsed < st.Duration {
+			return from + (st.Rate-from)*float64(elapsed)/float64(st.Duration), true
+		}
+		elapsed -= st.Duration
+		from = st.Rate
+	}
+	return 0, false
+}
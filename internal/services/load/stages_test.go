@@ -0,0 +1,67 @@
+package load
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStages(t *testing.T) {
+	stages, err := ParseStages("30s:10, 2m:10,0s:100")
+	require.NoError(t, err)
+	require.Equal(t, []Stage{
+		{Duration: 30 * time.Second, Rate: 10},
+		{Duration: 2 * time.Minute, Rate: 10},
+		{Duration: 0, Rate: 100},
+	}, stages)
+}
+
+func TestParseStages_Invalid(t *testing.T) {
+	for _, s := range []string{"", "30s", "30s:", "foo:10", "30s:foo", "-1s:10", "30s:-1"} {
+		_, err := ParseStages(s)
+		require.Error(t, err, s)
+	}
+}
+
+func TestConstantStages(t *testing.T) {
+	stages := ConstantStages(10, time.Minute)
+	require.Equal(t, []Stage{{Rate: 10}, {Duration: time.Minute, Rate: 10}}, stages)
+
+	rate, ok := rateAt(stages, 0)
+	require.True(t, ok)
+	require.Equal(t, float64(10), rate)
+
+	_, ok = rateAt(stages, time.Minute)
+	require.False(t, ok)
+
+	_, ok = rateAt(ConstantStages(10, 0), 24*time.Hour)
+	require.True(t, ok)
+}
+
+func TestRateAt(t *testing.T) {
+	stages := []Stage{
+		{Duration: 10 * time.Second, Rate: 100},
+		{Duration: 10 * time.Second, Rate: 100},
+		{Duration: 10 * time.Second, Rate: 0},
+	}
+
+	tests := []struct {
+		elapsed time.Duration
+		rate    float64
+	}{
+		{0, 0},
+		{5 * time.Second, 50},
+		{10 * time.Second, 100},
+		{15 * time.Second, 100},
+		{25 * time.Second, 50},
+	}
+	for _, tt := range tests {
+		rate, ok := rateAt(stages, tt.elapsed)
+		require.True(t, ok, tt.elapsed)
+		require.InDelta(t, tt.rate, rate, 0.001, tt.elapsed)
+	}
+
+	_, ok := rateAt(stages, 30*time.Second)
+	require.False(t, ok)
+}
@@ -0,0 +1,155 @@
+package load
+
+import (
+	"strconv"
+	"sync"
+	"time"
+)
+
+// buckets are the upper bounds of the latency histogram.
+var buckets = []time.Duration{
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	1 * time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Report summarizes the requests sent over a period of time.
+type Report struct {
+	Requests uint64 `json:"requests"`
+	// Errors counts the requests which failed without a response.
+	Errors uint64 `json:"errors"`
+	// Skipped counts the requests which were not sent because every worker was busy.
+	Skipped uint64            `json:"skipped"`
+	Codes   map[string]uint64 `json:"codes"`
+	// Histogram counts the requests per latency bucket, indexed like buckets
+	// with an extra bucket for slower requests.
+	Histogram []uint64      `json:"histogram"`
+	Min       time.Duration `json:"min"`
+	Max       time.Duration `json:"max"`
+	Mean      time.Duration `json:"mean"`
+	Elapsed   time.Duration `json:"elapsed"`
+}
+
+// Rate returns the number of requests sent per second.
+func (r *Report) Rate() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// Percentile returns the upper bound of the histogram bucket holding the q
+// quantile of the latencies, or Max when it falls in the last bucket.
+func (r *Report) Percentile(q float64) time.Duration {
+	var total uint64
+	for _, c := range r.Histogram {
+		total += c
+	}
+	if total == 0 {
+		return 0
+	}
+
+	var cumulative uint64
+	for i, c := range r.Histogram {
+		cumulative += c
+		if float64(cumulative) >= q*float64(total) {
+			if i < len(buckets) {
+				return buckets[i]
+			}
+			break
+		}
+	}
+	return r.Max
+}
+
+// stats accumulates the results of the requests.
+type stats struct {
+	mu      sync.Mutex
+	start   time.Time
+	report  Report
+	latency time.Duration
+}
+
+func newStats() *stats {
+	return &stats{
+		start: time.Now(),
+		report: Report{
+			Codes:     make(map[string]uint64),
+			Histogram: make([]uint64, len(buckets)+1),
+		},
+	}
+}
+
+func (s *stats) record(d time.Duration, code int, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.report.Requests++
+	if err != nil {
+		s.report.Errors++
+		return
+	}
+	s.report.Codes[strconv.Itoa(code)]++
+
+	i := 0
+	for i < len(buckets) && d > buckets[i] {
+		i++
+	}
+	s.report.Histogram[i]++
+
+	if s.report.Min == 0 || d < s.report.Min {
+		s.report.Min = d
+	}
+	if d > s.report.Max {
+		s.report.Max = d
+	}
+	s.latency += d
+}
+
+func (s *stats) skip() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.report.Skipped++
+}
+
+// snapshot returns a copy of the report accumulated since start.
+func (s *stats) snapshot() Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.snapshotLocked()
+}
+
+// reset returns the report accumulated since start and starts a new one.
+func (s *stats) reset() Report {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r := s.snapshotLocked()
+	fresh := newStats()
+	s.start, s.report, s.latency = fresh.start, fresh.report, 0
+	return r
+}
+
+func (s *stats) snapshotLocked() Report {
+	r := s.report
+	r.Codes = make(map[string]uint64, len(s.report.Codes))
+	for k, v := range s.report.Codes {
+		r.Codes[k] = v
+	}
+	r.Histogram = append([]uint64(nil), s.report.Histogram...)
+	r.Elapsed = time.Since(s.start)
+
+	if responses := r.Requests - r.Errors; responses > 0 {
+		r.Mean = s.latency / time.Duration(responses)
+	}
+
+	return r
+}
@@ -0,0 +1,68 @@
+package load
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStats_Record(t *testing.T) {
+	s := newStats()
+	s.record(3*time.Millisecond, 200, nil)
+	s.record(30*time.Millisecond, 200, nil)
+	s.record(90*time.Millisecond, 503, nil)
+	s.record(time.Second, 0, errors.New("connection refused"))
+	s.skip()
+
+	r := s.snapshot()
+	require.Equal(t, uint64(4), r.Requests)
+	require.Equal(t, uint64(1), r.Errors)
+	require.Equal(t, uint64(1), r.Skipped)
+	require.Equal(t, map[string]uint64{"200": 2, "503": 1}, r.Codes)
+	require.Equal(t, 3*time.Millisecond, r.Min)
+	require.Equal(t, 90*time.Millisecond, r.Max)
+	require.Equal(t, 41*time.Millisecond, r.Mean)
+	require.Equal(t, uint64(1), r.Histogram[0])
+	require.Equal(t, uint64(1), r.Histogram[3])
+	require.Equal(t, uint64(1), r.Histogram[4])
+}
+
+func TestStats_Reset(t *testing.T) {
+	s := newStats()
+	s.record(time.Millisecond, 200, nil)
+
+	r := s.reset()
+	require.Equal(t, uint64(1), r.Requests)
+
+	r = s.snapshot()
+	require.Equal(t, uint64(0), r.Requests)
+	require.Empty(t, r.Codes)
+	require.Equal(t, time.Duration(0), r.Min)
+}
+
+func TestReport_Percentile(t *testing.T) {
+	s := newStats()
+	for i := 0; i < 90; i++ {
+		s.record(time.Millisecond, 200, nil)
+	}
+	for i := 0; i < 9; i++ {
+		s.record(200*time.Millisecond, 200, nil)
+	}
+	s.record(20*time.Second, 200, nil)
+
+	r := s.snapshot()
+	require.Equal(t, 5*time.Millisecond, r.Percentile(0.5))
+	require.Equal(t, 5*time.Millisecond, r.Percentile(0.9))
+	require.Equal(t, 250*time.Millisecond, r.Percentile(0.99))
+	require.Equal(t, 20*time.Second, r.Percentile(1))
+
+	require.Equal(t, time.Duration(0), (&Report{}).Percentile(0.5))
+}
+
+func TestReport_Rate(t *testing.T) {
+	r := Report{Requests: 100, Elapsed: 10 * time.Second}
+	require.Equal(t, float64(10), r.Rate())
+	require.Equal(t, float64(0), (&Report{}).Rate())
+}
@@ -2,21 +2,69 @@ package memory
 
 import (
 	"bytes"
+	"context"
+	"fmt"
 	"runtime/debug"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/alecthomas/units"
 	"go.pixelfactory.io/pkg/observability/log"
 	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
 )
 
+// Settings describes how the memory usage grows.
+type Settings struct {
+	Target    units.Base2Bytes `json:"target"`
+	Increment units.Base2Bytes `json:"increment"`
+	Interval  chaos.Duration   `json:"interval"`
+}
+
+// Status describes the memory usage target and the memory allocated so far.
+type Status struct {
+	Settings
+	Allocated units.Base2Bytes `json:"allocated"`
+}
+
 type service struct {
-	logger               *log.DefaultLogger
+	logger *log.DefaultLogger
+	events events.Publisher
+
+	mu                   sync.Mutex
 	memTarget            units.Base2Bytes
 	memIncrement         units.Base2Bytes
 	memIncrementInterval time.Duration
 	steps                units.Base2Bytes
 	reader               *bytes.Reader
+	chunks               [][]byte
+	running              bool
+	fault                fault
+	// gcPercent is the garbage collection target percentage restored once
+	// the target is 0, gcOff is true while the garbage collector is off.
+	gcPercent int
+	gcOff     bool
+
+	allocated int64
+}
+
+// fault identifies the current memory usage target in the events.
+type fault struct {
+	id      string
+	trigger events.Trigger
+}
+
+// Option configures the service.
+type Option func(*service)
+
+// WithEvents publishes the memory usage lifecycle events to p.
+func WithEvents(p events.Publisher) Option {
+	return func(s *service) {
+		s.events = p
+	}
 }
 
 func New(
@@ -24,6 +72,7 @@ func New(
 	memTarget units.Base2Bytes,
 	memIncrement units.Base2Bytes,
 	memIncrementInterval time.Duration,
+	opts ...Option,
 ) *service {
 	logger.Info(
 		"Creating memory manager",
@@ -32,30 +81,217 @@ func New(
 		fields.Any("interval", memIncrementInterval),
 	)
 
-	logger.Info("Creating memory ballast")
-	debug.SetGCPercent(-1)
-	steps := memTarget / memIncrement
-	ballast := make([]byte, memTarget)
-	reader := bytes.NewReader(ballast)
+	s := &service{
+		logger:               logger,
+		memTarget:            memTarget,
+		memIncrement:         memIncrement,
+		memIncrementInterval: memIncrementInterval,
+		fault:                fault{id: events.NewFaultID(), trigger: events.TriggerFlag},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
 
-	return &service{
-		logger,
-		memTarget,
-		memIncrement,
-		memIncrementInterval,
-		steps,
-		reader,
+	s.sizeSteps()
+
+	return s
+}
+
+// sizeSteps computes the number of increments of the target. It must be
+// called with mu held.
+func (s *service) sizeSteps() {
+	if s.memIncrement > 0 {
+		s.steps = s.memTarget / s.memIncrement
+	} else {
+		s.steps = 0
+	}
+}
+
+// prepare sizes the steps and, while a target is set, creates the ballast
+// the memory increments are copied from and turns the garbage collector off
+// so that the memory allocated is never collected. The garbage collector is
+// turned back on once the target is 0. It must be called with mu held.
+func (s *service) prepare() {
+	s.sizeSteps()
+
+	if s.memTarget == 0 {
+		s.reader = nil
+		if s.gcOff {
+			s.logger.Info("Enabling garbage collector")
+			debug.SetGCPercent(s.gcPercent)
+			s.gcOff = false
+		}
+		return
+	}
+
+	if !s.gcOff {
+		s.logger.Info("Disabling garbage collector")
+		s.gcPercent = debug.SetGCPercent(-1)
+		s.gcOff = true
+	}
+	if s.reader == nil || s.reader.Size() != int64(s.memTarget) {
+		s.logger.Info("Creating memory ballast")
+		s.reader = bytes.NewReader(make([]byte, s.memTarget))
+	}
+}
+
+// Allocated returns the amount of memory the service has allocated so far.
+func (s *service) Allocated() units.Base2Bytes {
+	return units.Base2Bytes(atomic.LoadInt64(&s.allocated))
+}
+
+// Status returns the memory usage settings and the memory allocated so far.
+func (s *service) Status() Status {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return Status{
+		Settings: Settings{
+			Target:    s.memTarget,
+			Increment: s.memIncrement,
+			Interval:  chaos.Duration(s.memIncrementInterval),
+		},
+		Allocated: s.Allocated(),
 	}
 }
 
+// Set replaces the memory usage target. A zero increment or interval keeps
+// the current one. Memory above the new target is released immediately,
+// while memory below it is allocated one increment per interval. The events
+// of the new target report the trigger of ctx.
+func (s *service) Set(ctx context.Context, settings Settings) error {
+	if settings.Target < 0 || settings.Increment < 0 || settings.Interval < 0 {
+		return fmt.Errorf("target, increment and interval must not be negative")
+	}
+
+	s.mu.Lock()
+	if settings.Target > 0 && settings.Increment == 0 && s.memIncrement == 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("an increment is required")
+	}
+	if settings.Increment > 0 {
+		s.memIncrement = settings.Increment
+	}
+	if settings.Interval > 0 {
+		s.memIncrementInterval = time.Duration(settings.Interval)
+	}
+	s.memTarget = settings.Target
+	s.fault = fault{id: events.NewFaultID(), trigger: events.TriggerFrom(ctx)}
+	s.prepare()
+
+	s.logger.Info(
+		"Setting memory target",
+		fields.Any("target", s.memTarget),
+		fields.Any("increment", s.memIncrement),
+		fields.Any("interval", s.memIncrementInterval),
+	)
+
+	released := s.release()
+	grow := !s.running && s.Allocated() < s.steps*s.memIncrement
+	data, f := s.eventData(), s.fault
+	s.mu.Unlock()
+
+	if released {
+		// return the released chunks to the OS right away
+		debug.FreeOSMemory()
+		s.publish(f, events.TypeCancelled, "Memory released", data)
+	}
+	if grow {
+		go s.Start()
+	}
+
+	return nil
+}
+
+// release drops the chunks allocated above the target and returns true if
+// any was dropped. It must be called with mu held.
+func (s *service) release() bool {
+	released := false
+	for len(s.chunks) > 0 && s.Allocated() > s.memTarget {
+		last := len(s.chunks) - 1
+		atomic.AddInt64(&s.allocated, -int64(len(s.chunks[last])))
+		s.chunks[last] = nil
+		s.chunks = s.chunks[:last]
+		released = true
+	}
+	return released
+}
+
+// Start allocates one increment per interval until the target is reached.
 func (s *service) Start() {
-	for i, _ := units.ParseBase2Bytes("0B"); i < s.steps; i++ {
-		s.logger.Debug("Incrementing memory")
-		buf := make([]byte, s.memIncrement)
-		_, err := s.reader.Read(buf)
-		if err != nil {
-			s.logger.Error("", fields.Error(err))
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.prepare()
+	s.mu.Unlock()
+
+	for first := true; ; first = false {
+		interval, ok := s.increment(first)
+		if !ok {
+			if !first {
+				s.mu.Lock()
+				data, f := s.eventData(), s.fault
+				s.mu.Unlock()
+				s.publish(f, events.TypeTriggered, "Memory target reached", data)
+			}
+			return
 		}
-		time.Sleep(s.memIncrementInterval)
+		time.Sleep(interval)
+	}
+}
+
+// increment allocates one increment and returns the interval to wait before
+// the next one, or false once the target is reached.
+func (s *service) increment(first bool) (time.Duration, bool) {
+	s.mu.Lock()
+	allocated := s.Allocated()
+	if allocated >= s.steps*s.memIncrement {
+		s.running = false
+		s.mu.Unlock()
+		return 0, false
+	}
+
+	f, started := s.fault, s.eventData()
+	s.logger.Debug("Incrementing memory")
+	buf := make([]byte, s.memIncrement)
+	_, err := s.reader.ReadAt(buf, int64(allocated))
+	if err != nil {
+		s.logger.Error("", fields.Error(err))
+	}
+	s.chunks = append(s.chunks, buf)
+	atomic.AddInt64(&s.allocated, int64(s.memIncrement))
+	progress, interval := s.eventData(), s.memIncrementInterval
+	s.mu.Unlock()
+
+	if first {
+		s.publish(f, events.TypeStarted, "Memory usage growing", started)
+	}
+	s.publish(f, events.TypeProgress, "Memory incremented", progress)
+
+	return interval, true
+}
+
+// eventData returns the memory usage reported by the events. It must be called with mu held.
+func (s *service) eventData() map[string]interface{} {
+	return map[string]interface{}{
+		"allocated": s.Allocated().String(),
+		"target":    s.memTarget.String(),
+	}
+}
+
+func (s *service) publish(f fault, t events.Type, msg string, data map[string]interface{}) {
+	if s.events == nil {
+		return
 	}
+	s.events.Publish(events.Event{
+		Type:    t,
+		Source:  "memory",
+		FaultID: f.id,
+		Trigger: f.trigger,
+		Message: msg,
+		Data:    data,
+	})
 }
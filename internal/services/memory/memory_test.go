@@ -1,12 +1,17 @@
 package memory
 
 import (
+	"context"
+	"runtime/debug"
 	"testing"
 	"time"
 
 	"github.com/alecthomas/units"
 	"github.com/stretchr/testify/require"
 	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
 )
 
 func TestNew(t *testing.T) {
@@ -22,7 +27,8 @@ func TestNew(t *testing.T) {
 	require.Equal(t, memTarget, svc.memTarget)
 	require.Equal(t, memIncrement, svc.memIncrement)
 	require.Equal(t, memIncrementInterval, svc.memIncrementInterval)
-	require.NotNil(t, svc.reader)
+	// the ballast is created once the service starts
+	require.Nil(t, svc.reader)
 }
 
 func TestNew_CalculatesSteps(t *testing.T) {
@@ -89,13 +95,19 @@ func TestNew_WithDifferentSizes(t *testing.T) {
 	}
 }
 
-func TestNew_CreatesReader(t *testing.T) {
+func TestService_Prepare_CreatesReader(t *testing.T) {
 	logger := log.New(log.WithLevel("info"))
 	memTarget := 10 * units.MiB
 	memIncrement := 1 * units.MiB
 	memIncrementInterval := 100 * time.Millisecond
 
 	svc := New(logger, memTarget, memIncrement, memIncrementInterval)
+	svc.mu.Lock()
+	svc.prepare()
+	svc.mu.Unlock()
+	defer func() {
+		_ = svc.Set(context.Background(), Settings{})
+	}()
 
 	require.NotNil(t, svc.reader)
 
@@ -178,7 +190,6 @@ func TestService_Fields(t *testing.T) {
 	require.Equal(t, memIncrement, svc.memIncrement)
 	require.Equal(t, memIncrementInterval, svc.memIncrementInterval)
 	require.Equal(t, memTarget/memIncrement, svc.steps)
-	require.NotNil(t, svc.reader)
 }
 
 func TestService_Start_WithZeroSteps(t *testing.T) {
@@ -207,3 +218,141 @@ func TestService_Start_WithZeroSteps(t *testing.T) {
 		t.Fatal("Start did not complete in time")
 	}
 }
+
+func TestService_Allocated(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	memTarget := 4 * units.KiB
+	memIncrement := 1 * units.KiB
+	memIncrementInterval := 1 * time.Millisecond
+
+	svc := New(logger, memTarget, memIncrement, memIncrementInterval)
+	require.Equal(t, units.Base2Bytes(0), svc.Allocated())
+
+	svc.Start()
+
+	require.Equal(t, memTarget, svc.Allocated())
+}
+
+func TestService_Status(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, 4*units.KiB, units.KiB, time.Millisecond)
+	svc.Start()
+
+	require.Equal(t, Status{
+		Settings: Settings{
+			Target:    4 * units.KiB,
+			Increment: units.KiB,
+			Interval:  chaos.Duration(time.Millisecond),
+		},
+		Allocated: 4 * units.KiB,
+	}, svc.Status())
+}
+
+func TestService_Set_Grow(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, 0, 0, 0)
+	svc.Start()
+	require.Equal(t, units.Base2Bytes(0), svc.Allocated())
+
+	require.NoError(t, svc.Set(context.Background(), Settings{Target: 8 * units.KiB, Increment: 2 * units.KiB, Interval: chaos.Duration(time.Millisecond)}))
+
+	require.Eventually(t, func() bool {
+		return svc.Allocated() == 8*units.KiB
+	}, time.Second, 5*time.Millisecond)
+	require.Len(t, svc.chunks, 4)
+}
+
+func TestService_Set_Release(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, 8*units.KiB, 2*units.KiB, time.Millisecond)
+	svc.Start()
+	require.Equal(t, 8*units.KiB, svc.Allocated())
+
+	// keeps the current increment and interval
+	require.NoError(t, svc.Set(context.Background(), Settings{Target: 3 * units.KiB}))
+	require.Equal(t, 2*units.KiB, svc.Allocated())
+	require.Equal(t, 2*units.KiB, svc.Status().Increment)
+
+	require.NoError(t, svc.Set(context.Background(), Settings{}))
+	require.Equal(t, units.Base2Bytes(0), svc.Allocated())
+	require.Empty(t, svc.chunks)
+}
+
+func TestService_Set_Invalid(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, 0, 0, 0)
+
+	require.Error(t, svc.Set(context.Background(), Settings{Target: units.KiB}))
+	require.Error(t, svc.Set(context.Background(), Settings{Target: -1}))
+	require.Error(t, svc.Set(context.Background(), Settings{Increment: -1}))
+	require.Error(t, svc.Set(context.Background(), Settings{Interval: -1}))
+	require.Equal(t, Settings{}, svc.Status().Settings)
+}
+
+func TestService_Events(t *testing.T) {
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, 2*units.KiB, units.KiB, time.Millisecond, WithEvents(bus))
+	svc.Start()
+	require.NoError(t, svc.Set(context.Background(), Settings{}))
+
+	var (
+		types    []events.Type
+		faultIDs []string
+		triggers []events.Trigger
+	)
+	for len(ch) > 0 {
+		e := <-ch
+		require.Equal(t, "memory", e.Source)
+		types = append(types, e.Type)
+		faultIDs = append(faultIDs, e.FaultID)
+		triggers = append(triggers, e.Trigger)
+	}
+	require.Equal(t, []events.Type{
+		events.TypeStarted,
+		events.TypeProgress,
+		events.TypeProgress,
+		events.TypeTriggered,
+		events.TypeCancelled,
+	}, types)
+
+	// the target set by the flags grows under one fault id, the release is a new fault
+	require.Equal(t, []events.Trigger{
+		events.TriggerFlag,
+		events.TriggerFlag,
+		events.TriggerFlag,
+		events.TriggerFlag,
+		events.TriggerAPI,
+	}, triggers)
+	require.NotEmpty(t, faultIDs[0])
+	require.Equal(t, faultIDs[0], faultIDs[3])
+	require.NotEqual(t, faultIDs[0], faultIDs[4])
+}
+
+func TestService_GC(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	gcPercent := func() int {
+		p := debug.SetGCPercent(100)
+		debug.SetGCPercent(p)
+		return p
+	}
+	before := gcPercent()
+
+	// the garbage collector runs without a target
+	svc := New(logger, 0, 0, 0)
+	svc.Start()
+	require.Equal(t, before, gcPercent())
+
+	require.NoError(t, svc.Set(context.Background(), Settings{Target: 2 * units.KiB, Increment: units.KiB, Interval: chaos.Duration(time.Millisecond)}))
+	require.Equal(t, -1, gcPercent())
+	require.Eventually(t, func() bool {
+		return svc.Allocated() == 2*units.KiB
+	}, time.Second, time.Millisecond)
+
+	require.NoError(t, svc.Set(context.Background(), Settings{}))
+	require.Equal(t, before, gcPercent())
+	require.Nil(t, svc.reader)
+}
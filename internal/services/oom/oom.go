@@ -0,0 +1,150 @@
+package oom
+
+import (
+	"sync"
+	"time"
+
+	"github.com/alecthomas/units"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/cgroup"
+)
+
+type service struct {
+	logger    *log.DefaultLogger
+	root      string
+	interval  time.Duration
+	allocated func() units.Base2Bytes
+
+	mu    sync.RWMutex
+	stats *cgroup.MemoryStats
+}
+
+// New returns a service watching the memory controller of the cgroup mounted at root.
+// allocated reports the memory allocated by crashlooper itself and may be nil.
+func New(
+	logger *log.DefaultLogger,
+	root string,
+	interval time.Duration,
+	allocated func() units.Base2Bytes,
+) *service {
+	logger.Info(
+		"Creating OOM monitor",
+		fields.String("root", root),
+		fields.Duration("interval", interval),
+	)
+
+	return &service{
+		logger:    logger,
+		root:      root,
+		interval:  interval,
+		allocated: allocated,
+	}
+}
+
+// Stats returns the last memory statistics read from the cgroup.
+// It returns nil until the cgroup has been read once.
+func (s *service) Stats() *cgroup.MemoryStats {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.stats
+}
+
+// Start polls the cgroup memory controller and logs memory events as they occur.
+// It returns right away when no memory controller can be found.
+func (s *service) Start() {
+	stats, err := cgroup.ReadMemory(s.root)
+	if err != nil {
+		s.logger.Warn("Unable to monitor cgroup memory", fields.Error(err))
+		return
+	}
+
+	s.logger.Info(
+		"Monitoring cgroup memory",
+		fields.String("version", string(stats.Version)),
+		fields.Any("limit", units.Base2Bytes(stats.Limit)),
+	)
+	s.setStats(stats)
+
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		s.poll()
+	}
+}
+
+func (s *service) poll() {
+	stats, err := cgroup.ReadMemory(s.root)
+	if err != nil {
+		s.logger.Error("Unable to read cgroup memory", fields.Error(err))
+		return
+	}
+
+	prev := s.Stats()
+	s.setStats(stats)
+	if prev != nil {
+		s.report(prev, stats)
+	}
+}
+
+func (s *service) setStats(stats *cgroup.MemoryStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stats = stats
+}
+
+// report logs the changes between two consecutive reads of the cgroup.
+func (s *service) report(prev, cur *cgroup.MemoryStats) {
+	var allocated units.Base2Bytes
+	if s.allocated != nil {
+		allocated = s.allocated()
+	}
+
+	counters := []struct {
+		event     string
+		prev, cur uint64
+	}{
+		{"high", prev.Events.High, cur.Events.High},
+		{"max", prev.Events.Max, cur.Events.Max},
+		{"oom", prev.Events.OOM, cur.Events.OOM},
+		{"oom_kill", prev.Events.OOMKill, cur.Events.OOMKill},
+	}
+
+	for _, c := range counters {
+		if c.cur <= c.prev {
+			continue
+		}
+		s.logger.Warn(
+			"Memory event",
+			fields.String("event", c.event),
+			fields.Int("count", int(c.cur)),
+			fields.Int("delta", int(c.cur-c.prev)),
+			fields.Any("current", units.Base2Bytes(cur.Current)),
+			fields.Any("limit", units.Base2Bytes(cur.Limit)),
+			fields.Any("allocated", allocated),
+		)
+	}
+
+	if cur.UnderOOM && !prev.UnderOOM {
+		s.logger.Warn(
+			"Memory cgroup under OOM",
+			fields.Any("current", units.Base2Bytes(cur.Current)),
+			fields.Any("limit", units.Base2Bytes(cur.Limit)),
+			fields.Any("allocated", allocated),
+		)
+	}
+
+	if cur.Pressure != nil && prev.Pressure != nil && cur.Pressure.Some.Total > prev.Pressure.Some.Total {
+		// PSI totals are expressed in microseconds
+		stalled := time.Duration(cur.Pressure.Some.Total-prev.Pressure.Some.Total) * time.Microsecond
+		s.logger.Info(
+			"Memory pressure",
+			fields.Duration("stalled", stalled),
+			fields.Any("some", cur.Pressure.Some),
+			fields.Any("full", cur.Pressure.Full),
+			fields.Any("current", units.Base2Bytes(cur.Current)),
+			fields.Any("allocated", allocated),
+		)
+	}
+}
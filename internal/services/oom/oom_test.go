@@ -0,0 +1,117 @@
+package oom
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/cgroup"
+)
+
+func writeCgroupV2(t *testing.T, root string, current string, events string) {
+	t.Helper()
+	files := map[string]string{
+		"memory.current":  current,
+		"memory.max":      "31457280",
+		"memory.events":   events,
+		"memory.pressure": "some avg10=0.00 avg60=0.00 avg300=0.00 total=0\nfull avg10=0.00 avg60=0.00 avg300=0.00 total=0\n",
+	}
+	for name, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(root, name), []byte(content), 0o644))
+	}
+}
+
+func TestNew(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	allocated := func() units.Base2Bytes { return 10 * units.MiB }
+
+	svc := New(logger, "/sys/fs/cgroup", time.Second, allocated)
+
+	require.NotNil(t, svc)
+	require.Equal(t, logger, svc.logger)
+	require.Equal(t, "/sys/fs/cgroup", svc.root)
+	require.Equal(t, time.Second, svc.interval)
+	require.NotNil(t, svc.allocated)
+	require.Nil(t, svc.Stats())
+}
+
+func TestService_Start_NoCgroup(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, t.TempDir(), time.Millisecond, nil)
+
+	done := make(chan bool)
+	go func() {
+		svc.Start()
+		done <- true
+	}()
+
+	select {
+	case <-done:
+		require.Nil(t, svc.Stats())
+	case <-time.After(time.Second):
+		t.Fatal("Start did not return without a cgroup")
+	}
+}
+
+func TestService_Poll(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupV2(t, root, "1048576", "low 0\nhigh 0\nmax 0\noom 0\noom_kill 0\n")
+
+	logger := log.New(log.WithLevel("debug"))
+	svc := New(logger, root, time.Second, func() units.Base2Bytes { return units.MiB })
+
+	svc.poll()
+	stats := svc.Stats()
+	require.NotNil(t, stats)
+	require.Equal(t, cgroup.V2, stats.Version)
+	require.Equal(t, uint64(1048576), stats.Current)
+	require.Equal(t, uint64(0), stats.Events.OOMKill)
+
+	writeCgroupV2(t, root, "31457280", "low 0\nhigh 2\nmax 5\noom 1\noom_kill 1\n")
+
+	svc.poll()
+	stats = svc.Stats()
+	require.Equal(t, uint64(31457280), stats.Current)
+	require.Equal(t, cgroup.MemoryEvents{High: 2, Max: 5, OOM: 1, OOMKill: 1}, stats.Events)
+}
+
+func TestService_Poll_KeepsLastStatsOnError(t *testing.T) {
+	root := t.TempDir()
+	writeCgroupV2(t, root, "4096", "low 0\nhigh 0\nmax 0\noom 0\noom_kill 0\n")
+
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, root, time.Second, nil)
+
+	svc.poll()
+	require.NotNil(t, svc.Stats())
+
+	require.NoError(t, os.WriteFile(filepath.Join(root, "memory.current"), []byte("garbage"), 0o644))
+
+	svc.poll()
+	require.Equal(t, uint64(4096), svc.Stats().Current)
+}
+
+func TestService_Report(t *testing.T) {
+	logger := log.New(log.WithLevel("debug"))
+	svc := New(logger, t.TempDir(), time.Second, nil)
+
+	prev := &cgroup.MemoryStats{
+		Version:  cgroup.V2,
+		Pressure: &cgroup.PSI{},
+	}
+	cur := &cgroup.MemoryStats{
+		Version:  cgroup.V2,
+		UnderOOM: true,
+		Events:   cgroup.MemoryEvents{High: 1, OOMKill: 1},
+		Pressure: &cgroup.PSI{Some: cgroup.Pressure{Total: 1500}},
+	}
+
+	require.NotPanics(t, func() {
+		svc.report(prev, cur)
+	})
+}
@@ -0,0 +1,416 @@
+// Package pids spawns threads or processes until a target count or the pids
+// limit of the cgroup is reached, to test the pids limits of the pods and the
+// node protections without a fork bomb.
+package pids
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/cgroup"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// Mode is what the service spawns.
+type Mode string
+
+const (
+	// ModeThreads spawns OS threads in the crashlooper process.
+	ModeThreads Mode = "threads"
+	// ModeProcesses spawns child processes sleeping until they are killed.
+	ModeProcesses Mode = "processes"
+)
+
+const (
+	// reserve is the number of pids left free below the cgroup limit, the
+	// Go runtime crashing when it fails to create a thread.
+	reserve = 16
+	// maxThreads bounds the threads spawned below the 10000 threads the Go
+	// runtime allows by default.
+	maxThreads = 9000
+	// processTasks is the number of pids counted for a spawned process, its
+	// Go runtime running a few threads even with GOMAXPROCS=1.
+	processTasks = 8
+)
+
+// Settings describes how the spawned threads or processes grow.
+type Settings struct {
+	Mode      Mode           `json:"mode"`
+	Target    int            `json:"target"`
+	Increment int            `json:"increment"`
+	Interval  chaos.Duration `json:"interval"`
+}
+
+// Status describes the target and the threads or processes spawned so far.
+type Status struct {
+	Settings
+	Spawned int `json:"spawned"`
+	// LimitReached is true when the growth stopped short of the target, at
+	// the cgroup limit or on a spawn failure.
+	LimitReached bool `json:"limit_reached"`
+	// Cgroup is the pids controller of the cgroup, nil when none is found.
+	Cgroup *cgroup.PidsStats `json:"cgroup,omitempty"`
+}
+
+type service struct {
+	logger     *log.DefaultLogger
+	events     events.Publisher
+	cgroupRoot string
+	command    []string
+
+	mu           sync.Mutex
+	settings     Settings
+	threads      []chan struct{}
+	processes    []*os.Process
+	limitReached bool
+	running      bool
+	fault        fault
+}
+
+// fault identifies the current target in the events.
+type fault struct {
+	id      string
+	trigger events.Trigger
+}
+
+// Option configures the service.
+type Option func(*service)
+
+// WithEvents publishes the growth lifecycle events to p.
+func WithEvents(p events.Publisher) Option {
+	return func(s *service) {
+		s.events = p
+	}
+}
+
+// WithCgroupRoot sets the cgroup filesystem the pids limit is read from,
+// cgroup.DefaultRoot by default.
+func WithCgroupRoot(root string) Option {
+	return func(s *service) {
+		s.cgroupRoot = root
+	}
+}
+
+// WithCommand sets the command line of the processes spawned in processes
+// mode, which must run until they are killed. The processes mode is not
+// available without it.
+func WithCommand(command []string) Option {
+	return func(s *service) {
+		s.command = command
+	}
+}
+
+// New returns a service growing the threads or processes as settings, the
+// mode defaulting to threads.
+func New(logger *log.DefaultLogger, settings Settings, opts ...Option) *service {
+	if settings.Mode == "" {
+		settings.Mode = ModeThreads
+	}
+	logger.Info(
+		"Creating pids manager",
+		fields.String("mode", string(settings.Mode)),
+		fields.Int("target", settings.Target),
+		fields.Int("increment", settings.Increment),
+		fields.Duration("interval", time.Duration(settings.Interval)),
+	)
+
+	s := &service{
+		logger:     logger,
+		cgroupRoot: cgroup.DefaultRoot,
+		settings:   settings,
+		fault:      fault{id: events.NewFaultID(), trigger: events.TriggerFlag},
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Status returns the settings, the threads or processes spawned so far and
+// the pids controller of the cgroup.
+func (s *service) Status() Status {
+	s.mu.Lock()
+	status := Status{Settings: s.settings, Spawned: s.spawned(), LimitReached: s.limitReached}
+	s.mu.Unlock()
+
+	if stats, err := cgroup.ReadPids(s.cgroupRoot); err == nil {
+		status.Cgroup = stats
+	}
+	return status
+}
+
+// Set replaces the target. An empty mode, a zero increment or interval keeps
+// the current one, and changing the mode releases everything spawned in the
+// previous one. The threads or processes above the new target are released
+// immediately, while the ones below are spawned one increment per interval.
+// The events of the new target report the trigger of ctx.
+func (s *service) Set(ctx context.Context, settings Settings) error {
+	if settings.Target < 0 || settings.Increment < 0 || settings.Interval < 0 {
+		return fmt.Errorf("target, increment and interval must not be negative")
+	}
+
+	s.mu.Lock()
+	mode := settings.Mode
+	if mode == "" {
+		mode = s.settings.Mode
+	}
+	if err := s.validate(mode, settings); err != nil {
+		s.mu.Unlock()
+		return err
+	}
+
+	released := false
+	if mode != s.settings.Mode {
+		s.settings.Target = 0
+		released = s.release()
+		s.settings.Mode = mode
+	}
+	if settings.Increment > 0 {
+		s.settings.Increment = settings.Increment
+	}
+	if settings.Interval > 0 {
+		s.settings.Interval = settings.Interval
+	}
+	s.settings.Target = settings.Target
+	s.limitReached = false
+	s.fault = fault{id: events.NewFaultID(), trigger: events.TriggerFrom(ctx)}
+
+	s.logger.Info(
+		"Setting pids target",
+		fields.String("mode", string(s.settings.Mode)),
+		fields.Int("target", s.settings.Target),
+		fields.Int("increment", s.settings.Increment),
+		fields.Duration("interval", time.Duration(s.settings.Interval)),
+	)
+
+	released = s.release() || released
+	grow := !s.running && s.spawned() < s.settings.Target
+	data, f := s.eventData(), s.fault
+	s.mu.Unlock()
+
+	if released {
+		s.publish(f, events.TypeCancelled, "Pids released", data)
+	}
+	if grow {
+		go s.Start()
+	}
+
+	return nil
+}
+
+// validate returns an error if settings cannot be applied in mode. It must
+// be called with mu held.
+func (s *service) validate(mode Mode, settings Settings) error {
+	switch mode {
+	case ModeThreads:
+		if settings.Target > maxThreads {
+			return fmt.Errorf("the threads target must not exceed %d", maxThreads)
+		}
+	case ModeProcesses:
+		if len(s.command) == 0 {
+			return fmt.Errorf("the processes mode is not supported on %s", runtime.GOOS)
+		}
+	default:
+		return fmt.Errorf("unknown mode %q, must be one of threads or processes", mode)
+	}
+	if settings.Target > 0 && settings.Increment == 0 && s.settings.Increment == 0 {
+		return fmt.Errorf("an increment is required")
+	}
+	return nil
+}
+
+// Start spawns one increment per interval until the target or the limit is
+// reached.
+func (s *service) Start() {
+	s.mu.Lock()
+	if s.running {
+		s.mu.Unlock()
+		return
+	}
+	s.running = true
+	s.mu.Unlock()
+
+	for first := true; ; first = false {
+		interval, ok := s.increment(first)
+		if !ok {
+			if !first {
+				s.mu.Lock()
+				msg := "Pids target reached"
+				if s.limitReached {
+					msg = "Pids limit reached"
+				}
+				data, f := s.eventData(), s.fault
+				s.mu.Unlock()
+				s.publish(f, events.TypeTriggered, msg, data)
+			}
+			return
+		}
+		time.Sleep(interval)
+	}
+}
+
+// increment spawns one increment and returns the interval to wait before
+// the next one, or false once the target or the limit is reached.
+func (s *service) increment(first bool) (time.Duration, bool) {
+	s.mu.Lock()
+	if s.limitReached || s.spawned() >= s.settings.Target {
+		s.running = false
+		s.mu.Unlock()
+		return 0, false
+	}
+
+	f, started := s.fault, s.eventData()
+	n := s.settings.Target - s.spawned()
+	if n > s.settings.Increment {
+		n = s.settings.Increment
+	}
+	s.logger.Debug("Incrementing pids", fields.Int("count", n))
+	spawned, start := 0, int64(-1)
+	for ; spawned < n; spawned++ {
+		if !s.room(&start, spawned) {
+			s.limitReached = true
+			break
+		}
+		if err := s.spawn(); err != nil {
+			s.logger.Warn("Unable to spawn", fields.String("mode", string(s.settings.Mode)), fields.Error(err))
+			s.limitReached = true
+			break
+		}
+	}
+	progress := s.eventData()
+	interval := time.Duration(s.settings.Interval)
+	if s.limitReached {
+		s.logger.Warn("Pids limit reached", fields.Int("spawned", s.spawned()))
+		// the next increment stops right away
+		interval = 0
+	}
+	s.mu.Unlock()
+
+	if first {
+		s.publish(f, events.TypeStarted, "Pids growing", started)
+	}
+	if spawned > 0 {
+		s.publish(f, events.TypeProgress, "Pids incremented", progress)
+	}
+	return interval, true
+}
+
+// room returns false if spawning one more thread or process would use the
+// reserve below the cgroup limit. The cgroup is read before each spawn, and
+// start is the pids in use at the first read of the increment, to which the
+// spawned threads or processes are added as their tasks may not all be
+// started yet. It must be called with mu held.
+func (s *service) room(start *int64, spawned int) bool {
+	stats, err := cgroup.ReadPids(s.cgroupRoot)
+	if err != nil || stats.Limit == 0 {
+		return true
+	}
+	cost := int64(1)
+	if s.settings.Mode == ModeProcesses {
+		cost = processTasks
+	}
+	if *start < 0 {
+		*start = int64(stats.Current)
+	}
+	used := max(int64(stats.Current), *start+int64(spawned)*cost)
+	return int64(stats.Limit)-used-reserve >= cost
+}
+
+// spawn starts a thread or a process. It must be called with mu held.
+func (s *service) spawn() error {
+	if s.settings.Mode == ModeProcesses {
+		p, err := os.StartProcess(s.command[0], s.command, &os.ProcAttr{
+			// the fewer threads per process the more processes
+			Env:   append(os.Environ(), "GOMAXPROCS=1"),
+			Files: []*os.File{os.Stdin, os.Stdout, os.Stderr},
+		})
+		if err != nil {
+			return errors.Wrap(err, "unable to start process")
+		}
+		s.processes = append(s.processes, p)
+		return nil
+	}
+
+	if len(s.threads) >= maxThreads {
+		return errors.Errorf("the %d threads of the Go runtime limit are spawned", maxThreads)
+	}
+	release := make(chan struct{})
+	started := make(chan struct{})
+	go func() {
+		// the thread is held by the goroutine locked to it, and terminated
+		// once the goroutine exits locked
+		runtime.LockOSThread()
+		close(started)
+		<-release
+	}()
+	<-started
+	s.threads = append(s.threads, release)
+	return nil
+}
+
+// release stops the threads or processes spawned above the target and
+// returns true if any was stopped. It must be called with mu held.
+func (s *service) release() bool {
+	released := false
+	for len(s.threads) > s.settings.Target {
+		last := len(s.threads) - 1
+		close(s.threads[last])
+		s.threads = s.threads[:last]
+		released = true
+	}
+	for len(s.processes) > s.settings.Target {
+		last := len(s.processes) - 1
+		p := s.processes[last]
+		if err := p.Kill(); err != nil {
+			s.logger.Warn("Unable to kill process", fields.Int("pid", p.Pid), fields.Error(err))
+		}
+		_, _ = p.Wait()
+		s.processes = s.processes[:last]
+		released = true
+	}
+	return released
+}
+
+// spawned returns the number of threads or processes spawned. It must be
+// called with mu held.
+func (s *service) spawned() int {
+	return len(s.threads) + len(s.processes)
+}
+
+// eventData returns the growth reported by the events. It must be called
+// with mu held.
+func (s *service) eventData() map[string]interface{} {
+	data := map[string]interface{}{
+		"mode":    string(s.settings.Mode),
+		"spawned": s.spawned(),
+		"target":  s.settings.Target,
+	}
+	if stats, err := cgroup.ReadPids(s.cgroupRoot); err == nil {
+		data["limit"] = stats.Limit
+		data["current"] = stats.Current
+	}
+	return data
+}
+
+func (s *service) publish(f fault, t events.Type, msg string, data map[string]interface{}) {
+	if s.events == nil {
+		return
+	}
+	s.events.Publish(events.Event{
+		Type:    t,
+		Source:  "pids",
+		FaultID: f.id,
+		Trigger: f.trigger,
+		Message: msg,
+		Data:    data,
+	})
+}
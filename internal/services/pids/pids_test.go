@@ -0,0 +1,188 @@
+package pids
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/cgroup"
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// sleeperEnv makes the test binary sleep until it is killed, as the
+// processes spawned in processes mode.
+const sleeperEnv = "PIDS_TEST_SLEEPER"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(sleeperEnv) != "" {
+		time.Sleep(time.Hour)
+		os.Exit(0)
+	}
+	// the processes inherit the environment
+	_ = os.Setenv(sleeperEnv, "1")
+	os.Exit(m.Run())
+}
+
+func newService(t *testing.T, settings Settings, opts ...Option) *service {
+	t.Helper()
+	logger := log.New(log.WithLevel("info"))
+	// no pids controller unless the test writes one
+	opts = append([]Option{WithCgroupRoot(t.TempDir())}, opts...)
+	svc := New(logger, settings, opts...)
+	t.Cleanup(func() {
+		_ = svc.Set(context.Background(), Settings{})
+	})
+	return svc
+}
+
+func TestNew(t *testing.T) {
+	svc := newService(t, Settings{Target: 4, Increment: 2})
+	require.Equal(t, Settings{Mode: ModeThreads, Target: 4, Increment: 2}, svc.Status().Settings)
+	require.Equal(t, cgroup.DefaultRoot, New(log.New(), Settings{}).cgroupRoot)
+}
+
+func TestService_Start(t *testing.T) {
+	svc := newService(t, Settings{Target: 5, Increment: 2, Interval: chaos.Duration(time.Millisecond)})
+	svc.Start()
+
+	require.Equal(t, Status{
+		Settings: Settings{Mode: ModeThreads, Target: 5, Increment: 2, Interval: chaos.Duration(time.Millisecond)},
+		Spawned:  5,
+	}, svc.Status())
+}
+
+func TestService_Set_Grow(t *testing.T) {
+	svc := newService(t, Settings{})
+	svc.Start()
+	require.Zero(t, svc.Status().Spawned)
+
+	require.NoError(t, svc.Set(context.Background(), Settings{Target: 6, Increment: 2, Interval: chaos.Duration(time.Millisecond)}))
+	require.Eventually(t, func() bool {
+		return svc.Status().Spawned == 6
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestService_Set_Release(t *testing.T) {
+	svc := newService(t, Settings{Target: 6, Increment: 3, Interval: chaos.Duration(time.Millisecond)})
+	svc.Start()
+	require.Len(t, svc.threads, 6)
+
+	// keeps the current increment and interval
+	require.NoError(t, svc.Set(context.Background(), Settings{Target: 2}))
+	require.Equal(t, 2, svc.Status().Spawned)
+	require.Equal(t, 3, svc.Status().Increment)
+
+	require.NoError(t, svc.Set(context.Background(), Settings{}))
+	require.Empty(t, svc.threads)
+}
+
+func TestService_Events(t *testing.T) {
+	var svc *service
+	var published []events.Type
+	// the hooks run synchronously, reading the status deadlocks if mu is held
+	bus := events.NewBus(events.WithHook(func(e events.Event) {
+		svc.Status()
+		published = append(published, e.Type)
+	}))
+	svc = newService(t, Settings{Target: 4, Increment: 2, Interval: chaos.Duration(time.Millisecond)}, WithEvents(bus))
+	svc.Start()
+
+	require.Equal(t, []events.Type{
+		events.TypeStarted, events.TypeProgress, events.TypeProgress, events.TypeTriggered,
+	}, published)
+}
+
+func TestService_Set_Invalid(t *testing.T) {
+	svc := newService(t, Settings{})
+
+	require.Error(t, svc.Set(context.Background(), Settings{Target: 1}))
+	require.Error(t, svc.Set(context.Background(), Settings{Target: -1}))
+	require.Error(t, svc.Set(context.Background(), Settings{Increment: -1}))
+	require.Error(t, svc.Set(context.Background(), Settings{Interval: -1}))
+	require.Error(t, svc.Set(context.Background(), Settings{Mode: "forks", Target: 1, Increment: 1}))
+	require.Error(t, svc.Set(context.Background(), Settings{Target: maxThreads + 1, Increment: 1}))
+	// the processes mode requires a command
+	require.Error(t, svc.Set(context.Background(), Settings{Mode: ModeProcesses, Target: 1, Increment: 1}))
+	require.Equal(t, Settings{Mode: ModeThreads}, svc.Status().Settings)
+}
+
+func TestService_Limit(t *testing.T) {
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pids.max"), []byte("40\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pids.current"), []byte("20\n"), 0o644))
+
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	// 4 pids are free above the reserve
+	svc := newService(t, Settings{}, WithCgroupRoot(root), WithEvents(bus))
+	require.NoError(t, svc.Set(context.Background(), Settings{Target: 10, Increment: 10, Interval: chaos.Duration(time.Millisecond)}))
+	require.Eventually(t, func() bool {
+		return len(ch) == 3
+	}, time.Second, 5*time.Millisecond)
+
+	status := svc.Status()
+	require.Equal(t, 4, status.Spawned)
+	require.True(t, status.LimitReached)
+	require.Equal(t, &cgroup.PidsStats{Version: cgroup.V2, Current: 20, Limit: 40}, status.Cgroup)
+
+	var types []events.Type
+	for len(ch) > 0 {
+		e := <-ch
+		require.Equal(t, "pids", e.Source)
+		require.Equal(t, events.TriggerAPI, e.Trigger)
+		types = append(types, e.Type)
+	}
+	require.Equal(t, []events.Type{events.TypeStarted, events.TypeProgress, events.TypeTriggered}, types)
+
+	// a new target grows again
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pids.max"), []byte("max\n"), 0o644))
+	require.NoError(t, svc.Set(context.Background(), Settings{Target: 6}))
+	require.Eventually(t, func() bool {
+		return svc.Status().Spawned == 6
+	}, time.Second, 5*time.Millisecond)
+	require.False(t, svc.Status().LimitReached)
+}
+
+func TestService_Processes(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+
+	svc := newService(t, Settings{Increment: 1, Interval: chaos.Duration(time.Millisecond)}, WithCommand([]string{exe}))
+	require.NoError(t, svc.Set(context.Background(), Settings{Mode: ModeProcesses, Target: 2}))
+	require.Eventually(t, func() bool {
+		return svc.Status().Spawned == 2
+	}, 5*time.Second, 5*time.Millisecond)
+	require.Len(t, svc.processes, 2)
+
+	// changing the mode kills the processes
+	require.NoError(t, svc.Set(context.Background(), Settings{Mode: ModeThreads, Target: 1}))
+	require.Empty(t, svc.processes)
+	require.Eventually(t, func() bool {
+		return svc.Status().Spawned == 1
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestService_Limit_Processes(t *testing.T) {
+	exe, err := os.Executable()
+	require.NoError(t, err)
+	root := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pids.max"), []byte("60\n"), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "pids.current"), []byte("20\n"), 0o644))
+
+	// 24 pids are free above the reserve, each process counting for the
+	// tasks of its Go runtime
+	svc := newService(t, Settings{}, WithCgroupRoot(root), WithCommand([]string{exe}))
+	require.NoError(t, svc.Set(context.Background(), Settings{Mode: ModeProcesses, Target: 10, Increment: 10, Interval: chaos.Duration(time.Millisecond)}))
+	require.Eventually(t, func() bool {
+		return svc.Status().LimitReached
+	}, 5*time.Second, 5*time.Millisecond)
+	require.Equal(t, 24/processTasks, svc.Status().Spawned)
+}
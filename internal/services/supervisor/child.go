@@ -0,0 +1,106 @@
+//go:build unix
+
+package supervisor
+
+import (
+	"math"
+	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Behavior is what a child does.
+type Behavior string
+
+const (
+	// BehaviorExit exits with the exit code after the lifetime.
+	BehaviorExit Behavior = "exit"
+	// BehaviorKill kills itself with SIGKILL after the lifetime, as a crash.
+	BehaviorKill Behavior = "kill"
+	// BehaviorOrphan spawns a grandchild exiting after the lifetime and
+	// exits right away, the grandchild being orphaned.
+	BehaviorOrphan Behavior = "orphan"
+	// BehaviorIgnoreSignals ignores SIGTERM, SIGINT and SIGHUP and exits
+	// after the lifetime, so that only SIGKILL stops it earlier.
+	BehaviorIgnoreSignals Behavior = "ignore-signals"
+)
+
+// ChildConfig configures a child.
+type ChildConfig struct {
+	Behavior Behavior
+	// Lifetime is the time before the child exits, 0 never exits until
+	// the child is killed.
+	Lifetime time.Duration
+	ExitCode int
+}
+
+// Validate returns an error if the configuration is not usable.
+func (c ChildConfig) Validate() error {
+	switch c.Behavior {
+	case BehaviorExit, BehaviorKill, BehaviorOrphan, BehaviorIgnoreSignals:
+	default:
+		return errors.Errorf("unknown child behavior %q, must be one of exit, kill, orphan or ignore-signals", c.Behavior)
+	}
+	if c.Lifetime < 0 {
+		return errors.New("the child lifetime must not be negative")
+	}
+	if c.ExitCode < 0 || c.ExitCode > 255 {
+		return errors.Errorf("invalid exit code %d, must be in [0, 255]", c.ExitCode)
+	}
+	return nil
+}
+
+// Args returns the command line arguments of the child configuration.
+func (c ChildConfig) Args() []string {
+	return []string{
+		"--behavior", string(c.Behavior),
+		"--lifetime", c.Lifetime.String(),
+		"--exit-code", strconv.Itoa(c.ExitCode),
+	}
+}
+
+// RunChild runs the child of cfg in the current process and returns its
+// exit code. command is the command line of the grandchild of an orphan.
+func RunChild(cfg ChildConfig, command []string) int {
+	switch cfg.Behavior {
+	case BehaviorOrphan:
+		grandchild := ChildConfig{Behavior: BehaviorExit, Lifetime: cfg.Lifetime, ExitCode: cfg.ExitCode}
+		p, err := start(command, grandchild)
+		if err != nil {
+			return 1
+		}
+		_ = p.Release()
+		return 0
+	case BehaviorIgnoreSignals:
+		signal.Ignore(syscall.SIGTERM, syscall.SIGINT, syscall.SIGHUP)
+	}
+
+	lifetime := cfg.Lifetime
+	if lifetime == 0 {
+		lifetime = math.MaxInt64
+	}
+	time.Sleep(lifetime)
+
+	if cfg.Behavior == BehaviorKill {
+		_ = syscall.Kill(os.Getpid(), syscall.SIGKILL)
+	}
+	return cfg.ExitCode
+}
+
+// start starts command as a child of cfg, sharing the standard streams of
+// the current process.
+func start(command []string, cfg ChildConfig) (*os.Process, error) {
+	if len(command) == 0 {
+		return nil, errors.New("no child command")
+	}
+	args := append(append([]string{}, command...), cfg.Args()...)
+	p, err := os.StartProcess(command[0], args, &os.ProcAttr{Files: []*os.File{os.Stdin, os.Stdout, os.Stderr}})
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to start child")
+	}
+	return p, nil
+}
@@ -0,0 +1,63 @@
+//go:build linux
+
+package supervisor
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/pkg/errors"
+	"golang.org/x/sys/unix"
+)
+
+// setSubreaper makes the current process the child subreaper of its
+// descendants.
+func setSubreaper() error {
+	if err := unix.Prctl(unix.PR_SET_CHILD_SUBREAPER, 1, 0, 0, 0); err != nil {
+		return errors.Wrap(err, "unable to set child subreaper")
+	}
+	return nil
+}
+
+// zombies returns the number of zombie children of ppid, read from /proc.
+func zombies(ppid int) (int, bool) {
+	paths, err := filepath.Glob("/proc/[0-9]*/stat")
+	if err != nil {
+		return 0, false
+	}
+
+	n := 0
+	for _, path := range paths {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			// the process exited since the glob
+			continue
+		}
+		state, parent, ok := parseStat(b)
+		if ok && state == 'Z' && parent == ppid {
+			n++
+		}
+	}
+	return n, true
+}
+
+// parseStat returns the state and the parent pid of a /proc/<pid>/stat
+// line, "pid (comm) state ppid ...", comm possibly holding spaces and
+// parentheses.
+func parseStat(b []byte) (byte, int, bool) {
+	i := bytes.LastIndexByte(b, ')')
+	if i < 0 {
+		return 0, 0, false
+	}
+	f := bytes.Fields(b[i+1:])
+	if len(f) < 2 || len(f[0]) != 1 {
+		return 0, 0, false
+	}
+	ppid, err := strconv.Atoi(string(f[1]))
+	if err != nil {
+		return 0, 0, false
+	}
+	return f[0][0], ppid, true
+}
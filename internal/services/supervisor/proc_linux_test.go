@@ -0,0 +1,19 @@
+//go:build linux
+
+package supervisor
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStat(t *testing.T) {
+	state, ppid, ok := parseStat([]byte("4242 (crash (looper)) Z 1 4242 4242 0 -1"))
+	require.True(t, ok)
+	require.Equal(t, byte('Z'), state)
+	require.Equal(t, 1, ppid)
+
+	_, _, ok = parseStat([]byte("4242 crashlooper"))
+	require.False(t, ok)
+}
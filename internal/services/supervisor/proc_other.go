@@ -0,0 +1,15 @@
+//go:build unix && !linux
+
+package supervisor
+
+import "github.com/pkg/errors"
+
+// setSubreaper is only supported on linux.
+func setSubreaper() error {
+	return errors.New("a child subreaper requires linux")
+}
+
+// zombies is only reported on linux, from /proc.
+func zombies(ppid int) (int, bool) {
+	return 0, false
+}
@@ -0,0 +1,312 @@
+//go:build unix
+
+// Package supervisor spawns child processes that exit, crash, get orphaned or
+// ignore the signals, reaping them or leaving them as zombies, so that the
+// PID 1 duties of an image, or of the init it runs such as tini or dumb-init,
+// can be tested.
+package supervisor
+
+import (
+	"os"
+	"os/signal"
+	"sort"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+)
+
+// SignalHandling is how the supervisor handles SIGTERM and SIGINT.
+type SignalHandling string
+
+const (
+	// SignalsForward forwards the signal to the children, waits for them up
+	// to the grace period, kills the remaining ones and exits.
+	SignalsForward SignalHandling = "forward"
+	// SignalsExit exits right away, leaving the children running.
+	SignalsExit SignalHandling = "exit"
+	// SignalsIgnore ignores the signal, as a PID 1 without handler does, so
+	// that only SIGKILL stops the supervisor.
+	SignalsIgnore SignalHandling = "ignore"
+)
+
+// pollInterval is the interval the children are checked on shutdown.
+const pollInterval = 50 * time.Millisecond
+
+// Config configures the supervisor.
+type Config struct {
+	// Children is the number of children spawned, one every SpawnInterval,
+	// 0 spawns them until the supervisor exits.
+	Children      int
+	SpawnInterval time.Duration
+	// Child is the behavior of the children.
+	Child ChildConfig
+	// Reap waits for the children as they exit, and for the orphans
+	// reparented to the supervisor. Otherwise they are left as zombies.
+	Reap bool
+	// Subreaper makes the orphans of the children reparented to the
+	// supervisor rather than to PID 1, linux only.
+	Subreaper bool
+	Signals   SignalHandling
+	// Grace is the time the children are given to exit once the signal is
+	// forwarded.
+	Grace time.Duration
+	// ReportInterval is the interval the children are reported, 0 disables
+	// the reports.
+	ReportInterval time.Duration
+}
+
+// Validate returns an error if the configuration is not usable.
+func (c Config) Validate() error {
+	if c.Children < 0 {
+		return errors.New("the number of children must not be negative")
+	}
+	if c.SpawnInterval <= 0 {
+		return errors.New("the spawn interval must be positive")
+	}
+	if c.Grace < 0 || c.ReportInterval < 0 {
+		return errors.New("the grace period and the report interval must not be negative")
+	}
+	switch c.Signals {
+	case SignalsForward, SignalsExit, SignalsIgnore:
+	default:
+		return errors.Errorf("unknown signal handling %q, must be one of forward, exit or ignore", c.Signals)
+	}
+	return c.Child.Validate()
+}
+
+// Stats counts the children of the supervisor.
+type Stats struct {
+	// Spawned is the number of children spawned.
+	Spawned int
+	// Children is the number of children not reaped yet, running or zombie.
+	Children int
+	// Reaped is the number of children reaped, and Orphans the number of
+	// orphans reaped among them.
+	Reaped  int
+	Orphans int
+}
+
+type service struct {
+	logger  *log.DefaultLogger
+	cfg     Config
+	command []string
+
+	mu       sync.Mutex
+	children map[int]bool
+	stats    Stats
+}
+
+// New returns a supervisor of cfg, command is the command line of the
+// children, to which the arguments of the child configuration are appended.
+func New(logger *log.DefaultLogger, cfg Config, command []string) *service {
+	logger.Info(
+		"Creating supervisor",
+		fields.Int("children", cfg.Children),
+		fields.String("behavior", string(cfg.Child.Behavior)),
+		fields.Any("reap", cfg.Reap),
+		fields.String("signals", string(cfg.Signals)),
+	)
+	return &service{
+		logger:   logger,
+		cfg:      cfg,
+		command:  command,
+		children: make(map[int]bool),
+	}
+}
+
+// Stats returns the counts of the children.
+func (s *service) Stats() Stats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats := s.stats
+	stats.Children = len(s.children)
+	return stats
+}
+
+// Run spawns the children until a signal received on signals stops the
+// supervisor, and returns the exit code of the process.
+func (s *service) Run(signals <-chan os.Signal) (int, error) {
+	if s.cfg.Subreaper {
+		if err := setSubreaper(); err != nil {
+			return 0, err
+		}
+	}
+
+	var sigchld chan os.Signal
+	if s.cfg.Reap {
+		sigchld = make(chan os.Signal, 1)
+		signal.Notify(sigchld, syscall.SIGCHLD)
+		defer signal.Stop(sigchld)
+	}
+
+	spawnTicker := time.NewTicker(s.cfg.SpawnInterval)
+	defer spawnTicker.Stop()
+	spawn := spawnTicker.C
+
+	var report <-chan time.Time
+	if s.cfg.ReportInterval > 0 {
+		reportTicker := time.NewTicker(s.cfg.ReportInterval)
+		defer reportTicker.Stop()
+		report = reportTicker.C
+	}
+
+	s.logger.Info("Starting supervisor", fields.Int("pid", os.Getpid()))
+	s.spawn()
+	for {
+		if s.cfg.Children > 0 && s.Stats().Spawned >= s.cfg.Children {
+			spawn = nil
+		}
+
+		select {
+		case <-spawn:
+			s.spawn()
+		case <-sigchld:
+			s.reap()
+		case <-report:
+			s.report()
+		case sig := <-signals:
+			if s.cfg.Signals == SignalsIgnore {
+				s.logger.Info("Ignoring signal", fields.String("signal", sig.String()))
+				continue
+			}
+			return s.stop(sig), nil
+		}
+	}
+}
+
+// spawn starts a child. The failures, such as when the pids limit is
+// reached, are logged and the next child is tried on the next tick.
+func (s *service) spawn() {
+	p, err := start(s.command, s.cfg.Child)
+	if err != nil {
+		s.logger.Warn("Unable to spawn child", fields.Error(err))
+		return
+	}
+	pid := p.Pid
+	// the child is waited for with its pid, not the process handle
+	_ = p.Release()
+
+	s.mu.Lock()
+	s.children[pid] = true
+	s.stats.Spawned++
+	s.mu.Unlock()
+	s.logger.Info("Child spawned", fields.Int("pid", pid), fields.String("behavior", string(s.cfg.Child.Behavior)))
+}
+
+// reap waits for any exited child of the supervisor, the orphans included.
+func (s *service) reap() {
+	for {
+		var ws syscall.WaitStatus
+		pid, err := syscall.Wait4(-1, &ws, syscall.WNOHANG, nil)
+		if err == syscall.EINTR {
+			continue
+		}
+		if err != nil || pid <= 0 {
+			return
+		}
+		s.reaped(pid, ws)
+	}
+}
+
+// waitChildren waits for the exited children spawned by the supervisor.
+func (s *service) waitChildren() {
+	for _, pid := range s.pids() {
+		var ws syscall.WaitStatus
+		if wpid, err := syscall.Wait4(pid, &ws, syscall.WNOHANG, nil); err == nil && wpid == pid {
+			s.reaped(pid, ws)
+		}
+	}
+}
+
+func (s *service) reaped(pid int, ws syscall.WaitStatus) {
+	s.mu.Lock()
+	own := s.children[pid]
+	delete(s.children, pid)
+	s.stats.Reaped++
+	if !own {
+		s.stats.Orphans++
+	}
+	s.mu.Unlock()
+	s.logger.Info("Child reaped", fields.Int("pid", pid), fields.Any("orphan", !own), fields.String("status", status(ws)))
+}
+
+// pids returns the pids of the children not reaped yet.
+func (s *service) pids() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	pids := make([]int, 0, len(s.children))
+	for pid := range s.children {
+		pids = append(pids, pid)
+	}
+	sort.Ints(pids)
+	return pids
+}
+
+func (s *service) report() {
+	logger := s.logger
+	if n, ok := zombies(os.Getpid()); ok {
+		logger = logger.With(fields.Int("zombies", n))
+	}
+	stats := s.Stats()
+	logger.Info(
+		"Supervising",
+		fields.Int("spawned", stats.Spawned),
+		fields.Int("children", stats.Children),
+		fields.Int("reaped", stats.Reaped),
+		fields.Int("orphans", stats.Orphans),
+	)
+}
+
+// stop handles sig and returns the exit code of the process.
+func (s *service) stop(sig os.Signal) int {
+	if s.cfg.Signals == SignalsExit {
+		s.logger.Info("Exiting without stopping the children", fields.String("signal", sig.String()))
+		return 128 + int(sig.(syscall.Signal))
+	}
+
+	s.logger.Info("Forwarding signal", fields.String("signal", sig.String()), fields.Int("children", len(s.pids())))
+	for _, pid := range s.pids() {
+		_ = syscall.Kill(pid, sig.(syscall.Signal))
+	}
+
+	// the children are waited for on shutdown, even when they are not
+	// reaped while running
+	deadline := time.Now().Add(s.cfg.Grace)
+	for {
+		s.waitChildren()
+		if s.cfg.Reap {
+			s.reap()
+		}
+		if len(s.pids()) == 0 {
+			s.logger.Info("Children stopped")
+			return 0
+		}
+		if time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(pollInterval)
+	}
+
+	s.logger.Warn("Killing the children still running after the grace period", fields.Int("children", len(s.pids())))
+	for _, pid := range s.pids() {
+		_ = syscall.Kill(pid, syscall.SIGKILL)
+		var ws syscall.WaitStatus
+		if wpid, err := syscall.Wait4(pid, &ws, 0, nil); err == nil && wpid == pid {
+			s.reaped(pid, ws)
+		}
+	}
+	return 0
+}
+
+// status describes how a child exited.
+func status(ws syscall.WaitStatus) string {
+	if ws.Signaled() {
+		return "signal: " + ws.Signal().String()
+	}
+	return "exit status " + strconv.Itoa(ws.ExitStatus())
+}
@@ -0,0 +1,217 @@
+//go:build unix
+
+package supervisor
+
+import (
+	"flag"
+	"os"
+	"runtime"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+)
+
+// childEnv makes the test binary run as a child, as the supervisor command
+// does when it is executed by the supervisor.
+const childEnv = "SUPERVISOR_TEST_CHILD"
+
+func TestMain(m *testing.M) {
+	if os.Getenv(childEnv) != "" {
+		os.Exit(runTestChild(os.Args[1:]))
+	}
+	// the children inherit the environment
+	_ = os.Setenv(childEnv, "1")
+	os.Exit(m.Run())
+}
+
+func runTestChild(args []string) int {
+	var cfg ChildConfig
+	var behavior string
+	fs := flag.NewFlagSet("child", flag.ContinueOnError)
+	fs.StringVar(&behavior, "behavior", "", "")
+	fs.DurationVar(&cfg.Lifetime, "lifetime", 0, "")
+	fs.IntVar(&cfg.ExitCode, "exit-code", 0, "")
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	cfg.Behavior = Behavior(behavior)
+	return RunChild(cfg, testCommand())
+}
+
+func testCommand() []string {
+	exe, err := os.Executable()
+	if err != nil {
+		panic(err)
+	}
+	return []string{exe}
+}
+
+func TestConfig_Validate(t *testing.T) {
+	child := ChildConfig{Behavior: BehaviorExit, Lifetime: time.Second, ExitCode: 1}
+	tests := []struct {
+		name    string
+		cfg     Config
+		wantErr bool
+	}{
+		{"valid", Config{Children: 3, SpawnInterval: time.Second, Child: child, Signals: SignalsForward}, false},
+		{"unlimited children", Config{SpawnInterval: time.Second, Child: child, Signals: SignalsIgnore}, false},
+		{"negative children", Config{Children: -1, SpawnInterval: time.Second, Child: child, Signals: SignalsForward}, true},
+		{"no spawn interval", Config{Children: 1, Child: child, Signals: SignalsForward}, true},
+		{"negative grace", Config{SpawnInterval: time.Second, Grace: -time.Second, Child: child, Signals: SignalsForward}, true},
+		{"unknown signals", Config{SpawnInterval: time.Second, Child: child, Signals: "reap"}, true},
+		{"unknown behavior", Config{SpawnInterval: time.Second, Child: ChildConfig{Behavior: "fork"}, Signals: SignalsExit}, true},
+		{"negative lifetime", Config{SpawnInterval: time.Second, Child: ChildConfig{Behavior: BehaviorKill, Lifetime: -1}, Signals: SignalsExit}, true},
+		{"invalid exit code", Config{SpawnInterval: time.Second, Child: ChildConfig{Behavior: BehaviorExit, ExitCode: 256}, Signals: SignalsExit}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.cfg.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+func TestChildConfig_Args(t *testing.T) {
+	cfg := ChildConfig{Behavior: BehaviorOrphan, Lifetime: 5 * time.Second, ExitCode: 3}
+	require.Equal(t, []string{"--behavior", "orphan", "--lifetime", "5s", "--exit-code", "3"}, cfg.Args())
+}
+
+// run runs s until the returned stop function sends sig, which returns the
+// exit code.
+func run(t *testing.T, s *service) func(sig os.Signal) int {
+	t.Helper()
+	signals := make(chan os.Signal, 1)
+	done := make(chan int)
+	go func() {
+		code, err := s.Run(signals)
+		require.NoError(t, err)
+		done <- code
+	}()
+	return func(sig os.Signal) int {
+		signals <- sig
+		select {
+		case code := <-done:
+			return code
+		case <-time.After(10 * time.Second):
+			t.Fatal("the supervisor did not stop")
+			return 0
+		}
+	}
+}
+
+func TestService_Run_Reap(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	s := New(logger, Config{
+		Children:      2,
+		SpawnInterval: 10 * time.Millisecond,
+		Child:         ChildConfig{Behavior: BehaviorExit, Lifetime: 10 * time.Millisecond, ExitCode: 3},
+		Reap:          true,
+		Signals:       SignalsForward,
+	}, testCommand())
+	stop := run(t, s)
+
+	require.Eventually(t, func() bool { return s.Stats().Reaped == 2 }, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, 0, stop(syscall.SIGTERM))
+	require.Equal(t, Stats{Spawned: 2, Reaped: 2}, s.Stats())
+}
+
+func TestService_Run_Zombies(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("the zombies are read from /proc")
+	}
+	logger := log.New(log.WithLevel("info"))
+	s := New(logger, Config{
+		Children:      2,
+		SpawnInterval: 10 * time.Millisecond,
+		Child:         ChildConfig{Behavior: BehaviorKill, Lifetime: 10 * time.Millisecond},
+		Signals:       SignalsForward,
+	}, testCommand())
+	stop := run(t, s)
+
+	require.Eventually(t, func() bool {
+		n, _ := zombies(os.Getpid())
+		return n == 2
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, 2, s.Stats().Children)
+
+	// the zombies are reaped on shutdown
+	require.Equal(t, 0, stop(syscall.SIGTERM))
+	n, ok := zombies(os.Getpid())
+	require.True(t, ok)
+	require.Zero(t, n)
+}
+
+func TestService_Run_Orphans(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("a child subreaper requires linux")
+	}
+	logger := log.New(log.WithLevel("info"))
+	s := New(logger, Config{
+		Children:      1,
+		SpawnInterval: time.Second,
+		Child:         ChildConfig{Behavior: BehaviorOrphan, Lifetime: 50 * time.Millisecond},
+		Reap:          true,
+		Subreaper:     true,
+		Signals:       SignalsForward,
+	}, testCommand())
+	stop := run(t, s)
+
+	// the child and its orphan are reaped by the supervisor
+	require.Eventually(t, func() bool { return s.Stats().Reaped == 2 }, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, 1, s.Stats().Orphans)
+	require.Equal(t, 0, stop(syscall.SIGTERM))
+}
+
+func TestService_Run_Forward(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	s := New(logger, Config{
+		Children:      2,
+		SpawnInterval: 10 * time.Millisecond,
+		Child:         ChildConfig{Behavior: BehaviorIgnoreSignals},
+		Reap:          true,
+		Signals:       SignalsForward,
+		Grace:         200 * time.Millisecond,
+	}, testCommand())
+	stop := run(t, s)
+
+	require.Eventually(t, func() bool { return s.Stats().Spawned == 2 }, 10*time.Second, 10*time.Millisecond)
+	// leave the children the time to ignore the signals
+	time.Sleep(200 * time.Millisecond)
+
+	// the children ignoring the signal are killed after the grace period
+	start := time.Now()
+	require.Equal(t, 0, stop(syscall.SIGTERM))
+	require.GreaterOrEqual(t, time.Since(start), 200*time.Millisecond)
+	require.Equal(t, Stats{Spawned: 2, Reaped: 2}, s.Stats())
+}
+
+func TestService_Run_Exit(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	s := New(logger, Config{
+		Children:      1,
+		SpawnInterval: time.Second,
+		Child:         ChildConfig{Behavior: BehaviorExit},
+		Signals:       SignalsExit,
+	}, testCommand())
+	stop := run(t, s)
+
+	require.Eventually(t, func() bool { return s.Stats().Spawned == 1 }, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, 128+int(syscall.SIGTERM), stop(syscall.SIGTERM))
+
+	// the child is left running
+	pids := s.pids()
+	require.Len(t, pids, 1)
+	require.NoError(t, syscall.Kill(pids[0], syscall.SIGKILL))
+	var ws syscall.WaitStatus
+	_, err := syscall.Wait4(pids[0], &ws, 0, nil)
+	require.NoError(t, err)
+	require.Equal(t, "signal: killed", status(ws))
+}
@@ -0,0 +1,42 @@
+package tcpproxy
+
+import (
+	"fmt"
+
+	"github.com/alecthomas/units"
+
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// Faults describes the faults applied to the proxied connections.
+// The zero value forwards connections untouched.
+type Faults struct {
+	// Latency delays every chunk of data forwarded, in both directions.
+	Latency chaos.Duration `json:"latency,omitempty"`
+	// Bandwidth limits the throughput of each direction of a connection,
+	// per second. Zero means unlimited.
+	Bandwidth units.Base2Bytes `json:"bandwidth,omitempty"`
+	// DisconnectProbability is the probability of resetting the connection
+	// each time a chunk of data is forwarded.
+	DisconnectProbability float64 `json:"disconnect_probability,omitempty"`
+	// Blackhole accepts new connections but never dials the upstream,
+	// data sent by the client is read and discarded.
+	Blackhole bool `json:"blackhole,omitempty"`
+	// HalfOpen stops forwarding data on every connection without closing
+	// them, as if the peer had vanished.
+	HalfOpen bool `json:"half_open,omitempty"`
+}
+
+// Validate returns an error if the faults are not usable.
+func (f Faults) Validate() error {
+	if f.Latency < 0 {
+		return fmt.Errorf("latency must not be negative")
+	}
+	if f.Bandwidth < 0 {
+		return fmt.Errorf("bandwidth must not be negative")
+	}
+	if f.DisconnectProbability < 0 || f.DisconnectProbability > 1 {
+		return fmt.Errorf("invalid disconnect probability %v, must be in [0, 1]", f.DisconnectProbability)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package tcpproxy
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"math/rand"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+)
+
+const (
+	chunkSize     = 32 * 1024
+	dialTimeout   = 5 * time.Second
+	halfOpenCheck = 100 * time.Millisecond
+)
+
+type service struct {
+	logger   *log.DefaultLogger
+	listen   string
+	upstream string
+	events   events.Publisher
+
+	mu      sync.RWMutex
+	faults  Faults
+	faultID string
+
+	rndMu sync.Mutex
+	rnd   *rand.Rand
+}
+
+// Option configures the service.
+type Option func(*service)
+
+// WithEvents publishes the changes of the faults to p.
+func WithEvents(p events.Publisher) Option {
+	return func(s *service) {
+		s.events = p
+	}
+}
+
+// New returns a service forwarding the TCP connections accepted on listen to upstream.
+func New(logger *log.DefaultLogger, listen string, upstream string, opts ...Option) *service {
+	logger.Info(
+		"Creating TCP proxy",
+		fields.String("listen", listen),
+		fields.String("upstream", upstream),
+	)
+
+	s := &service{
+		logger:   logger,
+		listen:   listen,
+		upstream: upstream,
+		rnd:      rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// Faults returns the faults currently applied to the connections.
+func (s *service) Faults() Faults {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.faults
+}
+
+// SetFaults validates and replaces the faults applied to the connections.
+// Latency, bandwidth, disconnect and half-open faults also apply to the
+// connections already established. Setting faults starts them and the zero
+// value cancels them, the events reporting the trigger of ctx.
+func (s *service) SetFaults(ctx context.Context, f Faults) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	previous := s.faults
+	s.faults = f
+
+	s.logger.Info("Setting TCP faults", fields.Any("faults", f))
+	var (
+		t     events.Type
+		msg   string
+		id    string
+		event Faults
+	)
+	switch {
+	case f != Faults{}:
+		s.faultID = events.NewFaultID()
+		t, msg, id, event = events.TypeStarted, "TCP faults set", s.faultID, f
+	case previous != Faults{}:
+		t, msg, id, event = events.TypeCancelled, "TCP faults removed", s.faultID, previous
+		s.faultID = ""
+	}
+	s.mu.Unlock()
+
+	if t != "" {
+		s.publish(ctx, t, msg, id, event)
+	}
+	return nil
+}
+
+// publish publishes an event of the faults f identified by id.
+func (s *service) publish(ctx context.Context, t events.Type, msg string, id string, f Faults) {
+	if s.events == nil {
+		return
+	}
+	// the parameters are the fields of the faults API
+	var data map[string]interface{}
+	if b, err := json.Marshal(f); err == nil {
+		_ = json.Unmarshal(b, &data)
+	}
+	s.events.Publish(events.Event{
+		Type:    t,
+		Source:  "tcp",
+		FaultID: id,
+		Trigger: events.TriggerFrom(ctx),
+		Message: msg,
+		Data:    data,
+	})
+}
+
+// Start listens on the listen address and forwards connections until the listener fails.
+func (s *service) Start() error {
+	l, err := net.Listen("tcp", s.listen)
+	if err != nil {
+		return errors.Wrap(err, "unable to listen")
+	}
+
+	return s.serve(l)
+}
+
+func (s *service) serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return errors.Wrap(err, "unable to accept connection")
+		}
+		go s.handle(conn)
+	}
+}
+
+func (s *service) handle(client net.Conn) {
+	defer client.Close()
+
+	if s.Faults().Blackhole {
+		s.logger.Debug("Blackholing connection", fields.String("client", client.RemoteAddr().String()))
+		_, _ = io.Copy(io.Discard, client)
+		return
+	}
+
+	upstream, err := net.DialTimeout("tcp", s.upstream, dialTimeout)
+	if err != nil {
+		s.logger.Error("Unable to reach upstream", fields.String("upstream", s.upstream), fields.Error(err))
+		return
+	}
+	defer upstream.Close()
+
+	s.logger.Debug("Connection opened", fields.String("client", client.RemoteAddr().String()))
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		s.pipe(upstream, client)
+	}()
+	go func() {
+		defer wg.Done()
+		s.pipe(client, upstream)
+	}()
+	wg.Wait()
+
+	s.logger.Debug("Connection closed", fields.String("client", client.RemoteAddr().String()))
+}
+
+// pipe forwards data from src to dst until src is closed or a fault breaks the connection.
+func (s *service) pipe(dst, src net.Conn) {
+	buf := make([]byte, chunkSize)
+	for {
+		n, err := src.Read(buf[:s.chunkSize()])
+		if n > 0 && !s.forward(dst, src, buf[:n]) {
+			return
+		}
+		if err != nil {
+			// propagate the end of stream while letting the other direction finish
+			if tcpConn, ok := dst.(*net.TCPConn); ok {
+				_ = tcpConn.CloseWrite()
+			} else {
+				_ = dst.Close()
+			}
+			return
+		}
+	}
+}
+
+// forward applies the faults to a chunk of data and writes it to dst.
+// It returns false when the connection must not be used anymore.
+func (s *service) forward(dst, src net.Conn, b []byte) bool {
+	f := s.Faults()
+	for f.HalfOpen {
+		time.Sleep(halfOpenCheck)
+		f = s.Faults()
+	}
+
+	if f.DisconnectProbability > 0 && s.rand() < f.DisconnectProbability {
+		s.logger.Debug("Resetting connection", fields.String("src", src.RemoteAddr().String()))
+		reset(src)
+		reset(dst)
+		return false
+	}
+
+	if f.Latency > 0 {
+		time.Sleep(time.Duration(f.Latency))
+	}
+
+	if f.Bandwidth > 0 {
+		time.Sleep(time.Duration(float64(len(b)) / float64(f.Bandwidth) * float64(time.Second)))
+	}
+
+	_, err := dst.Write(b)
+	return err == nil
+}
+
+// chunkSize keeps chunks smaller than the bandwidth limit, so that the
+// throughput stays smooth instead of bursting once per second.
+func (s *service) chunkSize() int {
+	bandwidth := int(s.Faults().Bandwidth)
+	if bandwidth > 0 && bandwidth < chunkSize {
+		return bandwidth
+	}
+	return chunkSize
+}
+
+func (s *service) rand() float64 {
+	s.rndMu.Lock()
+	defer s.rndMu.Unlock()
+	return s.rnd.Float64()
+}
+
+// reset closes the connection with a RST instead of a FIN.
+func reset(conn net.Conn) {
+	if tcpConn, ok := conn.(*net.TCPConn); ok {
+		_ = tcpConn.SetLinger(0)
+	}
+	_ = conn.Close()
+}
@@ -0,0 +1,260 @@
+package tcpproxy
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/alecthomas/units"
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// startEcho starts an upstream echoing everything it receives and returns
+// its address and the number of connections it accepted.
+func startEcho(t *testing.T) (string, *int32) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	var accepted int32
+	go func() {
+		for {
+			conn, err := l.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&accepted, 1)
+			go func() {
+				defer conn.Close()
+				_, _ = io.Copy(conn, conn)
+			}()
+		}
+	}()
+
+	return l.Addr().String(), &accepted
+}
+
+// startProxy starts a proxy in front of upstream and returns the service and its address.
+func startProxy(t *testing.T, upstream string) (*service, string) {
+	t.Helper()
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, l.Addr().String(), upstream)
+	go func() {
+		_ = svc.serve(l)
+	}()
+
+	return svc, l.Addr().String()
+}
+
+func roundTrip(t *testing.T, conn net.Conn, msg string, timeout time.Duration) (string, error) {
+	t.Helper()
+
+	_, err := conn.Write([]byte(msg))
+	require.NoError(t, err)
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(timeout)))
+	buf := make([]byte, len(msg))
+	_, err = io.ReadFull(conn, buf)
+	return string(buf), err
+}
+
+func TestNew(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, ":15432", "localhost:5432")
+
+	require.NotNil(t, svc)
+	require.Equal(t, logger, svc.logger)
+	require.Equal(t, ":15432", svc.listen)
+	require.Equal(t, "localhost:5432", svc.upstream)
+	require.Equal(t, Faults{}, svc.Faults())
+}
+
+func TestService_SetFaults(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, ":0", "localhost:5432")
+
+	faults := Faults{Latency: chaos.Duration(time.Second), Bandwidth: 64 * units.KiB, DisconnectProbability: 0.1}
+	require.NoError(t, svc.SetFaults(context.Background(), faults))
+	require.Equal(t, faults, svc.Faults())
+
+	require.Error(t, svc.SetFaults(context.Background(), Faults{DisconnectProbability: 2}))
+	require.Error(t, svc.SetFaults(context.Background(), Faults{Latency: -1}))
+	require.Error(t, svc.SetFaults(context.Background(), Faults{Bandwidth: -1}))
+	require.Equal(t, faults, svc.Faults())
+}
+
+func TestService_SetFaults_Events(t *testing.T) {
+	bus := events.NewBus()
+	ch, unsubscribe := bus.Subscribe(0)
+	defer unsubscribe()
+
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, ":0", "localhost:5432", WithEvents(bus))
+
+	require.NoError(t, svc.SetFaults(context.Background(), Faults{Blackhole: true}))
+	started := <-ch
+	require.Equal(t, events.TypeStarted, started.Type)
+	require.Equal(t, "tcp", started.Source)
+	require.Equal(t, events.TriggerAPI, started.Trigger)
+	require.Equal(t, map[string]interface{}{"blackhole": true}, started.Data)
+
+	ctx := events.WithTrigger(context.Background(), events.TriggerScenario)
+	require.NoError(t, svc.SetFaults(ctx, Faults{}))
+	cancelled := <-ch
+	require.Equal(t, events.TypeCancelled, cancelled.Type)
+	require.Equal(t, started.FaultID, cancelled.FaultID)
+	require.Equal(t, events.TriggerScenario, cancelled.Trigger)
+
+	// removing no faults publishes nothing
+	require.NoError(t, svc.SetFaults(context.Background(), Faults{}))
+	require.Empty(t, ch)
+}
+
+func TestService_SetFaults_EventsUnlocked(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	var svc *service
+	var seen []Faults
+	// the hooks run synchronously, reading the faults deadlocks if mu is held
+	bus := events.NewBus(events.WithHook(func(events.Event) {
+		seen = append(seen, svc.Faults())
+	}))
+	svc = New(logger, ":0", "localhost:5432", WithEvents(bus))
+
+	require.NoError(t, svc.SetFaults(context.Background(), Faults{Blackhole: true}))
+	require.NoError(t, svc.SetFaults(context.Background(), Faults{}))
+	require.Equal(t, []Faults{{Blackhole: true}, {}}, seen)
+}
+
+func TestService_Start_InvalidAddress(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	svc := New(logger, "invalid-address", "localhost:5432")
+
+	require.Error(t, svc.Start())
+}
+
+func TestService_Forward(t *testing.T) {
+	upstream, _ := startEcho(t)
+	_, addr := startProxy(t, upstream)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	got, err := roundTrip(t, conn, "ping", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "ping", got)
+}
+
+func TestService_Latency(t *testing.T) {
+	upstream, _ := startEcho(t)
+	svc, addr := startProxy(t, upstream)
+	require.NoError(t, svc.SetFaults(context.Background(), Faults{Latency: chaos.Duration(50 * time.Millisecond)}))
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	start := time.Now()
+	got, err := roundTrip(t, conn, "ping", time.Second)
+	require.NoError(t, err)
+	require.Equal(t, "ping", got)
+
+	// delayed once in each direction
+	require.GreaterOrEqual(t, time.Since(start), 100*time.Millisecond)
+}
+
+func TestService_Bandwidth(t *testing.T) {
+	upstream, _ := startEcho(t)
+	svc, addr := startProxy(t, upstream)
+	require.NoError(t, svc.SetFaults(context.Background(), Faults{Bandwidth: 10 * units.KiB}))
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	msg := string(make([]byte, 2*units.KiB))
+
+	start := time.Now()
+	_, err = roundTrip(t, conn, msg, 5*time.Second)
+	require.NoError(t, err)
+
+	// 2KiB at 10KiB/s takes 200ms in each direction
+	require.GreaterOrEqual(t, time.Since(start), 350*time.Millisecond)
+}
+
+func TestService_Blackhole(t *testing.T) {
+	upstream, accepted := startEcho(t)
+	svc, addr := startProxy(t, upstream)
+	require.NoError(t, svc.SetFaults(context.Background(), Faults{Blackhole: true}))
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = roundTrip(t, conn, "ping", 100*time.Millisecond)
+	require.Error(t, err)
+
+	var netErr net.Error
+	require.ErrorAs(t, err, &netErr)
+	require.True(t, netErr.Timeout())
+	require.Equal(t, int32(0), atomic.LoadInt32(accepted))
+}
+
+func TestService_Disconnect(t *testing.T) {
+	upstream, _ := startEcho(t)
+	svc, addr := startProxy(t, upstream)
+	require.NoError(t, svc.SetFaults(context.Background(), Faults{DisconnectProbability: 1}))
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = roundTrip(t, conn, "ping", time.Second)
+	require.Error(t, err)
+
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		require.False(t, netErr.Timeout(), "connection should be closed, not stalled")
+	}
+}
+
+func TestService_HalfOpen(t *testing.T) {
+	upstream, _ := startEcho(t)
+	svc, addr := startProxy(t, upstream)
+
+	conn, err := net.Dial("tcp", addr)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	_, err = roundTrip(t, conn, "ping", time.Second)
+	require.NoError(t, err)
+
+	require.NoError(t, svc.SetFaults(context.Background(), Faults{HalfOpen: true}))
+
+	_, err = roundTrip(t, conn, "pong", 200*time.Millisecond)
+	require.Error(t, err)
+
+	// the stalled data is delivered once the fault is removed
+	require.NoError(t, svc.SetFaults(context.Background(), Faults{}))
+
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	buf := make([]byte, 4)
+	_, err = io.ReadFull(conn, buf)
+	require.NoError(t, err)
+	require.Equal(t, "pong", string(buf))
+}
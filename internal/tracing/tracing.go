@@ -0,0 +1,106 @@
+// Package tracing exports the spans of crashlooper to an OpenTelemetry
+// collector over OTLP.
+package tracing
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/pkg/errors"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.37.0"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+// Protocol is the OTLP transport of the spans.
+type Protocol string
+
+const (
+	// ProtocolGRPC exports the spans over gRPC, usually on port 4317.
+	ProtocolGRPC Protocol = "grpc"
+	// ProtocolHTTP exports the spans as protobuf over HTTP, usually on port 4318.
+	ProtocolHTTP Protocol = "http"
+)
+
+// Config configures the export of the spans.
+type Config struct {
+	// Endpoint is the URL of the collector, such as http://localhost:4317.
+	// The http scheme disables TLS.
+	Endpoint string
+	Protocol Protocol
+	// SampleRatio is the ratio of the traces started by crashlooper that
+	// are sampled, the sampling decision of the callers is always followed.
+	SampleRatio float64
+	Version     string
+	Pod         *podinfo.Info
+}
+
+// NewProvider returns a tracer provider exporting the spans as configured.
+// It must be shut down to flush the spans before the process exits.
+func NewProvider(ctx context.Context, cfg Config) (*sdktrace.TracerProvider, error) {
+	u, err := url.Parse(cfg.Endpoint)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.Errorf("invalid OTLP endpoint %q, must be an http or https URL", cfg.Endpoint)
+	}
+	if cfg.SampleRatio < 0 || cfg.SampleRatio > 1 {
+		return nil, errors.Errorf("invalid sample ratio %v, must be in [0, 1]", cfg.SampleRatio)
+	}
+
+	var exporter sdktrace.SpanExporter
+	switch cfg.Protocol {
+	case ProtocolGRPC:
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpointURL(cfg.Endpoint)}
+		if u.Scheme == "http" {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	case ProtocolHTTP:
+		opts := []otlptracehttp.Option{otlptracehttp.WithEndpointURL(cfg.Endpoint)}
+		if u.Path == "" || u.Path == "/" {
+			opts = append(opts, otlptracehttp.WithURLPath("/v1/traces"))
+		}
+		if u.Scheme == "http" {
+			opts = append(opts, otlptracehttp.WithInsecure())
+		}
+		exporter, err = otlptracehttp.New(ctx, opts...)
+	default:
+		return nil, errors.Errorf("unknown OTLP protocol %q, must be grpc or http", cfg.Protocol)
+	}
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to create OTLP exporter")
+	}
+
+	return sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(newResource(cfg)),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(cfg.SampleRatio))),
+	), nil
+}
+
+// newResource describes crashlooper and its pod.
+func newResource(cfg Config) *resource.Resource {
+	attrs := []attribute.KeyValue{
+		semconv.ServiceName("crashlooper"),
+		semconv.ServiceVersion(cfg.Version),
+	}
+
+	if pod := cfg.Pod; pod != nil {
+		for _, kv := range []attribute.KeyValue{
+			semconv.K8SPodName(pod.Name),
+			semconv.K8SNamespaceName(pod.Namespace),
+			semconv.K8SNodeName(pod.Node),
+			semconv.HostName(pod.Hostname),
+		} {
+			if kv.Value.AsString() != "" {
+				attrs = append(attrs, kv)
+			}
+		}
+	}
+
+	return resource.NewWithAttributes(semconv.SchemaURL, attrs...)
+}
@@ -0,0 +1,134 @@
+package tracing
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	collectorpb "go.opentelemetry.io/proto/otlp/collector/trace/v1"
+	tracepb "go.opentelemetry.io/proto/otlp/trace/v1"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+// collector is a stand-in OpenTelemetry collector keeping the spans it receives.
+type collector struct {
+	collectorpb.UnimplementedTraceServiceServer
+
+	mu    sync.Mutex
+	spans []*tracepb.ResourceSpans
+}
+
+func (c *collector) Export(_ context.Context, req *collectorpb.ExportTraceServiceRequest) (*collectorpb.ExportTraceServiceResponse, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.spans = append(c.spans, req.ResourceSpans...)
+	return &collectorpb.ExportTraceServiceResponse{}, nil
+}
+
+func (c *collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/v1/traces" {
+		http.NotFound(w, r)
+		return
+	}
+
+	b, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req collectorpb.ExportTraceServiceRequest
+	if err := proto.Unmarshal(b, &req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp, _ := c.Export(r.Context(), &req)
+	b, _ = proto.Marshal(resp)
+	w.Header().Set("Content-Type", "application/x-protobuf")
+	_, _ = w.Write(b)
+}
+
+// received returns the resource attributes and the name of the spans received.
+func (c *collector) received() (map[string]string, []string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	resource := map[string]string{}
+	var names []string
+	for _, rs := range c.spans {
+		for _, kv := range rs.GetResource().GetAttributes() {
+			resource[kv.Key] = kv.GetValue().GetStringValue()
+		}
+		for _, ss := range rs.ScopeSpans {
+			for _, s := range ss.Spans {
+				names = append(names, s.Name)
+			}
+		}
+	}
+	return resource, names
+}
+
+func export(t *testing.T, cfg Config, c *collector) {
+	t.Helper()
+
+	cfg.SampleRatio = 1
+	cfg.Version = "v1.2.3"
+	cfg.Pod = &podinfo.Info{Name: "crashlooper-7d9f", Namespace: "crashlooper"}
+
+	tp, err := NewProvider(context.Background(), cfg)
+	require.NoError(t, err)
+
+	_, span := tp.Tracer("test").Start(context.Background(), "GET /")
+	span.End()
+	require.NoError(t, tp.Shutdown(context.Background()))
+
+	resource, names := c.received()
+	require.Equal(t, []string{"GET /"}, names)
+	require.Equal(t, "crashlooper", resource["service.name"])
+	require.Equal(t, "v1.2.3", resource["service.version"])
+	require.Equal(t, "crashlooper-7d9f", resource["k8s.pod.name"])
+	require.Equal(t, "crashlooper", resource["k8s.namespace.name"])
+	require.NotContains(t, resource, "k8s.node.name")
+}
+
+func TestNewProvider_HTTP(t *testing.T) {
+	c := &collector{}
+	srv := httptest.NewServer(c)
+	defer srv.Close()
+
+	export(t, Config{Endpoint: srv.URL, Protocol: ProtocolHTTP}, c)
+}
+
+func TestNewProvider_GRPC(t *testing.T) {
+	c := &collector{}
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	collectorpb.RegisterTraceServiceServer(srv, c)
+	go func() { _ = srv.Serve(l) }()
+	defer srv.Stop()
+
+	export(t, Config{Endpoint: "http://" + l.Addr().String(), Protocol: ProtocolGRPC}, c)
+}
+
+func TestNewProvider_Errors(t *testing.T) {
+	tests := []Config{
+		{Endpoint: "localhost:4317", Protocol: ProtocolGRPC},
+		{Endpoint: "ftp://localhost:4317", Protocol: ProtocolGRPC},
+		{Endpoint: "http://localhost:4317", Protocol: "thrift"},
+		{Endpoint: "http://localhost:4317", Protocol: ProtocolGRPC, SampleRatio: 2},
+	}
+	for _, cfg := range tests {
+		_, err := NewProvider(context.Background(), cfg)
+		require.Error(t, err, "%+v", cfg)
+	}
+}
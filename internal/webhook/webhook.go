@@ -0,0 +1,255 @@
+// Package webhook notifies external services of the fault events, such as a
+// test orchestrator or a chat bridge, by posting them to webhooks.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"github.com/pkg/errors"
+	"go.pixelfactory.io/pkg/observability/log"
+	"go.pixelfactory.io/pkg/observability/log/fields"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+// DefaultTemplate posts the event encoded as JSON.
+const DefaultTemplate = `{{json .}}`
+
+const queueSize = 64
+
+// Config configures the webhooks.
+type Config struct {
+	// URLs are the webhooks every notification is posted to.
+	URLs []string
+	// Headers are added to the requests, Content-Type defaults to application/json.
+	Headers http.Header
+	// Template is the text/template of the request body, executed with a
+	// Payload. The json function encodes a value as JSON.
+	Template string
+	// Phases are the event types notified, the exit of the process is
+	// always notified.
+	Phases []events.Type
+	// Timeout bounds each request.
+	Timeout time.Duration
+	// Retries is the number of times a failed request is retried.
+	Retries int
+	// ExitTimeout bounds the time the process waits for the webhooks
+	// before a crash exits it.
+	ExitTimeout time.Duration
+}
+
+// Payload is the data of the body template.
+type Payload struct {
+	events.Event
+	Pod *podinfo.Info `json:"pod,omitempty"`
+}
+
+// Notifier posts the fault events to the webhooks.
+type Notifier struct {
+	logger     *log.DefaultLogger
+	cfg        Config
+	tmpl       *template.Template
+	phases     map[events.Type]bool
+	pod        *podinfo.Info
+	httpClient *http.Client
+	queue      chan events.Event
+}
+
+// Option configures a Notifier.
+type Option func(*Notifier)
+
+// WithHTTPClient sets the HTTP client used to send the requests.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(n *Notifier) {
+		n.httpClient = httpClient
+	}
+}
+
+// WithPod adds the identity of the pod to the payloads.
+func WithPod(pod *podinfo.Info) Option {
+	return func(n *Notifier) {
+		n.pod = pod
+	}
+}
+
+// New returns a Notifier posting to the webhooks of cfg.
+func New(logger *log.DefaultLogger, cfg Config, opts ...Option) (*Notifier, error) {
+	if len(cfg.URLs) == 0 {
+		return nil, errors.New("at least one webhook URL is required")
+	}
+	for _, u := range cfg.URLs {
+		parsed, err := url.Parse(u)
+		if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+			return nil, errors.Errorf("invalid webhook URL %q, must be an http or https URL", u)
+		}
+	}
+	if cfg.Timeout <= 0 || cfg.ExitTimeout <= 0 {
+		return nil, errors.New("webhook timeouts must be positive")
+	}
+	if cfg.Retries < 0 {
+		return nil, errors.New("webhook retries must not be negative")
+	}
+
+	if cfg.Template == "" {
+		cfg.Template = DefaultTemplate
+	}
+	tmpl, err := template.New("webhook").Funcs(template.FuncMap{"json": toJSON}).Parse(cfg.Template)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid webhook template")
+	}
+
+	logger.Info(
+		"Creating webhook notifier",
+		fields.Any("urls", cfg.URLs),
+		fields.Any("phases", cfg.Phases),
+	)
+
+	n := &Notifier{
+		logger:     logger,
+		cfg:        cfg,
+		tmpl:       tmpl,
+		phases:     make(map[events.Type]bool),
+		httpClient: &http.Client{},
+		queue:      make(chan events.Event, queueSize),
+	}
+	for _, p := range cfg.Phases {
+		n.phases[p] = true
+	}
+	for _, opt := range opts {
+		opt(n)
+	}
+	return n, nil
+}
+
+// Notify posts e to the webhooks if its phase is notified. It is meant to be
+// used as an events.WithHook function: the events preceding the exit of the
+// process are posted before Notify returns, within the exit timeout, the
+// others are queued and posted by Start.
+func (n *Notifier) Notify(e events.Event) {
+	if e.Exiting() {
+		ctx, cancel := context.WithTimeout(context.Background(), n.cfg.ExitTimeout)
+		defer cancel()
+		n.post(ctx, e)
+		return
+	}
+
+	if !n.phases[e.Type] {
+		return
+	}
+	select {
+	case n.queue <- e:
+	default:
+		n.logger.Warn("Webhook queue full, dropping event", fields.Any("event_id", e.ID))
+	}
+}
+
+// Start posts the queued events, one at a time so that they are received in order.
+func (n *Notifier) Start() {
+	for e := range n.queue {
+		n.post(context.Background(), e)
+	}
+}
+
+// post posts e to every webhook concurrently and waits for the requests,
+// retried on failure, to complete.
+func (n *Notifier) post(ctx context.Context, e events.Event) {
+	var body bytes.Buffer
+	if err := n.tmpl.Execute(&body, Payload{Event: e, Pod: n.pod}); err != nil {
+		n.logger.Error("Unable to render webhook body", fields.Error(err))
+		return
+	}
+
+	var wg sync.WaitGroup
+	for _, u := range n.cfg.URLs {
+		wg.Add(1)
+		go func(u string) {
+			defer wg.Done()
+			if err := n.send(ctx, u, body.Bytes()); err != nil {
+				n.logger.Error("Unable to notify webhook", fields.String("url", u), fields.Error(err))
+			}
+		}(u)
+	}
+	wg.Wait()
+}
+
+// send posts body to u, retrying with an exponential backoff until the
+// retries are exhausted or ctx is done.
+func (n *Notifier) send(ctx context.Context, u string, body []byte) error {
+	backoff := 100 * time.Millisecond
+	var err error
+	for attempt := 0; ; attempt++ {
+		if err = n.sendOnce(ctx, u, body); err == nil {
+			return nil
+		}
+		if attempt == n.cfg.Retries {
+			return err
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+func (n *Notifier) sendOnce(ctx context.Context, u string, body []byte) error {
+	ctx, cancel := context.WithTimeout(ctx, n.cfg.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, u, bytes.NewReader(body))
+	if err != nil {
+		return errors.Wrap(err, "unable to create request")
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "crashlooper")
+	for name, values := range n.cfg.Headers {
+		req.Header.Del(name)
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+
+	resp, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return errors.Errorf("webhook answered %s", resp.Status)
+	}
+	return nil
+}
+
+// ParseHeaders parses headers formatted as "Name: value".
+func ParseHeaders(headers []string) (http.Header, error) {
+	h := http.Header{}
+	for _, header := range headers {
+		name, value, ok := strings.Cut(header, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, errors.Errorf("invalid webhook header %q, expected \"Name: value\"", header)
+		}
+		h.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return h, nil
+}
+
+func toJSON(v interface{}) (string, error) {
+	b, err := json.Marshal(v)
+	return string(b), err
+}
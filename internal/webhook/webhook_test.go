@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.pixelfactory.io/pkg/observability/log"
+
+	"github.com/pixelfactoryio/crashlooper/internal/events"
+	"github.com/pixelfactoryio/crashlooper/internal/podinfo"
+)
+
+// standIn is a webhook receiving the notifications.
+type standIn struct {
+	mu       sync.Mutex
+	bodies   []string
+	headers  []http.Header
+	failures int32
+	delay    time.Duration
+}
+
+func (s *standIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if atomic.AddInt32(&s.failures, -1) >= 0 {
+		http.Error(w, "unavailable", http.StatusServiceUnavailable)
+		return
+	}
+	time.Sleep(s.delay)
+
+	b, _ := io.ReadAll(r.Body)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.bodies = append(s.bodies, string(b))
+	s.headers = append(s.headers, r.Header)
+}
+
+func (s *standIn) received() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.bodies...)
+}
+
+func newTestNotifier(t *testing.T, cfg Config) *Notifier {
+	t.Helper()
+
+	if cfg.Timeout == 0 {
+		cfg.Timeout = time.Second
+	}
+	if cfg.ExitTimeout == 0 {
+		cfg.ExitTimeout = time.Second
+	}
+	n, err := New(log.New(log.WithLevel("info")), cfg, WithPod(&podinfo.Info{Name: "crashlooper-7d9f"}))
+	require.NoError(t, err)
+	go n.Start()
+	return n
+}
+
+func TestNew_Invalid(t *testing.T) {
+	logger := log.New(log.WithLevel("info"))
+	tests := []Config{
+		{},
+		{URLs: []string{"localhost:8080"}, Timeout: time.Second, ExitTimeout: time.Second},
+		{URLs: []string{"http://localhost"}, ExitTimeout: time.Second},
+		{URLs: []string{"http://localhost"}, Timeout: time.Second, ExitTimeout: time.Second, Retries: -1},
+		{URLs: []string{"http://localhost"}, Timeout: time.Second, ExitTimeout: time.Second, Template: "{{.Missing"},
+	}
+	for _, cfg := range tests {
+		_, err := New(logger, cfg)
+		require.Error(t, err, "%+v", cfg)
+	}
+}
+
+func TestNotifier_Notify(t *testing.T) {
+	s := &standIn{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	n := newTestNotifier(t, Config{
+		URLs:    []string{srv.URL},
+		Headers: http.Header{"Authorization": {"Bearer secret"}},
+		Phases:  []events.Type{events.TypeScheduled},
+	})
+
+	n.Notify(events.Event{ID: 1, Type: events.TypeProgress, Source: "memory"})
+	n.Notify(events.Event{ID: 2, Type: events.TypeScheduled, Source: "crash", FaultID: "f1", Trigger: events.TriggerAPI})
+
+	require.Eventually(t, func() bool { return len(s.received()) == 1 }, time.Second, 10*time.Millisecond)
+
+	var payload Payload
+	require.NoError(t, json.Unmarshal([]byte(s.received()[0]), &payload))
+	require.Equal(t, uint64(2), payload.ID)
+	require.Equal(t, "f1", payload.FaultID)
+	require.Equal(t, "crashlooper-7d9f", payload.Pod.Name)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	require.Equal(t, "Bearer secret", s.headers[0].Get("Authorization"))
+	require.Equal(t, "application/json", s.headers[0].Get("Content-Type"))
+}
+
+func TestNotifier_Notify_Template(t *testing.T) {
+	s := &standIn{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	n := newTestNotifier(t, Config{
+		URLs:     []string{srv.URL},
+		Template: `{"text": {{json (printf "%s %s on %s" .Source .Type .Pod.Name)}}}`,
+		Phases:   []events.Type{events.TypeStarted},
+	})
+
+	n.Notify(events.Event{Type: events.TypeStarted, Source: "faults"})
+
+	require.Eventually(t, func() bool { return len(s.received()) == 1 }, time.Second, 10*time.Millisecond)
+	require.JSONEq(t, `{"text": "faults started on crashlooper-7d9f"}`, s.received()[0])
+}
+
+func TestNotifier_Notify_Retries(t *testing.T) {
+	s := &standIn{failures: 2}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	n := newTestNotifier(t, Config{
+		URLs:    []string{srv.URL},
+		Phases:  []events.Type{events.TypeScheduled},
+		Retries: 2,
+	})
+
+	n.Notify(events.Event{Type: events.TypeScheduled, Source: "crash"})
+	require.Eventually(t, func() bool { return len(s.received()) == 1 }, 2*time.Second, 10*time.Millisecond)
+}
+
+func TestNotifier_Notify_Exit(t *testing.T) {
+	s := &standIn{}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	// the exit is notified whatever the phases
+	n := newTestNotifier(t, Config{URLs: []string{srv.URL}})
+
+	n.Notify(events.Event{Type: events.TypeTriggered, Source: "crash", Data: map[string]interface{}{"exit_code": 137}})
+
+	// posted synchronously
+	require.Len(t, s.received(), 1)
+	require.Contains(t, s.received()[0], `"exit_code":137`)
+}
+
+func TestNotifier_Notify_ExitTimeout(t *testing.T) {
+	s := &standIn{delay: time.Second}
+	srv := httptest.NewServer(s)
+	defer srv.Close()
+
+	n := newTestNotifier(t, Config{URLs: []string{srv.URL}, ExitTimeout: 50 * time.Millisecond, Retries: 3})
+
+	start := time.Now()
+	n.Notify(events.Event{Type: events.TypeTriggered, Source: events.SourceFaults})
+	require.Less(t, time.Since(start), 500*time.Millisecond)
+}
+
+func TestParseHeaders(t *testing.T) {
+	h, err := ParseHeaders([]string{"Authorization: Bearer a:b", "X-Team:chaos"})
+	require.NoError(t, err)
+	require.Equal(t, "Bearer a:b", h.Get("Authorization"))
+	require.Equal(t, "chaos", h.Get("X-Team"))
+
+	_, err = ParseHeaders([]string{"Authorization"})
+	require.Error(t, err)
+	_, err = ParseHeaders([]string{": value"})
+	require.Error(t, err)
+}
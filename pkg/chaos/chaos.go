@@ -0,0 +1,129 @@
+// Package chaos provides opt-in fault injection for net/http servers.
+//
+// Faults are registered on a Controller and applied by the Latency, Disconnect,
+// ResetStream, GoAway, Error and Crash middlewares, which are plain func(http.Handler) http.Handler
+// and can be used with gorilla/mux or any other router. A Controller is also an
+// http.Handler exposing a small JSON API to list, set and remove faults at runtime.
+package chaos
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Kind identifies a type of fault.
+type Kind string
+
+const (
+	// KindLatency delays requests.
+	KindLatency Kind = "latency"
+	// KindDisconnect closes the client connection without responding.
+	KindDisconnect Kind = "disconnect"
+	// KindError responds with an HTTP error instead of calling the next handler.
+	KindError Kind = "error"
+	// KindCrash exits the process while serving a request.
+	KindCrash Kind = "crash"
+	// KindResetStream resets the HTTP/2 stream of a request with RST_STREAM,
+	// leaving the connection and its other streams open.
+	KindResetStream Kind = "rst_stream"
+	// KindGoAway responds to an HTTP/2 request, then sends GOAWAY to make the
+	// client open a new connection for its next requests.
+	KindGoAway Kind = "goaway"
+)
+
+// Kinds lists the supported kinds of fault.
+var Kinds = []Kind{KindLatency, KindDisconnect, KindError, KindCrash, KindResetStream, KindGoAway}
+
+// Fault describes a fault and the requests it applies to.
+type Fault struct {
+	Kind Kind `json:"kind"`
+	// Probability of the fault being applied to a request, in (0, 1].
+	Probability float64 `json:"probability"`
+	// PathPrefix restricts the fault to requests whose path starts with it.
+	PathPrefix string `json:"path_prefix,omitempty"`
+
+	// Delay and Jitter configure a latency fault, requests are delayed
+	// by Delay plus a random duration up to Jitter.
+	Delay  Duration `json:"delay,omitempty"`
+	Jitter Duration `json:"jitter,omitempty"`
+
+	// Reset configures a disconnect fault to reset the TCP connection
+	// instead of closing it gracefully.
+	Reset bool `json:"reset,omitempty"`
+
+	// StatusCode and Body configure an error fault.
+	StatusCode int    `json:"status_code,omitempty"`
+	Body       string `json:"body,omitempty"`
+
+	// ExitCode configures a crash fault.
+	ExitCode int `json:"exit_code,omitempty"`
+}
+
+// Validate returns an error if the fault is not usable.
+func (f Fault) Validate() error {
+	if f.Probability <= 0 || f.Probability > 1 {
+		return fmt.Errorf("invalid probability %v, must be in (0, 1]", f.Probability)
+	}
+
+	switch f.Kind {
+	case KindLatency:
+		if f.Delay <= 0 {
+			return fmt.Errorf("latency fault requires a positive delay")
+		}
+		if f.Jitter < 0 {
+			return fmt.Errorf("latency fault jitter must not be negative")
+		}
+	case KindDisconnect, KindResetStream, KindGoAway:
+	case KindError:
+		if f.StatusCode < 400 || f.StatusCode > 599 {
+			return fmt.Errorf("invalid status code %d, must be in [400, 599]", f.StatusCode)
+		}
+	case KindCrash:
+		if f.ExitCode < 0 || f.ExitCode > 255 {
+			return fmt.Errorf("invalid exit code %d, must be in [0, 255]", f.ExitCode)
+		}
+	default:
+		return fmt.Errorf("unknown fault kind %q", f.Kind)
+	}
+
+	return nil
+}
+
+// matches reports whether the fault applies to path.
+func (f Fault) matches(path string) bool {
+	return f.PathPrefix == "" || strings.HasPrefix(path, f.PathPrefix)
+}
+
+// Duration is a time.Duration encoded in JSON as a string such as "250ms".
+// Integers are decoded as nanoseconds.
+type Duration time.Duration
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	switch value := v.(type) {
+	case float64:
+		*d = Duration(value)
+	case string:
+		parsed, err := time.ParseDuration(value)
+		if err != nil {
+			return err
+		}
+		*d = Duration(parsed)
+	default:
+		return fmt.Errorf("invalid duration %s", string(b))
+	}
+
+	return nil
+}
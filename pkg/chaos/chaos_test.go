@@ -0,0 +1,104 @@
+package chaos
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestFault_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		fault   Fault
+		wantErr bool
+	}{
+		{
+			name:  "valid latency fault",
+			fault: Fault{Kind: KindLatency, Probability: 1, Delay: Duration(time.Second)},
+		},
+		{
+			name:    "latency fault without delay",
+			fault:   Fault{Kind: KindLatency, Probability: 1},
+			wantErr: true,
+		},
+		{
+			name:    "latency fault with negative jitter",
+			fault:   Fault{Kind: KindLatency, Probability: 1, Delay: Duration(time.Second), Jitter: -1},
+			wantErr: true,
+		},
+		{
+			name:  "valid error fault",
+			fault: Fault{Kind: KindError, Probability: 0.5, StatusCode: 503},
+		},
+		{
+			name:    "error fault with success status",
+			fault:   Fault{Kind: KindError, Probability: 0.5, StatusCode: 200},
+			wantErr: true,
+		},
+		{
+			name:  "valid crash fault",
+			fault: Fault{Kind: KindCrash, Probability: 0.01, ExitCode: 137},
+		},
+		{
+			name:    "crash fault with invalid exit code",
+			fault:   Fault{Kind: KindCrash, Probability: 0.01, ExitCode: 256},
+			wantErr: true,
+		},
+		{
+			name:    "zero probability",
+			fault:   Fault{Kind: KindError, StatusCode: 500},
+			wantErr: true,
+		},
+		{
+			name:    "probability above one",
+			fault:   Fault{Kind: KindError, Probability: 1.5, StatusCode: 500},
+			wantErr: true,
+		},
+		{
+			name:  "valid disconnect fault",
+			fault: Fault{Kind: KindDisconnect, Probability: 1, Reset: true},
+		},
+		{
+			name:  "valid stream reset fault",
+			fault: Fault{Kind: KindResetStream, Probability: 0.1},
+		},
+		{
+			name:  "valid goaway fault",
+			fault: Fault{Kind: KindGoAway, Probability: 0.01},
+		},
+		{
+			name:    "unknown kind",
+			fault:   Fault{Kind: "unknown", Probability: 1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.fault.Validate()
+			if tt.wantErr {
+				require.Error(t, err)
+			} else {
+				require.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestDuration_JSON(t *testing.T) {
+	b, err := json.Marshal(Duration(250 * time.Millisecond))
+	require.NoError(t, err)
+	require.Equal(t, `"250ms"`, string(b))
+
+	var d Duration
+	require.NoError(t, json.Unmarshal([]byte(`"1m30s"`), &d))
+	require.Equal(t, Duration(90*time.Second), d)
+
+	require.NoError(t, json.Unmarshal([]byte(`1000000`), &d))
+	require.Equal(t, Duration(time.Millisecond), d)
+
+	require.Error(t, json.Unmarshal([]byte(`"soon"`), &d))
+	require.Error(t, json.Unmarshal([]byte(`true`), &d))
+}
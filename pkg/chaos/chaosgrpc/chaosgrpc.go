@@ -0,0 +1,118 @@
+// Package chaosgrpc applies the faults of a chaos.Controller to gRPC servers.
+//
+// The latency, error and crash faults apply to RPCs as they do to HTTP
+// requests, their path prefix being matched against the full method name, such
+// as "/crashlooper.echo.v1.Echo/Echo". Error faults fail RPCs with the gRPC
+// status code of their HTTP status code, see Code.
+package chaosgrpc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+// UnaryServerInterceptor applies the latency, error and crash faults of c to unary RPCs.
+func UnaryServerInterceptor(c *chaos.Controller) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := apply(ctx, c, info.FullMethod); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamServerInterceptor applies the latency, error and crash faults of c to
+// streaming RPCs, before the stream is handled.
+func StreamServerInterceptor(c *chaos.Controller) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if err := apply(ss.Context(), c, info.FullMethod); err != nil {
+			return err
+		}
+		return handler(srv, ss)
+	}
+}
+
+// apply applies the faults of c to the RPC of method, in the order of
+// chaos.Middleware. It returns the status error failing the RPC, if any.
+func apply(ctx context.Context, c *chaos.Controller, method string) error {
+	if f, ok := c.Trigger(chaos.KindLatency, method); ok {
+		delay := time.Duration(f.Delay)
+		if f.Jitter > 0 {
+			delay += c.Jitter(f.Jitter)
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return status.FromContextError(ctx.Err()).Err()
+		}
+		chaos.RecordFault(ctx, f, delay)
+		setFault(ctx, chaos.KindLatency)
+	}
+
+	if f, ok := c.Trigger(chaos.KindError, method); ok {
+		msg := f.Body
+		if msg == "" {
+			msg = http.StatusText(f.StatusCode)
+		}
+
+		chaos.RecordFault(ctx, f, 0)
+		setFault(ctx, chaos.KindError)
+		return status.Error(Code(f.StatusCode), msg)
+	}
+
+	if f, ok := c.Trigger(chaos.KindCrash, method); ok {
+		chaos.RecordCrash(ctx, f)
+		c.Crash(ctx, f)
+	}
+
+	return nil
+}
+
+// setFault adds kind to the chaos.HeaderFault header of the RPC response.
+func setFault(ctx context.Context, kind chaos.Kind) {
+	_ = grpc.SetHeader(ctx, metadata.Pairs(chaos.HeaderFault, string(kind)))
+}
+
+// Code returns the gRPC status code of an HTTP status code, following the
+// mapping of the gRPC HTTP gateways. Other 4xx codes are mapped to
+// FailedPrecondition, and other codes to Internal.
+func Code(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.Aborted
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case 499:
+		return codes.Canceled
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	}
+
+	if statusCode >= 400 && statusCode < 500 {
+		return codes.FailedPrecondition
+	}
+	return codes.Internal
+}
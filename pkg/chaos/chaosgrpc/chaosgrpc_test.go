@@ -0,0 +1,128 @@
+package chaosgrpc
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+const method = "/test.v1.Test/Call"
+
+var okHandler = grpc.UnaryHandler(func(ctx context.Context, req interface{}) (interface{}, error) {
+	return "ok", nil
+})
+
+func call(ctx context.Context, c *chaos.Controller) (interface{}, error) {
+	return UnaryServerInterceptor(c)(ctx, "req", &grpc.UnaryServerInfo{FullMethod: method}, okHandler)
+}
+
+func TestUnaryServerInterceptor_NoFault(t *testing.T) {
+	resp, err := call(context.Background(), chaos.NewController())
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+}
+
+func TestUnaryServerInterceptor_Latency(t *testing.T) {
+	c := chaos.NewController()
+	require.NoError(t, c.Set(chaos.Fault{Kind: chaos.KindLatency, Probability: 1, Delay: chaos.Duration(20 * time.Millisecond)}))
+
+	start := time.Now()
+	resp, err := call(context.Background(), c)
+	require.NoError(t, err)
+	require.Equal(t, "ok", resp)
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	require.NoError(t, c.Set(chaos.Fault{Kind: chaos.KindLatency, Probability: 1, Delay: chaos.Duration(time.Hour)}))
+
+	_, err = call(ctx, c)
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
+
+func TestUnaryServerInterceptor_Error(t *testing.T) {
+	c := chaos.NewController()
+	require.NoError(t, c.Set(chaos.Fault{Kind: chaos.KindError, Probability: 1, StatusCode: http.StatusServiceUnavailable}))
+
+	_, err := call(context.Background(), c)
+	s := status.Convert(err)
+	require.Equal(t, codes.Unavailable, s.Code())
+	require.Equal(t, "Service Unavailable", s.Message())
+
+	require.NoError(t, c.Set(chaos.Fault{Kind: chaos.KindError, Probability: 1, StatusCode: http.StatusTooManyRequests, Body: "slow down"}))
+	_, err = call(context.Background(), c)
+	s = status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, s.Code())
+	require.Equal(t, "slow down", s.Message())
+
+	// the path prefix matches the full method name
+	require.NoError(t, c.Set(chaos.Fault{Kind: chaos.KindError, Probability: 1, StatusCode: http.StatusInternalServerError, PathPrefix: "/other.v1.Other/"}))
+	_, err = call(context.Background(), c)
+	require.NoError(t, err)
+}
+
+func TestUnaryServerInterceptor_Crash(t *testing.T) {
+	exitCode := -1
+	c := chaos.NewController(chaos.WithExitFunc(func(code int) { exitCode = code }))
+	require.NoError(t, c.Set(chaos.Fault{Kind: chaos.KindCrash, Probability: 1, ExitCode: 3}))
+
+	_, _ = call(context.Background(), c)
+	require.Equal(t, 3, exitCode)
+}
+
+type serverStream struct {
+	grpc.ServerStream
+}
+
+func (serverStream) Context() context.Context { return context.Background() }
+
+func TestStreamServerInterceptor(t *testing.T) {
+	c := chaos.NewController()
+	interceptor := StreamServerInterceptor(c)
+
+	called := false
+	handler := func(srv interface{}, ss grpc.ServerStream) error {
+		called = true
+		return nil
+	}
+
+	require.NoError(t, interceptor(nil, serverStream{}, &grpc.StreamServerInfo{FullMethod: method}, handler))
+	require.True(t, called)
+
+	called = false
+	require.NoError(t, c.Set(chaos.Fault{Kind: chaos.KindError, Probability: 1, StatusCode: http.StatusGatewayTimeout}))
+	err := interceptor(nil, serverStream{}, &grpc.StreamServerInfo{FullMethod: method}, handler)
+	require.Equal(t, codes.DeadlineExceeded, status.Code(err))
+	require.False(t, called)
+}
+
+func TestCode(t *testing.T) {
+	tests := []struct {
+		statusCode int
+		code       codes.Code
+	}{
+		{http.StatusBadRequest, codes.InvalidArgument},
+		{http.StatusUnauthorized, codes.Unauthenticated},
+		{http.StatusForbidden, codes.PermissionDenied},
+		{http.StatusNotFound, codes.NotFound},
+		{http.StatusConflict, codes.Aborted},
+		{http.StatusTooManyRequests, codes.ResourceExhausted},
+		{http.StatusTeapot, codes.FailedPrecondition},
+		{http.StatusInternalServerError, codes.Internal},
+		{http.StatusNotImplemented, codes.Unimplemented},
+		{http.StatusBadGateway, codes.Internal},
+		{http.StatusServiceUnavailable, codes.Unavailable},
+		{http.StatusGatewayTimeout, codes.DeadlineExceeded},
+	}
+	for _, tt := range tests {
+		require.Equal(t, tt.code, Code(tt.statusCode), "status code %d", tt.statusCode)
+	}
+}
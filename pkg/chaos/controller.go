@@ -0,0 +1,188 @@
+package chaos
+
+import (
+	"context"
+	"math/rand"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Controller holds the active faults, at most one per kind.
+// No fault is active until one is set, so the middlewares are no-op by default.
+type Controller struct {
+	mu     sync.RWMutex
+	faults map[Kind]Fault
+	rand   func() float64
+	exit   func(code int)
+	notify func(ctx context.Context, ch Change)
+}
+
+// Action is what happened to a fault.
+type Action string
+
+const (
+	// ActionSet reports a fault set, replacing the fault of its kind if any.
+	ActionSet Action = "set"
+	// ActionRemoved reports a fault removed.
+	ActionRemoved Action = "removed"
+	// ActionCrash reports a crash fault exiting the process.
+	ActionCrash Action = "crash"
+)
+
+// Change reports an action on a fault.
+type Change struct {
+	Action Action
+	Fault  Fault
+}
+
+// Option configures a Controller.
+type Option func(*Controller)
+
+// WithRand sets the source of randomness used to decide whether a fault
+// applies, f must return a number in [0, 1).
+func WithRand(f func() float64) Option {
+	return func(c *Controller) {
+		c.rand = f
+	}
+}
+
+// WithExitFunc sets the function called by crash faults, os.Exit by default.
+func WithExitFunc(f func(code int)) Option {
+	return func(c *Controller) {
+		c.exit = f
+	}
+}
+
+// WithNotify calls fn when a fault is set or removed, and before a crash fault
+// exits the process, ctx being the one of the call or of the request. fn is
+// called synchronously, so that it can record a crash before the exit.
+func WithNotify(fn func(ctx context.Context, ch Change)) Option {
+	return func(c *Controller) {
+		c.notify = fn
+	}
+}
+
+// NewController returns a Controller without any active fault.
+func NewController(opts ...Option) *Controller {
+	c := &Controller{
+		faults: make(map[Kind]Fault),
+		rand:   rand.New(rand.NewSource(time.Now().UnixNano())).Float64,
+		exit:   os.Exit,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	// the default source is not safe for concurrent use
+	rnd := c.rand
+	var mu sync.Mutex
+	c.rand = func() float64 {
+		mu.Lock()
+		defer mu.Unlock()
+		return rnd()
+	}
+
+	return c
+}
+
+// Set validates and activates a fault, replacing any fault of the same kind.
+func (c *Controller) Set(f Fault) error {
+	return c.SetContext(context.Background(), f)
+}
+
+// SetContext is Set, notifying the change with ctx.
+func (c *Controller) SetContext(ctx context.Context, f Fault) error {
+	if err := f.Validate(); err != nil {
+		return err
+	}
+
+	c.mu.Lock()
+	c.faults[f.Kind] = f
+	c.mu.Unlock()
+
+	c.notifyChange(ctx, ActionSet, f)
+	return nil
+}
+
+// Remove deactivates the fault of the given kind.
+// It returns false if no such fault was active.
+func (c *Controller) Remove(k Kind) bool {
+	return c.RemoveContext(context.Background(), k)
+}
+
+// RemoveContext is Remove, notifying the change with ctx.
+func (c *Controller) RemoveContext(ctx context.Context, k Kind) bool {
+	c.mu.Lock()
+	f, ok := c.faults[k]
+	delete(c.faults, k)
+	c.mu.Unlock()
+
+	if ok {
+		c.notifyChange(ctx, ActionRemoved, f)
+	}
+	return ok
+}
+
+// Get returns the active fault of the given kind.
+func (c *Controller) Get(k Kind) (Fault, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	f, ok := c.faults[k]
+	return f, ok
+}
+
+// Faults returns the active faults sorted by kind.
+func (c *Controller) Faults() []Fault {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	faults := make([]Fault, 0, len(c.faults))
+	for _, f := range c.faults {
+		faults = append(faults, f)
+	}
+	sort.Slice(faults, func(i, j int) bool {
+		return faults[i].Kind < faults[j].Kind
+	})
+
+	return faults
+}
+
+// trigger returns the fault of the given kind if it is active, matches the
+// request and wins the draw.
+func (c *Controller) trigger(k Kind, r *http.Request) (Fault, bool) {
+	return c.Trigger(k, r.URL.Path)
+}
+
+// Trigger returns the fault of the given kind if it is active, applies to path
+// and wins the draw. It lets transports other than HTTP share the faults of c.
+func (c *Controller) Trigger(k Kind, path string) (Fault, bool) {
+	f, ok := c.Get(k)
+	if !ok || !f.matches(path) {
+		return Fault{}, false
+	}
+
+	return f, c.rand() < f.Probability
+}
+
+// Jitter returns a random duration in [0, max).
+func (c *Controller) Jitter(max Duration) time.Duration {
+	return time.Duration(c.rand() * float64(max))
+}
+
+// Crash exits the process with the exit code of the crash fault f, once
+// notified. It lets transports other than HTTP apply the crash fault.
+func (c *Controller) Crash(ctx context.Context, f Fault) {
+	c.notifyChange(ctx, ActionCrash, f)
+	c.exit(f.ExitCode)
+}
+
+func (c *Controller) notifyChange(ctx context.Context, a Action, f Fault) {
+	if c.notify != nil {
+		c.notify(ctx, Change{Action: a, Fault: f})
+	}
+}
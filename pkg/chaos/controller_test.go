@@ -0,0 +1,122 @@
+package chaos
+
+import (
+	"context"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewController(t *testing.T) {
+	c := NewController()
+
+	require.NotNil(t, c)
+	require.Empty(t, c.Faults())
+}
+
+func TestController_SetGetRemove(t *testing.T) {
+	c := NewController()
+
+	latency := Fault{Kind: KindLatency, Probability: 1, Delay: Duration(time.Second)}
+	require.NoError(t, c.Set(latency))
+
+	f, ok := c.Get(KindLatency)
+	require.True(t, ok)
+	require.Equal(t, latency, f)
+
+	require.True(t, c.Remove(KindLatency))
+	require.False(t, c.Remove(KindLatency))
+
+	_, ok = c.Get(KindLatency)
+	require.False(t, ok)
+}
+
+func TestController_SetInvalid(t *testing.T) {
+	c := NewController()
+
+	require.Error(t, c.Set(Fault{Kind: KindError, Probability: 1}))
+	require.Empty(t, c.Faults())
+}
+
+func TestController_SetReplaces(t *testing.T) {
+	c := NewController()
+
+	require.NoError(t, c.Set(Fault{Kind: KindError, Probability: 1, StatusCode: 500}))
+	require.NoError(t, c.Set(Fault{Kind: KindError, Probability: 1, StatusCode: 503}))
+
+	faults := c.Faults()
+	require.Len(t, faults, 1)
+	require.Equal(t, 503, faults[0].StatusCode)
+}
+
+func TestController_FaultsSorted(t *testing.T) {
+	c := NewController()
+
+	require.NoError(t, c.Set(Fault{Kind: KindLatency, Probability: 1, Delay: 1}))
+	require.NoError(t, c.Set(Fault{Kind: KindCrash, Probability: 1}))
+	require.NoError(t, c.Set(Fault{Kind: KindError, Probability: 1, StatusCode: 500}))
+
+	faults := c.Faults()
+	require.Len(t, faults, 3)
+	require.Equal(t, KindCrash, faults[0].Kind)
+	require.Equal(t, KindError, faults[1].Kind)
+	require.Equal(t, KindLatency, faults[2].Kind)
+}
+
+func TestController_Trigger(t *testing.T) {
+	draw := 0.5
+	c := NewController(WithRand(func() float64 { return draw }))
+
+	req := httptest.NewRequest("GET", "/api/users", nil)
+
+	_, ok := c.trigger(KindError, req)
+	require.False(t, ok, "inactive fault should not trigger")
+
+	require.NoError(t, c.Set(Fault{Kind: KindError, Probability: 0.6, StatusCode: 500}))
+	_, ok = c.trigger(KindError, req)
+	require.True(t, ok)
+
+	draw = 0.7
+	_, ok = c.trigger(KindError, req)
+	require.False(t, ok, "fault should not trigger when losing the draw")
+
+	require.NoError(t, c.Set(Fault{Kind: KindError, Probability: 1, StatusCode: 500, PathPrefix: "/admin"}))
+	_, ok = c.trigger(KindError, req)
+	require.False(t, ok, "fault should not trigger outside of its path prefix")
+
+	_, ok = c.trigger(KindError, httptest.NewRequest("GET", "/admin/users", nil))
+	require.True(t, ok)
+}
+
+type ctxKey struct{}
+
+func TestController_Notify(t *testing.T) {
+	var changes []Change
+	var values []interface{}
+	var exited []int
+	c := NewController(
+		WithExitFunc(func(code int) { exited = append(exited, code) }),
+		WithNotify(func(ctx context.Context, ch Change) {
+			changes = append(changes, ch)
+			values = append(values, ctx.Value(ctxKey{}))
+		}),
+	)
+
+	ctx := context.WithValue(context.Background(), ctxKey{}, "scenario")
+	crash := Fault{Kind: KindCrash, Probability: 1, ExitCode: 3}
+	require.NoError(t, c.SetContext(ctx, crash))
+	require.Error(t, c.Set(Fault{Kind: KindError, Probability: 1}))
+	c.Crash(ctx, crash)
+	require.True(t, c.Remove(KindCrash))
+	require.False(t, c.Remove(KindCrash))
+
+	require.Equal(t, []Change{
+		{Action: ActionSet, Fault: crash},
+		{Action: ActionCrash, Fault: crash},
+		{Action: ActionRemoved, Fault: crash},
+	}, changes)
+	require.Equal(t, []interface{}{"scenario", "scenario", nil}, values)
+	require.Equal(t, []int{3}, exited)
+}
@@ -0,0 +1,33 @@
+package chaos_test
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+
+	"github.com/pixelfactoryio/crashlooper/pkg/chaos"
+)
+
+func Example() {
+	faults := chaos.NewController()
+
+	router := mux.NewRouter()
+	router.PathPrefix("/chaos/faults").Handler(http.StripPrefix("/chaos/faults", faults))
+
+	api := router.PathPrefix("/api").Subrouter()
+	api.Use(chaos.Middleware(faults))
+	api.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+
+	// Delay a quarter of the API requests by 200ms to 300ms
+	_ = faults.Set(chaos.Fault{
+		Kind:        chaos.KindLatency,
+		Probability: 0.25,
+		Delay:       chaos.Duration(200 * time.Millisecond),
+		Jitter:      chaos.Duration(100 * time.Millisecond),
+	})
+
+	_ = http.ListenAndServe(":8080", router)
+}
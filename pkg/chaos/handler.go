@@ -0,0 +1,68 @@
+package chaos
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ServeHTTP exposes the faults of the controller as a JSON API, meant to be
+// mounted with http.StripPrefix:
+//
+//	GET    /        list the active faults
+//	GET    /{kind}  get the active fault of a kind
+//	PUT    /{kind}  set the fault of a kind from the request body
+//	DELETE /{kind}  remove the fault of a kind
+func (c *Controller) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	kind := Kind(strings.Trim(r.URL.Path, "/"))
+
+	if kind == "" {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", http.MethodGet)
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+		writeJSON(w, http.StatusOK, c.Faults())
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		f, ok := c.Get(kind)
+		if !ok {
+			http.Error(w, "fault not found", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, http.StatusOK, f)
+
+	case http.MethodPut, http.MethodPost:
+		var f Fault
+		if err := json.NewDecoder(r.Body).Decode(&f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		f.Kind = kind
+		if err := c.SetContext(r.Context(), f); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		writeJSON(w, http.StatusOK, f)
+
+	case http.MethodDelete:
+		if !c.RemoveContext(r.Context(), kind) {
+			http.Error(w, "fault not found", http.StatusNotFound)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+
+	default:
+		w.Header().Set("Allow", "GET, PUT, POST, DELETE")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+	}
+}
+
+func writeJSON(w http.ResponseWriter, code int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	_ = json.NewEncoder(w).Encode(v)
+}
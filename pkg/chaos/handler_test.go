@@ -0,0 +1,99 @@
+package chaos
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func serve(c *Controller, method, path, body string) *httptest.ResponseRecorder {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(method, "/faults"+path, strings.NewReader(body))
+	http.StripPrefix("/faults", c).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestController_ServeHTTP_List(t *testing.T) {
+	c := NewController()
+	require.NoError(t, c.Set(Fault{Kind: KindError, Probability: 1, StatusCode: 500}))
+
+	rec := serve(c, http.MethodGet, "", "")
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "application/json", rec.Header().Get("Content-Type"))
+
+	var faults []Fault
+	require.NoError(t, json.NewDecoder(rec.Body).Decode(&faults))
+	require.Equal(t, c.Faults(), faults)
+}
+
+func TestController_ServeHTTP_Put(t *testing.T) {
+	c := NewController()
+
+	rec := serve(c, http.MethodPut, "/latency", `{"probability": 0.5, "delay": "2s", "jitter": "500ms"}`)
+	require.Equal(t, http.StatusOK, rec.Code)
+
+	f, ok := c.Get(KindLatency)
+	require.True(t, ok)
+	require.Equal(t, Fault{
+		Kind:        KindLatency,
+		Probability: 0.5,
+		Delay:       Duration(2 * time.Second),
+		Jitter:      Duration(500 * time.Millisecond),
+	}, f)
+}
+
+func TestController_ServeHTTP_PutInvalid(t *testing.T) {
+	c := NewController()
+
+	tests := []struct {
+		name string
+		path string
+		body string
+	}{
+		{name: "malformed body", path: "/error", body: `{`},
+		{name: "invalid fault", path: "/error", body: `{"probability": 1, "status_code": 200}`},
+		{name: "unknown kind", path: "/meteor", body: `{"probability": 1}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := serve(c, http.MethodPut, tt.path, tt.body)
+			require.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+	require.Empty(t, c.Faults())
+}
+
+func TestController_ServeHTTP_GetDelete(t *testing.T) {
+	c := NewController()
+	require.NoError(t, c.Set(Fault{Kind: KindCrash, Probability: 0.1, ExitCode: 2}))
+
+	rec := serve(c, http.MethodGet, "/crash", "")
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Contains(t, rec.Body.String(), `"exit_code":2`)
+
+	rec = serve(c, http.MethodDelete, "/crash", "")
+	require.Equal(t, http.StatusNoContent, rec.Code)
+
+	rec = serve(c, http.MethodGet, "/crash", "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+
+	rec = serve(c, http.MethodDelete, "/crash", "")
+	require.Equal(t, http.StatusNotFound, rec.Code)
+}
+
+func TestController_ServeHTTP_MethodNotAllowed(t *testing.T) {
+	c := NewController()
+
+	rec := serve(c, http.MethodPost, "/", "")
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+
+	rec = serve(c, http.MethodPatch, "/latency", "")
+	require.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
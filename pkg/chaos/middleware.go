@@ -0,0 +1,174 @@
+package chaos
+
+import (
+	"net"
+	"net/http"
+	"time"
+)
+
+// HeaderFault is the response header listing the faults applied to a request.
+const HeaderFault = "X-Chaos-Fault"
+
+// Middleware applies the latency, disconnect, stream reset, GOAWAY, error and
+// crash faults of c, in that order.
+func Middleware(c *Controller) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return Latency(c)(Disconnect(c)(ResetStream(c)(GoAway(c)(Error(c)(Crash(c)(next))))))
+	}
+}
+
+// Latency delays requests when the latency fault of c applies.
+// The delay is cut short if the request context is done.
+func Latency(c *Controller) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			f, ok := c.trigger(KindLatency, r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			delay := time.Duration(f.Delay)
+			if f.Jitter > 0 {
+				delay += c.Jitter(f.Jitter)
+			}
+
+			timer := time.NewTimer(delay)
+			defer timer.Stop()
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				return
+			}
+
+			RecordFault(r.Context(), f, delay)
+			w.Header().Add(HeaderFault, string(KindLatency))
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Disconnect closes the client connection without responding when the
+// disconnect fault of c applies. When the connection cannot be hijacked, as
+// with HTTP/2, the response is aborted with http.ErrAbortHandler instead.
+func Disconnect(c *Controller) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			f, ok := c.trigger(KindDisconnect, r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			RecordFault(r.Context(), f, 0)
+			conn, _, err := http.NewResponseController(w).Hijack()
+			if err != nil {
+				panic(http.ErrAbortHandler)
+			}
+
+			if tcpConn, ok := conn.(*net.TCPConn); ok && f.Reset {
+				// a zero linger makes Close send a RST instead of a FIN
+				_ = tcpConn.SetLinger(0)
+			}
+			_ = conn.Close()
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// ResetStream aborts HTTP/2 requests with RST_STREAM without responding when
+// the stream reset fault of c applies. HTTP/1 requests are never reset.
+func ResetStream(c *Controller) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor != 2 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			f, ok := c.trigger(KindResetStream, r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			RecordFault(r.Context(), f, 0)
+			// the HTTP/2 server resets the stream of an aborted handler
+			panic(http.ErrAbortHandler)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// GoAway makes the HTTP/2 server send GOAWAY after responding to the request
+// when the GOAWAY fault of c applies. The streams already open are completed
+// but the client must open a new connection for its next requests. HTTP/1
+// requests are never affected.
+func GoAway(c *Controller) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			if r.ProtoMajor != 2 {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if f, ok := c.trigger(KindGoAway, r); ok {
+				RecordFault(r.Context(), f, 0)
+				// the HTTP/2 server drops this header and shuts the connection
+				// down gracefully, like an HTTP/1 server closes the connection
+				w.Header().Set("Connection", "close")
+				w.Header().Add(HeaderFault, string(KindGoAway))
+			}
+
+			next.ServeHTTP(w, r)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Error responds with the configured status code instead of calling the next
+// handler when the error fault of c applies.
+func Error(c *Controller) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			f, ok := c.trigger(KindError, r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			body := f.Body
+			if body == "" {
+				body = http.StatusText(f.StatusCode)
+			}
+
+			RecordFault(r.Context(), f, 0)
+			w.Header().Add(HeaderFault, string(KindError))
+			http.Error(w, body, f.StatusCode)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
+
+// Crash exits the process with the configured exit code when the crash fault
+// of c applies. The request is left unanswered.
+func Crash(c *Controller) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		fn := func(w http.ResponseWriter, r *http.Request) {
+			f, ok := c.trigger(KindCrash, r)
+			if !ok {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			RecordCrash(r.Context(), f)
+			c.Crash(r.Context(), f)
+		}
+
+		return http.HandlerFunc(fn)
+	}
+}
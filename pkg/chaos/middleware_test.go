@@ -0,0 +1,247 @@
+package chaos
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/http/httptrace"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+var okHandler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+})
+
+func TestMiddleware_NoFault(t *testing.T) {
+	c := NewController()
+	handler := Middleware(c)(okHandler)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, "ok", rec.Body.String())
+	require.Empty(t, rec.Header().Get(HeaderFault))
+}
+
+func TestLatency(t *testing.T) {
+	c := NewController()
+	require.NoError(t, c.Set(Fault{Kind: KindLatency, Probability: 1, Delay: Duration(20 * time.Millisecond)}))
+
+	handler := Latency(c)(okHandler)
+
+	start := time.Now()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.GreaterOrEqual(t, time.Since(start), 20*time.Millisecond)
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Equal(t, string(KindLatency), rec.Header().Get(HeaderFault))
+}
+
+func TestLatency_ContextCancelled(t *testing.T) {
+	c := NewController()
+	require.NoError(t, c.Set(Fault{Kind: KindLatency, Probability: 1, Delay: Duration(time.Hour)}))
+
+	called := false
+	handler := Latency(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+
+	require.False(t, called)
+}
+
+func TestError(t *testing.T) {
+	tests := []struct {
+		name         string
+		fault        Fault
+		expectedBody string
+	}{
+		{
+			name:         "default body",
+			fault:        Fault{Kind: KindError, Probability: 1, StatusCode: http.StatusServiceUnavailable},
+			expectedBody: "Service Unavailable\n",
+		},
+		{
+			name:         "custom body",
+			fault:        Fault{Kind: KindError, Probability: 1, StatusCode: http.StatusTooManyRequests, Body: "slow down"},
+			expectedBody: "slow down\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewController()
+			require.NoError(t, c.Set(tt.fault))
+
+			rec := httptest.NewRecorder()
+			Error(c)(okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			require.Equal(t, tt.fault.StatusCode, rec.Code)
+			require.Equal(t, tt.expectedBody, rec.Body.String())
+			require.Equal(t, string(KindError), rec.Header().Get(HeaderFault))
+		})
+	}
+}
+
+func TestCrash(t *testing.T) {
+	exitCode := -1
+	c := NewController(WithExitFunc(func(code int) { exitCode = code }))
+	require.NoError(t, c.Set(Fault{Kind: KindCrash, Probability: 1, ExitCode: 3}))
+
+	called := false
+	handler := Crash(c)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, 3, exitCode)
+	require.False(t, called)
+}
+
+func TestMiddleware_Order(t *testing.T) {
+	exited := false
+	c := NewController(WithExitFunc(func(int) { exited = true }))
+	require.NoError(t, c.Set(Fault{Kind: KindLatency, Probability: 1, Delay: Duration(time.Millisecond)}))
+	require.NoError(t, c.Set(Fault{Kind: KindError, Probability: 1, StatusCode: http.StatusBadGateway}))
+	require.NoError(t, c.Set(Fault{Kind: KindCrash, Probability: 1}))
+
+	rec := httptest.NewRecorder()
+	Middleware(c)(okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	// the error fault answers before the crash fault is reached
+	require.Equal(t, http.StatusBadGateway, rec.Code)
+	require.Equal(t, []string{"latency", "error"}, rec.Header().Values(HeaderFault))
+	require.False(t, exited)
+}
+
+func TestDisconnect(t *testing.T) {
+	tests := []struct {
+		name  string
+		reset bool
+	}{
+		{name: "close", reset: false},
+		{name: "reset", reset: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewController()
+			require.NoError(t, c.Set(Fault{Kind: KindDisconnect, Probability: 1, Reset: tt.reset}))
+
+			srv := httptest.NewServer(Disconnect(c)(okHandler))
+			defer srv.Close()
+
+			resp, err := http.Get(srv.URL)
+			if err == nil {
+				resp.Body.Close()
+			}
+			require.Error(t, err)
+		})
+	}
+}
+
+func TestDisconnect_NotHijackable(t *testing.T) {
+	c := NewController()
+	require.NoError(t, c.Set(Fault{Kind: KindDisconnect, Probability: 1}))
+
+	rec := httptest.NewRecorder()
+	require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+		Disconnect(c)(okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+	})
+}
+
+// startHTTP2Server serves handler over HTTP/2 and returns a client of it
+// reporting whether each request reused a connection.
+func startHTTP2Server(t *testing.T, handler http.Handler) (string, func() (*http.Response, bool, error)) {
+	t.Helper()
+
+	srv := httptest.NewUnstartedServer(handler)
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+
+	client := srv.Client()
+	return srv.URL, func() (*http.Response, bool, error) {
+		reused := false
+		trace := &httptrace.ClientTrace{GotConn: func(info httptrace.GotConnInfo) { reused = info.Reused }}
+		req, err := http.NewRequestWithContext(httptrace.WithClientTrace(context.Background(), trace), http.MethodGet, srv.URL, nil)
+		require.NoError(t, err)
+
+		resp, err := client.Do(req)
+		if err == nil {
+			_, _ = io.ReadAll(resp.Body)
+			resp.Body.Close()
+		}
+		return resp, reused, err
+	}
+}
+
+func TestResetStream(t *testing.T) {
+	c := NewController()
+	require.NoError(t, c.Set(Fault{Kind: KindResetStream, Probability: 1}))
+
+	_, get := startHTTP2Server(t, ResetStream(c)(okHandler))
+
+	_, _, err := get()
+	require.ErrorContains(t, err, "stream error")
+
+	// the connection survives the reset stream
+	require.True(t, c.Remove(KindResetStream))
+	resp, reused, err := get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, resp.ProtoMajor)
+	require.True(t, reused)
+}
+
+func TestGoAway(t *testing.T) {
+	c := NewController()
+	require.NoError(t, c.Set(Fault{Kind: KindGoAway, Probability: 1}))
+
+	_, get := startHTTP2Server(t, GoAway(c)(okHandler))
+
+	// the request is answered, then the connection is shut down
+	resp, _, err := get()
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "goaway", resp.Header.Get(HeaderFault))
+	require.Empty(t, resp.Header.Get("Connection"))
+
+	require.True(t, c.Remove(KindGoAway))
+	require.Eventually(t, func() bool {
+		resp, reused, err := get()
+		return err == nil && resp.StatusCode == http.StatusOK && !reused
+	}, time.Second, 10*time.Millisecond)
+
+	// without fault the connection is kept
+	_, reused, err := get()
+	require.NoError(t, err)
+	require.True(t, reused)
+}
+
+func TestHTTP2Faults_HTTP1(t *testing.T) {
+	c := NewController()
+	require.NoError(t, c.Set(Fault{Kind: KindResetStream, Probability: 1}))
+	require.NoError(t, c.Set(Fault{Kind: KindGoAway, Probability: 1}))
+
+	rec := httptest.NewRecorder()
+	Middleware(c)(okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	require.Equal(t, http.StatusOK, rec.Code)
+	require.Empty(t, rec.Header().Get(HeaderFault))
+	require.Empty(t, rec.Header().Get("Connection"))
+}
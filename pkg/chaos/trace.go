@@ -0,0 +1,85 @@
+package chaos
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Span attributes recording the faults.
+const (
+	// AttrInjected is set on the spans of the requests a fault applied to.
+	AttrInjected = attribute.Key("chaos.injected")
+	// AttrKind is the kind of the fault of a span event.
+	AttrKind = attribute.Key("chaos.fault.kind")
+	// AttrProbability is the probability of the fault of a span event.
+	AttrProbability = attribute.Key("chaos.fault.probability")
+	// AttrPathPrefix is the path prefix of the fault of a span event.
+	AttrPathPrefix = attribute.Key("chaos.fault.path_prefix")
+	// AttrDelay is the delay injected by a latency fault, in milliseconds.
+	AttrDelay = attribute.Key("chaos.fault.delay_ms")
+	// AttrStatusCode is the status code of an error fault.
+	AttrStatusCode = attribute.Key("chaos.fault.status_code")
+	// AttrExitCode is the exit code of a crash fault.
+	AttrExitCode = attribute.Key("chaos.fault.exit_code")
+)
+
+// EventFault is the name of the span events recording the faults.
+const EventFault = "chaos.fault"
+
+const tracerName = "github.com/pixelfactoryio/crashlooper/pkg/chaos"
+
+// RecordFault records that f applied to the request of ctx as an event of
+// its span, if it is traced, so that tracing backends show the faults.
+// delay is the delay injected by a latency fault.
+func RecordFault(ctx context.Context, f Fault, delay time.Duration) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	span.SetAttributes(AttrInjected.Bool(true))
+	span.AddEvent(EventFault, trace.WithAttributes(faultAttributes(f, delay)...))
+}
+
+// RecordCrash records that the crash fault f applied to the request of ctx.
+// The span of the request never ends once the process exits, so the crash is
+// recorded by a child span which ends immediately, to be exported when the
+// spans are flushed on exit.
+func RecordCrash(ctx context.Context, f Fault) {
+	span := trace.SpanFromContext(ctx)
+	if !span.IsRecording() {
+		return
+	}
+
+	RecordFault(ctx, f, 0)
+
+	_, crash := span.TracerProvider().Tracer(tracerName).Start(ctx, "chaos crash",
+		trace.WithAttributes(faultAttributes(f, 0)...),
+	)
+	crash.SetAttributes(AttrInjected.Bool(true))
+	crash.End()
+}
+
+func faultAttributes(f Fault, delay time.Duration) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		AttrKind.String(string(f.Kind)),
+		AttrProbability.Float64(f.Probability),
+	}
+	if f.PathPrefix != "" {
+		attrs = append(attrs, AttrPathPrefix.String(f.PathPrefix))
+	}
+
+	switch f.Kind {
+	case KindLatency:
+		attrs = append(attrs, AttrDelay.Float64(float64(delay)/float64(time.Millisecond)))
+	case KindError:
+		attrs = append(attrs, AttrStatusCode.Int(f.StatusCode))
+	case KindCrash:
+		attrs = append(attrs, AttrExitCode.Int(f.ExitCode))
+	}
+
+	return attrs
+}
@@ -0,0 +1,91 @@
+package chaos
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func newRecorder() (*tracetest.SpanRecorder, *sdktrace.TracerProvider) {
+	recorder := tracetest.NewSpanRecorder()
+	return recorder, sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+}
+
+func attrs(kvs []attribute.KeyValue) map[attribute.Key]attribute.Value {
+	m := map[attribute.Key]attribute.Value{}
+	for _, kv := range kvs {
+		m[kv.Key] = kv.Value
+	}
+	return m
+}
+
+func TestRecordFault(t *testing.T) {
+	recorder, tp := newRecorder()
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	RecordFault(ctx, Fault{Kind: KindLatency, Probability: 0.5, PathPrefix: "/api", Delay: Duration(time.Second)}, 1500*time.Millisecond)
+	RecordFault(ctx, Fault{Kind: KindError, Probability: 1, StatusCode: 503}, 0)
+	span.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, true, attrs(spans[0].Attributes())[AttrInjected].AsBool())
+
+	events := spans[0].Events()
+	require.Len(t, events, 2)
+	require.Equal(t, EventFault, events[0].Name)
+
+	latency := attrs(events[0].Attributes)
+	require.Equal(t, "latency", latency[AttrKind].AsString())
+	require.Equal(t, 0.5, latency[AttrProbability].AsFloat64())
+	require.Equal(t, "/api", latency[AttrPathPrefix].AsString())
+	require.Equal(t, 1500.0, latency[AttrDelay].AsFloat64())
+
+	errAttrs := attrs(events[1].Attributes)
+	require.Equal(t, "error", errAttrs[AttrKind].AsString())
+	require.Equal(t, int64(503), errAttrs[AttrStatusCode].AsInt64())
+}
+
+func TestRecordFault_NotTraced(t *testing.T) {
+	// no span, nothing to record
+	RecordFault(context.Background(), Fault{Kind: KindError, Probability: 1, StatusCode: 500}, 0)
+	RecordCrash(context.Background(), Fault{Kind: KindCrash, Probability: 1})
+}
+
+func TestRecordCrash(t *testing.T) {
+	recorder, tp := newRecorder()
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	RecordCrash(ctx, Fault{Kind: KindCrash, Probability: 1, ExitCode: 137})
+
+	// the request span never ends, the crash span does
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	require.Equal(t, "chaos crash", spans[0].Name())
+	require.Equal(t, span.SpanContext().SpanID(), spans[0].Parent().SpanID())
+	require.Equal(t, int64(137), attrs(spans[0].Attributes())[AttrExitCode].AsInt64())
+}
+
+func TestMiddleware_RecordsFaults(t *testing.T) {
+	recorder, tp := newRecorder()
+
+	c := NewController()
+	require.NoError(t, c.Set(Fault{Kind: KindError, Probability: 1, StatusCode: 502}))
+
+	ctx, span := tp.Tracer("test").Start(context.Background(), "request")
+	rec := httptest.NewRecorder()
+	Middleware(c)(okHandler).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil).WithContext(ctx))
+	span.End()
+
+	require.Equal(t, 502, rec.Code)
+	events := recorder.Ended()[0].Events()
+	require.Len(t, events, 1)
+	require.Equal(t, "error", attrs(events[0].Attributes)[AttrKind].AsString())
+}